@@ -0,0 +1,140 @@
+#!/bin/bash
+
+# Benchmark for config listing (GET /configs) on a large tenant.
+# Seeds CONFIGS configs with an encrypted field into a fresh namespace, then times
+# pages at the start, middle and end of the listing as a secret reader.
+# Make sure the server is running before running this script. The seeded configs
+# are deleted afterwards unless KEEP=1, e.g. to rerun against them with SEED=0.
+#
+#   AUTH_TOKEN=... ./bench_listing.sh
+#   CONFIGS=10000 RUNS=50 NAMESPACE=bench/listing SEED=0 KEEP=1 ./bench_listing.sh
+
+BASE_URL="${BASE_URL:-http://localhost:8080}"
+# JWT for a principal holding the admin role, e.g. signed with JWT_SECRET
+AUTH_TOKEN="${AUTH_TOKEN:?AUTH_TOKEN must be set}"
+CONFIGS="${CONFIGS:-10000}"
+RUNS="${RUNS:-20}"
+PARALLEL="${PARALLEL:-16}"
+NAMESPACE="${NAMESPACE:-bench/listing-$(date +%s)}"
+# SEED=0 reuses configs seeded into NAMESPACE by an earlier run
+SEED="${SEED:-1}"
+# KEEP=1 leaves the configs in NAMESPACE for a later run
+KEEP="${KEEP:-0}"
+
+TYPE="benchlisting"
+SUBTYPE="secret"
+
+export BASE_URL AUTH_TOKEN NAMESPACE TYPE SUBTYPE
+
+# cleanup deletes every config in NAMESPACE, a page of listing ids at a time
+cleanup() {
+  if [ "$KEEP" = "1" ]; then
+    echo ""
+    echo "Keeping the configs in $NAMESPACE"
+    return
+  fi
+  echo ""
+  echo "5. Deleting the configs in $NAMESPACE..."
+  local ids previous=""
+  while true; do
+    ids=$(curl -s -H "Authorization: Bearer $AUTH_TOKEN" "$BASE_URL/configs?namespace=$NAMESPACE&limit=1000" \
+      | grep -o '"id":"[0-9a-f]\{24\}"' | cut -d'"' -f4)
+    [ -n "$ids" ] || break
+    # Stop when a page could not be deleted, e.g. protected configs
+    if [ "$ids" = "$previous" ]; then
+      echo "   Some configs could not be deleted"
+      break
+    fi
+    previous="$ids"
+    echo "$ids" | xargs -P "$PARALLEL" -I{} curl -s -o /dev/null \
+      -H "Authorization: Bearer $AUTH_TOKEN" -X DELETE "$BASE_URL/config?id={}"
+  done
+}
+trap cleanup EXIT
+
+echo "Benchmarking config listing..."
+echo "=============================="
+echo "  BASE_URL:  $BASE_URL"
+echo "  NAMESPACE: $NAMESPACE"
+echo "  CONFIGS:   $CONFIGS"
+echo "  RUNS:      $RUNS"
+
+if [ "$SEED" = "1" ]; then
+  # The type may already exist from an earlier run
+  echo ""
+  echo "1. Creating type $TYPE/$SUBTYPE..."
+  curl -s -o /dev/null -H "Authorization: Bearer $AUTH_TOKEN" -X POST "$BASE_URL/types" \
+    -H "Content-Type: application/json" \
+    -d "{\"name\": \"$TYPE\", \"description\": \"Listing benchmark\"}"
+  curl -s -o /dev/null -H "Authorization: Bearer $AUTH_TOKEN" -X POST "$BASE_URL/types/$TYPE/subtypes" \
+    -H "Content-Type: application/json" \
+    -d '{
+      "name": "'"$SUBTYPE"'",
+      "metadata_schema": {
+        "properties": {
+          "url": {"type": "string", "required": true},
+          "password": {"type": "string", "encryption": true},
+          "pool_size": {"type": "integer"}
+        }
+      }
+    }'
+
+  echo "2. Seeding $CONFIGS configs with $PARALLEL parallel requests..."
+  SEED_START=$(date +%s)
+  seq 1 "$CONFIGS" | xargs -P "$PARALLEL" -I{} sh -c '
+    status=$(curl -s -o /dev/null -w "%{http_code}" -H "Authorization: Bearer $AUTH_TOKEN" -X POST "$BASE_URL/config" \
+      -H "Content-Type: application/json" \
+      -d "{
+        \"name\": \"bench-{}\",
+        \"namespace\": \"$NAMESPACE\",
+        \"type\": \"$TYPE\",
+        \"subtype\": \"$SUBTYPE\",
+        \"tags\": [\"bench\"],
+        \"metadata\": {\"url\": \"postgres://db-{}.internal:5432/app\", \"password\": \"secret-{}\", \"pool_size\": 10}
+      }")
+    [ "$status" = "201" ] || echo "   config bench-{} failed with HTTP $status"
+  '
+  echo "   Seeded in $(( $(date +%s) - SEED_START ))s"
+fi
+
+# Confirm the listing sees every seeded config
+echo ""
+echo "3. Checking the total..."
+TOTAL=$(curl -s -H "Authorization: Bearer $AUTH_TOKEN" "$BASE_URL/configs?namespace=$NAMESPACE&limit=1" \
+  | grep -o '"total":[0-9]*' | cut -d':' -f2)
+echo "   total: ${TOTAL:-unknown}"
+if [ -z "$TOTAL" ] || [ "$TOTAL" -eq 0 ]; then
+  echo "   No configs to list, giving up"
+  exit 1
+fi
+
+# bench <label> <limit> <skip> times RUNS requests of one page and prints
+# min, median, p95 and max in milliseconds
+bench() {
+  local label="$1" limit="$2" skip="$3"
+  local url="$BASE_URL/configs?namespace=$NAMESPACE&limit=$limit&skip=$skip"
+  local failures
+  failures=$(curl -s -H "Authorization: Bearer $AUTH_TOKEN" "$url" | grep -o '"decryption_error"' | wc -l)
+
+  for _ in $(seq 1 "$RUNS"); do
+    curl -s -o /dev/null -w "%{time_total}\n" -H "Authorization: Bearer $AUTH_TOKEN" "$url"
+  done | sort -n | awk -v label="$label" -v failures="$failures" '
+    { times[NR] = $1 * 1000 }
+    END {
+      p95 = int(NR * 0.95); if (p95 < 1) p95 = 1
+      printf "   %-28s min %8.1fms  median %8.1fms  p95 %8.1fms  max %8.1fms  decryption errors %d\n",
+        label, times[1], times[int((NR + 1) / 2)], times[p95], times[NR], failures
+    }'
+}
+
+echo ""
+echo "4. Timing $RUNS requests per page..."
+LAST=$(( TOTAL > 100 ? TOTAL - 100 : 0 ))
+bench "limit=10 first page" 10 0
+bench "limit=100 first page" 100 0
+bench "limit=100 middle page" 100 $(( TOTAL / 2 ))
+bench "limit=100 last page" 100 "$LAST"
+bench "limit=1000 first page" 1000 0
+
+echo ""
+echo "Benchmark completed."
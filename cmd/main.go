@@ -3,7 +3,9 @@ package main
 import (
 	"context"
 	"log"
+	"net"
 	"net/http"
+	"os"
 	"time"
 
 	"makatom-api-config/internal/routes"
@@ -30,8 +32,8 @@ func main() {
 		}
 	}()
 
-	// Register routes and get the mux
-	mux := routes.RegisterConfigRoutes()
+	// Register routes and get the mux and gRPC server
+	mux, grpcServer := routes.RegisterConfigRoutes()
 
 	// Create HTTP server
 	server := &http.Server{
@@ -42,6 +44,23 @@ func main() {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	// Serve gRPC on a second port
+	grpcPort := os.Getenv("GRPC_PORT")
+	if grpcPort == "" {
+		grpcPort = ":9090"
+	}
+	listener, err := net.Listen("tcp", grpcPort)
+	if err != nil {
+		log.Fatalf("Failed to listen on %s: %v", grpcPort, err)
+	}
+	go func() {
+		log.Printf("Starting gRPC server on %s", grpcPort)
+		if err := grpcServer.Serve(listener); err != nil {
+			log.Fatalf("Failed to start gRPC server: %v", err)
+		}
+	}()
+	defer grpcServer.GracefulStop()
+
 	log.Printf("Starting server on %s", cfg.Port)
 	log.Printf("Environment: %s", cfg.Environment)
 	log.Printf("Debug mode: %t", cfg.Debug)
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"makatom-api-config/pkg/client"
+)
+
+// AgentConfig is the agent configuration file
+type AgentConfig struct {
+	Endpoint     string           `yaml:"endpoint"`
+	Token        string           `yaml:"token"`
+	APIKey       string           `yaml:"api_key"`
+	Interval     string           `yaml:"interval"`
+	SnapshotPath string           `yaml:"snapshot_path"`
+	Query        QueryConfig      `yaml:"query"`
+	Templates    []TemplateConfig `yaml:"templates"`
+}
+
+// QueryConfig selects the configs available to the templates
+type QueryConfig struct {
+	Type    string `yaml:"type"`
+	Subtype string `yaml:"subtype"`
+	Tag     string `yaml:"tag"`
+}
+
+// TemplateConfig describes one rendered file
+type TemplateConfig struct {
+	Source         string `yaml:"source"`
+	Destination    string `yaml:"destination"`
+	Perms          string `yaml:"perms"`
+	Command        string `yaml:"command"`
+	CommandTimeout string `yaml:"command_timeout"`
+}
+
+// loadConfig reads the YAML (or JSON) agent configuration; credentials may come from the environment
+func loadConfig(path string) (AgentConfig, error) {
+	var cfg AgentConfig
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	if err := yaml.Unmarshal(raw, &cfg); err != nil {
+		return cfg, fmt.Errorf("invalid agent config %s: %v", path, err)
+	}
+
+	if endpoint := os.Getenv("MAKATOM_ENDPOINT"); endpoint != "" {
+		cfg.Endpoint = endpoint
+	}
+	if token := os.Getenv("MAKATOM_TOKEN"); token != "" {
+		cfg.Token = token
+	}
+	if key := os.Getenv("MAKATOM_API_KEY"); key != "" {
+		cfg.APIKey = key
+	}
+
+	if cfg.Endpoint == "" {
+		return cfg, fmt.Errorf("endpoint is required")
+	}
+	if len(cfg.Templates) == 0 {
+		return cfg, fmt.Errorf("at least one template is required")
+	}
+	for i, tmpl := range cfg.Templates {
+		if tmpl.Source == "" || tmpl.Destination == "" {
+			return cfg, fmt.Errorf("template %d: source and destination are required", i)
+		}
+		if _, err := tmpl.mode(); err != nil {
+			return cfg, fmt.Errorf("template %d: %v", i, err)
+		}
+		if _, err := tmpl.timeout(); err != nil {
+			return cfg, fmt.Errorf("template %d: %v", i, err)
+		}
+	}
+	if _, err := cfg.interval(); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+func (c AgentConfig) interval() (time.Duration, error) {
+	if c.Interval == "" {
+		return client.DefaultRefreshInterval, nil
+	}
+	interval, err := time.ParseDuration(c.Interval)
+	if err != nil {
+		return 0, fmt.Errorf("invalid interval %q", c.Interval)
+	}
+	return interval, nil
+}
+
+func (c AgentConfig) client() *client.Client {
+	var opts []client.Option
+	switch {
+	case c.APIKey != "":
+		opts = append(opts, client.WithAPIKey(c.APIKey))
+	case c.Token != "":
+		opts = append(opts, client.WithToken(c.Token))
+	}
+	return client.New(c.Endpoint, opts...)
+}
+
+// mode parses the octal file mode, defaulting to 0644
+func (t TemplateConfig) mode() (os.FileMode, error) {
+	if t.Perms == "" {
+		return 0o644, nil
+	}
+	mode, err := strconv.ParseUint(t.Perms, 8, 32)
+	if err != nil || mode > 0o777 {
+		return 0, fmt.Errorf("invalid perms %q", t.Perms)
+	}
+	return os.FileMode(mode), nil
+}
+
+// timeout parses the reload command timeout, defaulting to 30s
+func (t TemplateConfig) timeout() (time.Duration, error) {
+	if t.CommandTimeout == "" {
+		return 30 * time.Second, nil
+	}
+	timeout, err := time.ParseDuration(t.CommandTimeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid command_timeout %q", t.CommandTimeout)
+	}
+	return timeout, nil
+}
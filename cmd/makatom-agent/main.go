@@ -0,0 +1,79 @@
+// Command makatom-agent renders configs to files through text/template and runs
+// reload commands when the rendered output changes.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"makatom-api-config/pkg/client"
+)
+
+func main() {
+	configPath := flag.String("config", "agent.yaml", "agent configuration file")
+	once := flag.Bool("once", false, "render the templates once and exit")
+	flag.Parse()
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		log.Fatalf("Failed to load agent config: %v", err)
+	}
+	interval, _ := cfg.interval()
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	var renderer *Renderer
+	cache := client.NewCache(cfg.client(), client.CacheOptions{
+		Query: client.ListOptions{
+			Type:    cfg.Query.Type,
+			Subtype: cfg.Query.Subtype,
+			Tag:     cfg.Query.Tag,
+		},
+		RefreshInterval: interval,
+		SnapshotPath:    cfg.SnapshotPath,
+		OnChange: func([]client.Config) {
+			if renderer != nil {
+				renderer.Render(ctx)
+			}
+		},
+		OnError: func(err error) {
+			log.Printf("Refresh failed, keeping the last rendered files: %v", err)
+		},
+	})
+	renderer = NewRenderer(cache, cfg.Templates)
+
+	if *once {
+		if err := cache.Refresh(ctx); err != nil {
+			log.Fatalf("Failed to load configs: %v", err)
+		}
+		if err := renderer.Render(ctx); err != nil {
+			log.Fatalf("Render failed: %v", err)
+		}
+		return
+	}
+
+	if err := cache.Start(ctx); err != nil {
+		log.Fatalf("Failed to load configs: %v", err)
+	}
+	if cache.Stale() {
+		log.Printf("Config service unavailable, rendering from snapshot %s", cfg.SnapshotPath)
+	}
+	renderer.Render(ctx)
+
+	// SIGHUP re-renders, e.g. after a template file was edited
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			renderer.Render(ctx)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+
+	"makatom-api-config/pkg/client"
+)
+
+// Renderer renders the configured templates from the cached configs
+type Renderer struct {
+	cache     *client.Cache
+	templates []TemplateConfig
+
+	mu sync.Mutex
+}
+
+// NewRenderer creates a new Renderer instance
+func NewRenderer(cache *client.Cache, templates []TemplateConfig) *Renderer {
+	return &Renderer{cache: cache, templates: templates}
+}
+
+// Render renders every template and runs the commands of the files that changed.
+// A failing template keeps its previous file and does not stop the others.
+func (r *Renderer) Render(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var (
+		failed   int
+		commands []TemplateConfig
+		queued   = make(map[string]bool)
+	)
+	for _, tmpl := range r.templates {
+		changed, err := r.renderOne(tmpl)
+		if err != nil {
+			failed++
+			log.Printf("template %s: %v", tmpl.Source, err)
+			continue
+		}
+		if !changed {
+			continue
+		}
+		log.Printf("rendered %s", tmpl.Destination)
+
+		// Templates sharing a reload command trigger it only once
+		if tmpl.Command != "" && !queued[tmpl.Command] {
+			queued[tmpl.Command] = true
+			commands = append(commands, tmpl)
+		}
+	}
+
+	for _, tmpl := range commands {
+		if err := runCommand(ctx, tmpl); err != nil {
+			failed++
+			log.Printf("command %q: %v", tmpl.Command, err)
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d templates or commands failed", failed)
+	}
+	return nil
+}
+
+// renderOne renders a template and writes it when the output differs from the file on disk.
+// Templates are parsed on every render so edits are picked up without a restart.
+func (r *Renderer) renderOne(tmpl TemplateConfig) (bool, error) {
+	source, err := os.ReadFile(tmpl.Source)
+	if err != nil {
+		return false, err
+	}
+	parsed, err := template.New(filepath.Base(tmpl.Source)).
+		Funcs(r.funcs()).
+		Option("missingkey=error").
+		Parse(string(source))
+	if err != nil {
+		return false, err
+	}
+
+	var out bytes.Buffer
+	if err := parsed.Execute(&out, r.cache.All()); err != nil {
+		return false, err
+	}
+
+	mode, _ := tmpl.mode()
+	current, err := os.ReadFile(tmpl.Destination)
+	if err == nil && bytes.Equal(current, out.Bytes()) {
+		if info, statErr := os.Stat(tmpl.Destination); statErr == nil && info.Mode().Perm() != mode {
+			return false, os.Chmod(tmpl.Destination, mode)
+		}
+		return false, nil
+	}
+
+	if err := writeAtomic(tmpl.Destination, out.Bytes(), mode); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// funcs are the template functions; lookups only see the configs selected by the agent query
+func (r *Renderer) funcs() template.FuncMap {
+	return template.FuncMap{
+		// config "type/name" or "type/subtype/name" returns a single config
+		"config": func(ref string) (client.Config, error) {
+			parts := strings.Split(ref, "/")
+			var configType, subtype, name string
+			switch len(parts) {
+			case 2:
+				configType, name = parts[0], parts[1]
+			case 3:
+				configType, subtype, name = parts[0], parts[1], parts[2]
+			default:
+				return client.Config{}, fmt.Errorf("invalid config reference %q", ref)
+			}
+			config, ok := r.cache.GetByName(configType, subtype, name)
+			if !ok {
+				return client.Config{}, fmt.Errorf("config %q not found", ref)
+			}
+			return config, nil
+		},
+		// configs "type" returns every config of the type
+		"configs": func(configType string) []client.Config {
+			var matched []client.Config
+			for _, config := range r.cache.All() {
+				if config.Type == configType {
+					matched = append(matched, config)
+				}
+			}
+			return matched
+		},
+		// byTag "tag" returns every config carrying the tag
+		"byTag": func(tag string) []client.Config {
+			var matched []client.Config
+			for _, config := range r.cache.All() {
+				for _, t := range config.Tags {
+					if t == tag {
+						matched = append(matched, config)
+						break
+					}
+				}
+			}
+			return matched
+		},
+		"toJSON": func(v interface{}) (string, error) {
+			encoded, err := json.MarshalIndent(v, "", "  ")
+			return string(encoded), err
+		},
+		"toYAML": func(v interface{}) (string, error) {
+			encoded, err := yaml.Marshal(v)
+			return strings.TrimSuffix(string(encoded), "\n"), err
+		},
+		"base64Decode": func(s string) (string, error) {
+			decoded, err := base64.StdEncoding.DecodeString(s)
+			return string(decoded), err
+		},
+		"default": func(fallback, v interface{}) interface{} {
+			if v == nil || v == "" {
+				return fallback
+			}
+			return v
+		},
+		"env": os.Getenv,
+	}
+}
+
+// writeAtomic writes data to a temp file next to path and renames it over path,
+// so readers never see a partially written file
+func writeAtomic(path string, data []byte, mode os.FileMode) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+"-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// runCommand runs the reload command of a template through the shell
+func runCommand(ctx context.Context, tmpl TemplateConfig) error {
+	timeout, _ := tmpl.timeout()
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "/bin/sh", "-c", tmpl.Command)
+	output, err := cmd.CombinedOutput()
+	if len(output) > 0 {
+		log.Printf("command %q: %s", tmpl.Command, strings.TrimSpace(string(output)))
+	}
+	return err
+}
@@ -0,0 +1,516 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"makatom-api-config/pkg/client"
+)
+
+func runGet(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("get", flag.ContinueOnError)
+	env := fs.String("env", "", "merge the metadata with this environment overlay")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errors.New("usage: makatomctl " + commands["get"].usage)
+	}
+
+	config, err := a.resolveConfig(ctx, positional[0])
+	if err != nil {
+		return err
+	}
+	if *env != "" {
+		if config, err = a.client.GetEnv(ctx, config.ID, *env); err != nil {
+			return err
+		}
+	}
+	return a.printConfig(config)
+}
+
+func runList(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	opts := listFlags(fs)
+	name := fs.String("name", "", "filter by name")
+	limit := fs.Int64("limit", 50, "maximum number of configs")
+	skip := fs.Int64("skip", 0, "number of configs to skip")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	opts.Name = *name
+	opts.Limit = *limit
+	opts.Skip = *skip
+	result, err := a.client.List(ctx, *opts)
+	if err != nil {
+		return err
+	}
+	return a.printConfigs(result.Configs, result.Total)
+}
+
+func runCreate(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	file := fs.String("f", "", "JSON or YAML file with the config (- for stdin)")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *file == "" {
+		return errors.New("usage: makatomctl " + commands["create"].usage)
+	}
+
+	var req client.CreateRequest
+	if err := decodeFile(*file, &req); err != nil {
+		return err
+	}
+	config, err := a.client.Create(ctx, req)
+	if err != nil {
+		return err
+	}
+	return a.printConfig(config)
+}
+
+func runEdit(ctx context.Context, a *app, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: makatomctl " + commands["edit"].usage)
+	}
+	config, err := a.resolveConfig(ctx, args[0])
+	if err != nil {
+		return err
+	}
+
+	// The temp file may hold decrypted secrets, so it is removed as soon as the editor exits
+	tmp, err := os.CreateTemp("", "makatomctl-*.yaml")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	original := editableConfig{Tags: config.Tags, Metadata: config.Metadata}
+	if err := printValue(tmp, "yaml", original); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	if err := openEditor(tmp.Name()); err != nil {
+		return err
+	}
+
+	var edited editableConfig
+	if err := decodeFile(tmp.Name(), &edited); err != nil {
+		return fmt.Errorf("invalid YAML after edit: %v", err)
+	}
+
+	before, _ := toGeneric(original)
+	after, _ := toGeneric(edited)
+	if reflect.DeepEqual(before, after) {
+		fmt.Fprintln(os.Stderr, "no changes")
+		return nil
+	}
+
+	updated, err := a.client.Update(ctx, config.ID, client.UpdateRequest{Tags: edited.Tags, Metadata: edited.Metadata})
+	if err != nil {
+		return err
+	}
+	return a.printConfig(updated)
+}
+
+// editableConfig is the part of a config that edit lets the operator change
+type editableConfig struct {
+	Tags     []string               `json:"tags"`
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+// openEditor runs $VISUAL or $EDITOR (default vi) on path
+func openEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// The editor may carry arguments, e.g. "code --wait"
+	parts := strings.Fields(editor)
+	cmd := exec.Command(parts[0], append(parts[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor %q failed: %v", editor, err)
+	}
+	return nil
+}
+
+func runDelete(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("delete", flag.ContinueOnError)
+	yes := fs.Bool("yes", false, "do not ask for confirmation")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errors.New("usage: makatomctl " + commands["delete"].usage)
+	}
+
+	config, err := a.resolveConfig(ctx, positional[0])
+	if err != nil {
+		return err
+	}
+	if !*yes && !confirm(fmt.Sprintf("Delete %s/%s/%s (%s) and all its archives?", config.Type, config.Subtype, config.Name, config.ID)) {
+		return errors.New("aborted")
+	}
+	if err := a.client.Delete(ctx, config.ID); err != nil {
+		return err
+	}
+	fmt.Fprintf(a.out, "deleted %s\n", config.ID)
+	return nil
+}
+
+// confirm asks a yes/no question on the terminal
+func confirm(question string) bool {
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+func runHistory(ctx context.Context, a *app, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: makatomctl " + commands["history"].usage)
+	}
+	config, err := a.resolveConfig(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	archives, err := a.client.Archives(ctx, config.ID)
+	if err != nil {
+		return err
+	}
+	return a.printArchives(archives)
+}
+
+func runDiff(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ContinueOnError)
+	from := fs.String("from", "", "archived version to diff from (default: latest archive)")
+	to := fs.String("to", "current", "archived version to diff to, or \"current\"")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		return errors.New("usage: makatomctl " + commands["diff"].usage)
+	}
+
+	config, err := a.resolveConfig(ctx, positional[0])
+	if err != nil {
+		return err
+	}
+	archives, err := a.client.Archives(ctx, config.ID)
+	if err != nil {
+		return err
+	}
+
+	if *from == "" {
+		if len(archives) == 0 {
+			return errors.New("config has no archived versions")
+		}
+		*from = strconv.Itoa(latestArchive(archives).Version)
+	}
+
+	before, err := versionMetadata(config, archives, *from)
+	if err != nil {
+		return err
+	}
+	after, err := versionMetadata(config, archives, *to)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(a.out, "--- %s\n+++ %s\n", *from, *to)
+	for _, line := range diffMetadata(before, after) {
+		fmt.Fprintln(a.out, line)
+	}
+	return nil
+}
+
+// versionMetadata returns the metadata of an archived version or of the current config
+func versionMetadata(config client.Config, archives []client.ConfigArchive, version string) (map[string]interface{}, error) {
+	if version == "current" {
+		return config.Metadata, nil
+	}
+	archive, err := findArchive(archives, version)
+	if err != nil {
+		return nil, err
+	}
+	return archive.Metadata, nil
+}
+
+// findArchive returns the most recent archive with the given version number
+func findArchive(archives []client.ConfigArchive, version string) (client.ConfigArchive, error) {
+	n, err := strconv.Atoi(version)
+	if err != nil {
+		return client.ConfigArchive{}, fmt.Errorf("invalid version %q", version)
+	}
+
+	var (
+		found client.ConfigArchive
+		ok    bool
+	)
+	for _, archive := range archives {
+		if archive.Version == n && (!ok || archive.ArchivedAt.After(found.ArchivedAt)) {
+			found, ok = archive, true
+		}
+	}
+	if !ok {
+		return client.ConfigArchive{}, fmt.Errorf("version %d not found in archives", n)
+	}
+	return found, nil
+}
+
+func latestArchive(archives []client.ConfigArchive) client.ConfigArchive {
+	latest := archives[0]
+	for _, archive := range archives[1:] {
+		if archive.ArchivedAt.After(latest.ArchivedAt) {
+			latest = archive
+		}
+	}
+	return latest
+}
+
+// diffMetadata lists removed (-), added (+) and changed (~) metadata keys
+func diffMetadata(before, after map[string]interface{}) []string {
+	keys := map[string]interface{}{}
+	for key := range before {
+		keys[key] = nil
+	}
+	for key := range after {
+		keys[key] = nil
+	}
+
+	var lines []string
+	for _, key := range sortedKeys(keys) {
+		oldValue, inBefore := before[key]
+		newValue, inAfter := after[key]
+		switch {
+		case !inAfter:
+			lines = append(lines, fmt.Sprintf("- %s: %s", key, formatScalar(oldValue)))
+		case !inBefore:
+			lines = append(lines, fmt.Sprintf("+ %s: %s", key, formatScalar(newValue)))
+		case !reflect.DeepEqual(oldValue, newValue):
+			lines = append(lines, fmt.Sprintf("~ %s: %s -> %s", key, formatScalar(oldValue), formatScalar(newValue)))
+		}
+	}
+	if len(lines) == 0 {
+		lines = append(lines, "(no metadata changes)")
+	}
+	return lines
+}
+
+func runRestore(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	version := fs.String("version", "", "archived version to restore")
+	archiveID := fs.String("archive", "", "archive ID to restore")
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 || (*version == "") == (*archiveID == "") {
+		return errors.New("usage: makatomctl " + commands["restore"].usage)
+	}
+
+	config, err := a.resolveConfig(ctx, positional[0])
+	if err != nil {
+		return err
+	}
+
+	if *version != "" {
+		archives, err := a.client.Archives(ctx, config.ID)
+		if err != nil {
+			return err
+		}
+		archive, err := findArchive(archives, *version)
+		if err != nil {
+			return err
+		}
+		*archiveID = archive.ID
+	}
+
+	restored, err := a.client.Restore(ctx, config.ID, *archiveID)
+	if err != nil {
+		return err
+	}
+	return a.printConfig(restored)
+}
+
+func runDecrypt(ctx context.Context, a *app, args []string) error {
+	if len(args) != 2 {
+		return errors.New("usage: makatomctl " + commands["decrypt"].usage)
+	}
+	config, err := a.resolveConfig(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	result, err := a.client.Decrypt(ctx, config.ID, args[1])
+	if err != nil {
+		return err
+	}
+	if a.format != "table" {
+		return printValue(a.out, a.format, result)
+	}
+	fmt.Fprintln(a.out, formatScalar(result.DecryptedValue))
+	return nil
+}
+
+func runImport(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	file := fs.String("f", "", "JSON or YAML file with a list of configs (- for stdin)")
+	dryRun := fs.Bool("dry-run", false, "only report what would change")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *file == "" {
+		return errors.New("usage: makatomctl " + commands["import"].usage)
+	}
+
+	var items []client.CreateRequest
+	if err := decodeFile(*file, &items); err != nil {
+		return err
+	}
+
+	var failed int
+	for _, item := range items {
+		ref := item.Type + "/" + item.Subtype + "/" + item.Name
+		if item.Namespace != "" {
+			ref = item.Namespace + ":" + ref
+		}
+		action, err := a.importConfig(ctx, item, *dryRun)
+		if err != nil {
+			failed++
+			fmt.Fprintf(a.out, "%-9s %s: %v\n", "failed", ref, err)
+			continue
+		}
+		fmt.Fprintf(a.out, "%-9s %s\n", action, ref)
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d configs failed to import", failed, len(items))
+	}
+	return nil
+}
+
+// importConfig creates the config or updates it in place when its natural key already exists
+func (a *app) importConfig(ctx context.Context, item client.CreateRequest, dryRun bool) (string, error) {
+	existing, err := a.findInNamespace(ctx, item)
+	if err != nil && !client.IsNotFound(err) {
+		return "", err
+	}
+
+	if err != nil {
+		if !dryRun {
+			if _, err := a.client.Create(ctx, item); err != nil {
+				return "", err
+			}
+		}
+		return "created", nil
+	}
+
+	before, _ := toGeneric(editableConfig{Tags: existing.Tags, Metadata: existing.Metadata})
+	after, _ := toGeneric(editableConfig{Tags: item.Tags, Metadata: item.Metadata})
+	if reflect.DeepEqual(before, after) {
+		return "unchanged", nil
+	}
+	if !dryRun {
+		if _, err := a.client.Update(ctx, existing.ID, client.UpdateRequest{Tags: item.Tags, Metadata: item.Metadata}); err != nil {
+			return "", err
+		}
+	}
+	return "updated", nil
+}
+
+// findInNamespace looks up a config by its natural key within the namespace of item
+func (a *app) findInNamespace(ctx context.Context, item client.CreateRequest) (client.Config, error) {
+	candidates, err := a.client.ListAll(ctx, client.ListOptions{
+		Name:      item.Name,
+		Namespace: item.Namespace,
+		Type:      item.Type,
+		Subtype:   item.Subtype,
+	})
+	if err != nil {
+		return client.Config{}, err
+	}
+	for _, candidate := range candidates {
+		if candidate.Namespace == item.Namespace && candidate.Subtype == item.Subtype {
+			return candidate, nil
+		}
+	}
+	return client.Config{}, &client.APIError{StatusCode: http.StatusNotFound, Message: "Config not found"}
+}
+
+func runExport(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	opts := listFlags(fs)
+	file := fs.String("f", "", "write to file instead of stdout")
+	if _, err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	configs, err := a.client.ListAll(ctx, *opts)
+	if err != nil {
+		return err
+	}
+
+	// Export in the import format so the output can be fed back to import
+	items := make([]client.CreateRequest, 0, len(configs))
+	for _, config := range configs {
+		items = append(items, client.CreateRequest{
+			Name:      config.Name,
+			Namespace: config.Namespace,
+			Type:      config.Type,
+			Subtype:   config.Subtype,
+			Tags:      config.Tags,
+			Metadata:  config.Metadata,
+		})
+	}
+
+	out := a.out
+	if *file != "" {
+		f, err := os.OpenFile(*file, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	format := a.format
+	if format == "table" {
+		format = "yaml"
+	}
+	return printValue(out, format, items)
+}
+
+// listFlags registers the namespace, type, subtype and tag filters on fs
+func listFlags(fs *flag.FlagSet) *client.ListOptions {
+	opts := &client.ListOptions{}
+	fs.StringVar(&opts.Namespace, "namespace", "", "filter by namespace")
+	fs.StringVar(&opts.NamespacePrefix, "namespace-prefix", "", "filter by namespace and its descendants")
+	fs.StringVar(&opts.Type, "type", "", "filter by type")
+	fs.StringVar(&opts.Subtype, "subtype", "", "filter by subtype")
+	fs.StringVar(&opts.Tag, "tag", "", "filter by tag")
+	return opts
+}
@@ -0,0 +1,156 @@
+// Command makatomctl is the operator command-line tool for the config service.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"makatom-api-config/pkg/client"
+)
+
+// app carries the state shared by every subcommand
+type app struct {
+	client *client.Client
+	out    io.Writer
+	format string
+}
+
+// command is a makatomctl subcommand
+type command struct {
+	usage   string
+	summary string
+	run     func(ctx context.Context, a *app, args []string) error
+}
+
+// commands is filled in init because the subcommands refer back to it for their usage
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"get":     {"get <id|type/[subtype/]name> [--env E]", "show a config", runGet},
+		"list":    {"list [--namespace NS | --namespace-prefix NS] [--type T] [--subtype S] [--tag T] [--name N]", "list configs", runList},
+		"create":  {"create -f <file>", "create a config from a JSON or YAML file", runCreate},
+		"edit":    {"edit <ref>", "edit the metadata of a config in $EDITOR", runEdit},
+		"delete":  {"delete <ref> [--yes]", "delete a config and its archives", runDelete},
+		"history": {"history <ref>", "list the archived versions of a config", runHistory},
+		"diff":    {"diff <ref> [--from V] [--to V]", "diff two versions of a config (default: latest archive vs current)", runDiff},
+		"restore": {"restore <ref> --version V | --archive ID", "restore a config from an archived version", runRestore},
+		"decrypt": {"decrypt <ref> <field>", "decrypt an encrypted metadata field", runDecrypt},
+		"import":  {"import -f <file> [--dry-run]", "create or update configs from a JSON or YAML file", runImport},
+		"export":  {"export [--type T] [--subtype S] [--tag T] [-f file]", "export configs as JSON or YAML", runExport},
+		"run":     {"run (--config REF | --tag T | --type T)... [--prefix P] [--secret F | --all-secrets] [--watch] -- cmd [args]", "run a command with configs injected as environment variables", runRun},
+		"profile": {"profile list | use <name> | set <name> [--endpoint URL] [--token T] [--api-key K]", "manage connection profiles", runProfile},
+	}
+}
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintf(os.Stderr, "makatomctl: %v\n", err)
+		var apiErr *client.APIError
+		if errors.As(err, &apiErr) && apiErr.Details != nil {
+			printValue(os.Stderr, "yaml", apiErr.Details)
+		}
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	global := flag.NewFlagSet("makatomctl", flag.ContinueOnError)
+	profileName := global.String("profile", "", "connection profile to use (default: current profile)")
+	format := global.String("o", "table", "output format: table, json or yaml")
+	global.Usage = usage
+	if err := global.Parse(args); err != nil {
+		return err
+	}
+
+	if global.NArg() == 0 {
+		usage()
+		return errors.New("missing command")
+	}
+	if *format != "table" && *format != "json" && *format != "yaml" {
+		return fmt.Errorf("unknown output format %q", *format)
+	}
+
+	name := global.Arg(0)
+	cmd, ok := commands[name]
+	if !ok {
+		usage()
+		return fmt.Errorf("unknown command %q", name)
+	}
+
+	a := &app{out: os.Stdout, format: *format}
+	if name != "profile" {
+		profile, err := resolveProfile(*profileName)
+		if err != nil {
+			return err
+		}
+		a.client = profile.client()
+	}
+
+	return cmd.run(context.Background(), a, global.Args()[1:])
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: makatomctl [--profile NAME] [-o table|json|yaml] <command> [args]")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Commands:")
+
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-8s %s\n", name, commands[name].summary)
+		fmt.Fprintf(os.Stderr, "           makatomctl %s\n", commands[name].usage)
+	}
+}
+
+// parseFlags parses subcommand flags, allowing them before and after positional args
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// resolveConfig looks up a config by ID or by "type/name" or "type/subtype/name"
+func (a *app) resolveConfig(ctx context.Context, ref string) (client.Config, error) {
+	if isObjectID(ref) {
+		return a.client.Get(ctx, ref)
+	}
+
+	parts := strings.Split(ref, "/")
+	switch len(parts) {
+	case 2:
+		return a.client.GetByName(ctx, parts[0], "", parts[1])
+	case 3:
+		return a.client.GetByName(ctx, parts[0], parts[1], parts[2])
+	}
+	return client.Config{}, fmt.Errorf("invalid config reference %q, expected an ID or type/[subtype/]name", ref)
+}
+
+func isObjectID(ref string) bool {
+	if len(ref) != 24 {
+		return false
+	}
+	for _, r := range ref {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", r) {
+			return false
+		}
+	}
+	return true
+}
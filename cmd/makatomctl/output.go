@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"makatom-api-config/pkg/client"
+)
+
+// printValue writes v as JSON or YAML; YAML keys follow the JSON field names
+func printValue(w io.Writer, format string, v interface{}) error {
+	if format == "json" {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	}
+
+	generic, err := toGeneric(v)
+	if err != nil {
+		return err
+	}
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	defer encoder.Close()
+	return encoder.Encode(generic)
+}
+
+// toGeneric round-trips v through JSON so YAML output uses the JSON field names
+func toGeneric(v interface{}) (interface{}, error) {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var generic interface{}
+	if err := json.Unmarshal(encoded, &generic); err != nil {
+		return nil, err
+	}
+	return generic, nil
+}
+
+// decodeFile reads JSON or YAML from path ("-" for stdin) into out
+func decodeFile(path string, out interface{}) error {
+	var (
+		raw []byte
+		err error
+	)
+	if path == "-" {
+		raw, err = io.ReadAll(os.Stdin)
+	} else {
+		raw, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return err
+	}
+
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".json" {
+		return json.Unmarshal(raw, out)
+	}
+
+	// YAML is a superset of JSON; decode generically, then map onto out via JSON
+	var generic interface{}
+	if err := yaml.Unmarshal(raw, &generic); err != nil {
+		return err
+	}
+	encoded, err := json.Marshal(generic)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(encoded, out)
+}
+
+func (a *app) printConfig(config client.Config) error {
+	if a.format != "table" {
+		return printValue(a.out, a.format, config)
+	}
+
+	tw := tabwriter.NewWriter(a.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(tw, "ID:\t%s\n", config.ID)
+	fmt.Fprintf(tw, "Name:\t%s\n", config.Name)
+	fmt.Fprintf(tw, "Namespace:\t%s\n", config.Namespace)
+	fmt.Fprintf(tw, "Type:\t%s\n", config.Type)
+	fmt.Fprintf(tw, "Subtype:\t%s\n", config.Subtype)
+	fmt.Fprintf(tw, "Tags:\t%s\n", strings.Join(config.Tags, ", "))
+	fmt.Fprintf(tw, "Schema version:\t%s\n", config.SchemaVersion)
+	fmt.Fprintf(tw, "Updated:\t%s by %s\n", config.UpdatedAt.Format(time.RFC3339), config.LastUpdatedBy)
+	if config.Env != "" {
+		fmt.Fprintf(tw, "Environment:\t%s\n", config.Env)
+	}
+	fmt.Fprintln(tw, "Metadata:\t")
+	for _, key := range sortedKeys(config.Metadata) {
+		fmt.Fprintf(tw, "  %s:\t%s\n", key, formatScalar(config.Metadata[key]))
+	}
+	envs := make([]string, 0, len(config.Overlays))
+	for env := range config.Overlays {
+		envs = append(envs, env)
+	}
+	sort.Strings(envs)
+	for _, env := range envs {
+		fmt.Fprintf(tw, "Overlay %s:\t\n", env)
+		for _, key := range sortedKeys(config.Overlays[env]) {
+			fmt.Fprintf(tw, "  %s:\t%s\n", key, formatScalar(config.Overlays[env][key]))
+		}
+	}
+	return tw.Flush()
+}
+
+func (a *app) printConfigs(configs []client.Config, total int64) error {
+	if a.format != "table" {
+		return printValue(a.out, a.format, configs)
+	}
+
+	tw := tabwriter.NewWriter(a.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tNAMESPACE\tTYPE\tSUBTYPE\tNAME\tTAGS\tUPDATED")
+	for _, config := range configs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			config.ID, config.Namespace, config.Type, config.Subtype, config.Name,
+			strings.Join(config.Tags, ","), config.UpdatedAt.Format(time.RFC3339))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if total > int64(len(configs)) {
+		fmt.Fprintf(a.out, "(%d of %d shown)\n", len(configs), total)
+	}
+	return nil
+}
+
+func (a *app) printArchives(archives []client.ConfigArchive) error {
+	if a.format != "table" {
+		return printValue(a.out, a.format, archives)
+	}
+
+	tw := tabwriter.NewWriter(a.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ARCHIVE ID\tVERSION\tARCHIVED AT\tARCHIVED BY\tSCHEMA")
+	for _, archive := range archives {
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\n",
+			archive.ID, archive.Version, archive.ArchivedAt.Format(time.RFC3339),
+			archive.ArchivedBy, archive.SchemaVersion)
+	}
+	return tw.Flush()
+}
+
+// formatScalar renders a metadata value on a single line
+func formatScalar(v interface{}) string {
+	switch v.(type) {
+	case map[string]interface{}, []interface{}:
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	}
+	return fmt.Sprint(v)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
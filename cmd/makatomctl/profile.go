@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"makatom-api-config/pkg/client"
+)
+
+const defaultEndpoint = "http://localhost:8080"
+
+// Profile holds the endpoint and credentials of one config service
+type Profile struct {
+	Endpoint string `json:"endpoint"`
+	Token    string `json:"token,omitempty"`
+	APIKey   string `json:"api_key,omitempty"`
+}
+
+// profileFile is the on-disk profile configuration
+type profileFile struct {
+	Current  string             `json:"current"`
+	Profiles map[string]Profile `json:"profiles"`
+}
+
+// profilePath returns $MAKATOMCTL_CONFIG or the per-user config location
+func profilePath() (string, error) {
+	if path := os.Getenv("MAKATOMCTL_CONFIG"); path != "" {
+		return path, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "makatomctl", "config.json"), nil
+}
+
+func loadProfiles() (profileFile, error) {
+	file := profileFile{Profiles: map[string]Profile{}}
+
+	path, err := profilePath()
+	if err != nil {
+		return file, err
+	}
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return file, nil
+	}
+	if err != nil {
+		return file, err
+	}
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return file, fmt.Errorf("invalid profile config %s: %v", path, err)
+	}
+	if file.Profiles == nil {
+		file.Profiles = map[string]Profile{}
+	}
+	return file, nil
+}
+
+// saveProfiles writes the profile config; it holds credentials, so only the owner may read it
+func saveProfiles(file profileFile) error {
+	path, err := profilePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	encoded, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, encoded, 0o600)
+}
+
+// resolveProfile returns the named (or current) profile with environment overrides applied
+func resolveProfile(name string) (Profile, error) {
+	file, err := loadProfiles()
+	if err != nil {
+		return Profile{}, err
+	}
+
+	if name == "" {
+		name = os.Getenv("MAKATOM_PROFILE")
+	}
+	if name == "" {
+		name = file.Current
+	}
+
+	profile := Profile{Endpoint: defaultEndpoint}
+	if name != "" {
+		stored, ok := file.Profiles[name]
+		if !ok {
+			return Profile{}, fmt.Errorf("unknown profile %q", name)
+		}
+		profile = stored
+	}
+
+	if endpoint := os.Getenv("MAKATOM_ENDPOINT"); endpoint != "" {
+		profile.Endpoint = endpoint
+	}
+	if token := os.Getenv("MAKATOM_TOKEN"); token != "" {
+		profile.Token = token
+	}
+	if key := os.Getenv("MAKATOM_API_KEY"); key != "" {
+		profile.APIKey = key
+	}
+	return profile, nil
+}
+
+// client creates an API client for the profile; API keys take precedence over tokens
+func (p Profile) client() *client.Client {
+	var opts []client.Option
+	switch {
+	case p.APIKey != "":
+		opts = append(opts, client.WithAPIKey(p.APIKey))
+	case p.Token != "":
+		opts = append(opts, client.WithToken(p.Token))
+	}
+	return client.New(p.Endpoint, opts...)
+}
+
+func runProfile(ctx context.Context, a *app, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: makatomctl " + commands["profile"].usage)
+	}
+
+	file, err := loadProfiles()
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "list":
+		names := make([]string, 0, len(file.Profiles))
+		for name := range file.Profiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			marker := " "
+			if name == file.Current {
+				marker = "*"
+			}
+			fmt.Fprintf(a.out, "%s %-16s %s\n", marker, name, file.Profiles[name].Endpoint)
+		}
+		return nil
+
+	case "use":
+		if len(args) != 2 {
+			return errors.New("usage: makatomctl profile use <name>")
+		}
+		if _, ok := file.Profiles[args[1]]; !ok {
+			return fmt.Errorf("unknown profile %q", args[1])
+		}
+		file.Current = args[1]
+		return saveProfiles(file)
+
+	case "set":
+		fs := flag.NewFlagSet("profile set", flag.ContinueOnError)
+		endpoint := fs.String("endpoint", "", "config service URL")
+		token := fs.String("token", "", "user JWT")
+		apiKey := fs.String("api-key", "", "service-account API key")
+		positional, err := parseFlags(fs, args[1:])
+		if err != nil {
+			return err
+		}
+		if len(positional) != 1 {
+			return errors.New("usage: makatomctl profile set <name> [--endpoint URL] [--token T] [--api-key K]")
+		}
+
+		name := positional[0]
+		profile, exists := file.Profiles[name]
+		if !exists {
+			profile.Endpoint = defaultEndpoint
+		}
+		if *endpoint != "" {
+			profile.Endpoint = *endpoint
+		}
+		if *token != "" {
+			profile.Token = *token
+		}
+		if *apiKey != "" {
+			profile.APIKey = *apiKey
+		}
+		file.Profiles[name] = profile
+		if file.Current == "" {
+			file.Current = name
+		}
+		return saveProfiles(file)
+	}
+
+	return fmt.Errorf("unknown profile command %q", args[0])
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"makatom-api-config/pkg/client"
+)
+
+// stringList is a repeatable string flag
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// envOptions controls how config metadata is mapped to environment variables
+type envOptions struct {
+	configs    stringList
+	tags       stringList
+	configType string
+	prefix     string
+	casing     string
+	qualify    bool
+	secrets    stringList
+	allSecrets bool
+}
+
+func runRun(ctx context.Context, a *app, args []string) error {
+	fs := flag.NewFlagSet("run", flag.ContinueOnError)
+	var opts envOptions
+	fs.Var(&opts.configs, "config", "config to inject as type/[subtype/]name or ID (repeatable)")
+	fs.Var(&opts.tags, "tag", "inject every config with this tag (repeatable)")
+	fs.StringVar(&opts.configType, "type", "", "inject every config of this type")
+	fs.StringVar(&opts.prefix, "prefix", "", "prefix for every variable name")
+	fs.StringVar(&opts.casing, "case", "upper", "variable name casing: upper, lower or keep")
+	fs.BoolVar(&opts.qualify, "qualify", false, "prefix keys with the config name, e.g. MAIN_PASSWORD")
+	fs.Var(&opts.secrets, "secret", "encrypted field to decrypt and inject (repeatable)")
+	fs.BoolVar(&opts.allSecrets, "all-secrets", false, "decrypt and inject every encrypted field")
+	watch := fs.Bool("watch", false, "restart the child when an injected config changes")
+	interval := fs.Duration("interval", 30*time.Second, "polling interval for --watch")
+	grace := fs.Duration("grace", 10*time.Second, "time the child gets to exit after SIGTERM before it is killed")
+
+	// Everything after "--" is the child command and is not parsed as flags
+	var child []string
+	for i, arg := range args {
+		if arg == "--" {
+			args, child = args[:i], args[i+1:]
+			break
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() > 0 {
+		child = fs.Args()
+	}
+	if len(child) == 0 {
+		return errors.New("usage: makatomctl " + commands["run"].usage)
+	}
+	if len(opts.configs) == 0 && len(opts.tags) == 0 && opts.configType == "" {
+		return errors.New("select configs with --config, --tag or --type")
+	}
+	if opts.casing != "upper" && opts.casing != "lower" && opts.casing != "keep" {
+		return fmt.Errorf("unknown casing %q", opts.casing)
+	}
+
+	vars, err := a.buildEnv(ctx, opts)
+	if err != nil {
+		return err
+	}
+
+	path, err := exec.LookPath(child[0])
+	if err != nil {
+		return err
+	}
+
+	if !*watch {
+		// Replace this process so signals and the exit code belong to the child
+		return syscall.Exec(path, child, mergeEnv(os.Environ(), vars))
+	}
+	return a.superviseChild(ctx, opts, path, child, vars, *interval, *grace)
+}
+
+// superviseChild runs the child and restarts it whenever the injected variables change
+func (a *app) superviseChild(ctx context.Context, opts envOptions, path string, argv []string, vars map[string]string, interval, grace time.Duration) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		cmd := exec.Command(path, argv[1:]...)
+		cmd.Env = mergeEnv(os.Environ(), vars)
+		cmd.Stdin = os.Stdin
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Start(); err != nil {
+			return err
+		}
+
+		exited := make(chan error, 1)
+		go func() { exited <- cmd.Wait() }()
+
+		restart := false
+		for !restart {
+			select {
+			case err := <-exited:
+				// The child stopped on its own; mirror its exit code
+				var exitErr *exec.ExitError
+				if errors.As(err, &exitErr) {
+					os.Exit(exitErr.ExitCode())
+				}
+				return err
+
+			case sig := <-signals:
+				cmd.Process.Signal(sig)
+
+			case <-ticker.C:
+				next, err := a.buildEnv(ctx, opts)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "makatomctl: refresh failed, keeping the running child: %v\n", err)
+					continue
+				}
+				if reflect.DeepEqual(next, vars) {
+					continue
+				}
+				fmt.Fprintln(os.Stderr, "makatomctl: configs changed, restarting child")
+				vars = next
+				restart = true
+			}
+		}
+
+		cmd.Process.Signal(syscall.SIGTERM)
+		select {
+		case <-exited:
+		case <-time.After(grace):
+			cmd.Process.Kill()
+			<-exited
+		}
+	}
+}
+
+// buildEnv fetches the selected configs and maps their metadata to variables
+func (a *app) buildEnv(ctx context.Context, opts envOptions) (map[string]string, error) {
+	configs, err := a.selectConfigs(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := make(map[string]bool, len(opts.secrets))
+	for _, field := range opts.secrets {
+		allowed[field] = true
+	}
+
+	vars := make(map[string]string)
+	source := make(map[string]string)
+	for _, config := range configs {
+		encrypted := make(map[string]bool, len(config.EncryptedFields))
+		for _, field := range config.EncryptedFields {
+			encrypted[field] = true
+		}
+
+		for _, key := range sortedKeys(config.Metadata) {
+			value := config.Metadata[key]
+			if encrypted[key] {
+				// Encrypted fields are only injected when explicitly allowed
+				if !opts.allSecrets && !allowed[key] {
+					continue
+				}
+				result, err := a.client.Decrypt(ctx, config.ID, key)
+				if err != nil {
+					return nil, fmt.Errorf("failed to decrypt %s of %s: %v", key, config.Name, err)
+				}
+				value = result.DecryptedValue
+			}
+
+			name := opts.envName(config, key)
+			if previous, ok := source[name]; ok {
+				fmt.Fprintf(os.Stderr, "makatomctl: %s from %s overrides %s\n", name, config.Name, previous)
+			}
+			vars[name] = envValue(value)
+			source[name] = config.Name
+		}
+	}
+	return vars, nil
+}
+
+// selectConfigs resolves the --config, --tag and --type selections, without duplicates
+func (a *app) selectConfigs(ctx context.Context, opts envOptions) ([]client.Config, error) {
+	var configs []client.Config
+	seen := make(map[string]bool)
+	add := func(config client.Config) {
+		if !seen[config.ID] {
+			seen[config.ID] = true
+			configs = append(configs, config)
+		}
+	}
+
+	if opts.configType != "" || len(opts.tags) > 0 {
+		tags := opts.tags
+		if len(tags) == 0 {
+			tags = stringList{""}
+		}
+		for _, tag := range tags {
+			matched, err := a.client.ListAll(ctx, client.ListOptions{Type: opts.configType, Tag: tag})
+			if err != nil {
+				return nil, err
+			}
+			sort.Slice(matched, func(i, j int) bool { return matched[i].Name < matched[j].Name })
+			for _, config := range matched {
+				add(config)
+			}
+		}
+	}
+
+	// Explicitly named configs come last so their variables win on conflicts
+	for _, ref := range opts.configs {
+		config, err := a.resolveConfig(ctx, ref)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", ref, err)
+		}
+		add(config)
+	}
+	return configs, nil
+}
+
+// envName maps a metadata key to a variable name
+func (o envOptions) envName(config client.Config, key string) string {
+	name := key
+	if o.qualify {
+		name = config.Name + "_" + key
+	}
+	name = o.prefix + sanitizeEnvName(name)
+
+	switch o.casing {
+	case "upper":
+		return strings.ToUpper(name)
+	case "lower":
+		return strings.ToLower(name)
+	}
+	return name
+}
+
+// sanitizeEnvName replaces characters that are not valid in variable names
+func sanitizeEnvName(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// envValue renders a metadata value; objects and arrays are JSON encoded
+func envValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	}
+	encoded, _ := json.Marshal(value)
+	return string(encoded)
+}
+
+// mergeEnv overrides the inherited environment with the injected variables
+func mergeEnv(environ []string, vars map[string]string) []string {
+	merged := make([]string, 0, len(environ)+len(vars))
+	for _, entry := range environ {
+		name, _, _ := strings.Cut(entry, "=")
+		if _, ok := vars[name]; !ok {
+			merged = append(merged, entry)
+		}
+	}
+	for name, value := range vars {
+		merged = append(merged, name+"="+value)
+	}
+	return merged
+}
@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// Scope narrows a role binding to a subset of configs within a tenant.
+// Empty fields match everything.
+type Scope struct {
+	Type    string `bson:"type,omitempty" json:"type,omitempty"`
+	Subtype string `bson:"subtype,omitempty" json:"subtype,omitempty"`
+	Tag     string `bson:"tag,omitempty" json:"tag,omitempty"`
+}
+
+// Binding grants a role to a principal within a scope
+type Binding struct {
+	Role  Role  `json:"role"`
+	Scope Scope `json:"scope"`
+}
+
+// Resource describes the config an operation is applied to
+type Resource struct {
+	Type    string
+	Subtype string
+	Tags    []string
+}
+
+// Principal is the authenticated caller of a request
+type Principal struct {
+	Subject  string
+	TenantID string
+	Bindings []Binding
+	// ServiceAccount is true when the caller authenticated with an API key
+	ServiceAccount bool
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying the principal
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal stored in ctx, if any
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok && p != nil
+}
+
+// matches reports whether the scope covers the resource
+func (s Scope) matches(res Resource) bool {
+	if s.Type != "" && s.Type != res.Type {
+		return false
+	}
+	if s.Subtype != "" && s.Subtype != res.Subtype {
+		return false
+	}
+	if s.Tag != "" {
+		for _, tag := range res.Tags {
+			if tag == s.Tag {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// covers reports whether every config in the other scope is also in s
+func (s Scope) covers(other Scope) bool {
+	return (s.Type == "" || s.Type == other.Type) &&
+		(s.Subtype == "" || s.Subtype == other.Subtype) &&
+		(s.Tag == "" || s.Tag == other.Tag)
+}
+
+// isTenantWide reports whether the scope covers the whole tenant
+func (s Scope) isTenantWide() bool {
+	return s.Type == "" && s.Subtype == "" && s.Tag == ""
+}
+
+// HasPermission reports whether any binding grants perm, regardless of scope.
+// It is used as a coarse check before the resource is known.
+func (p *Principal) HasPermission(perm Permission) bool {
+	for _, b := range p.Bindings {
+		if RoleGrants(b.Role, perm) {
+			return true
+		}
+	}
+	return false
+}
+
+// Can reports whether the principal holds perm on the given resource
+func (p *Principal) Can(perm Permission, res Resource) bool {
+	for _, b := range p.Bindings {
+		if RoleGrants(b.Role, perm) && b.Scope.matches(res) {
+			return true
+		}
+	}
+	return false
+}
+
+// CanScope reports whether the principal holds perm on every config in the
+// scope, e.g. to grant or revoke access to it
+func (p *Principal) CanScope(perm Permission, scope Scope) bool {
+	for _, b := range p.Bindings {
+		if RoleGrants(b.Role, perm) && b.Scope.covers(scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// CanGrant reports whether the principal holds every permission of the
+// binding's role on every config in its scope, so it grants no more than it has
+func (p *Principal) CanGrant(binding Binding) bool {
+	for _, perm := range rolePermissions[binding.Role] {
+		if !p.CanScope(perm, binding.Scope) {
+			return false
+		}
+	}
+	return true
+}
+
+// ScopeFilter returns a MongoDB filter restricting configs to the ones the
+// principal holds perm on. The second value is false when nothing is allowed.
+func (p *Principal) ScopeFilter(perm Permission) (bson.M, bool) {
+	var clauses []bson.M
+	for _, b := range p.Bindings {
+		if !RoleGrants(b.Role, perm) {
+			continue
+		}
+		if b.Scope.isTenantWide() {
+			return bson.M{}, true
+		}
+
+		clause := bson.M{}
+		if b.Scope.Type != "" {
+			clause["type"] = b.Scope.Type
+		}
+		if b.Scope.Subtype != "" {
+			clause["subtype"] = b.Scope.Subtype
+		}
+		if b.Scope.Tag != "" {
+			clause["tags"] = b.Scope.Tag
+		}
+		clauses = append(clauses, clause)
+	}
+
+	if len(clauses) == 0 {
+		return nil, false
+	}
+	return bson.M{"$or": clauses}, true
+}
@@ -0,0 +1,90 @@
+package auth
+
+// Role is a named set of permissions that can be bound to a subject
+type Role string
+
+// Permission is a single operation guarded by the config service
+type Permission string
+
+const (
+	RoleViewer       Role = "viewer"
+	RoleEditor       Role = "editor"
+	RoleSecretReader Role = "secret-reader"
+	RoleReviewer     Role = "reviewer"
+	RoleAdmin        Role = "admin"
+)
+
+const (
+	// PermConfigRead allows reading configs and their archives (encrypted fields stay encrypted)
+	PermConfigRead Permission = "config:read"
+	// PermConfigWrite allows creating and updating configs
+	PermConfigWrite Permission = "config:write"
+	// PermConfigDelete allows deleting configs
+	PermConfigDelete Permission = "config:delete"
+	// PermConfigReview allows approving and rejecting drafts of config changes
+	PermConfigReview Permission = "config:review"
+	// PermSecretRead allows decrypting fields marked with encryption=true
+	PermSecretRead Permission = "secret:read"
+	// PermTypeRead allows reading the type registry and validating metadata
+	PermTypeRead Permission = "type:read"
+	// PermTypeManage allows managing tenant-defined types and subtypes
+	PermTypeManage Permission = "type:manage"
+	// PermRBACManage allows managing role bindings
+	PermRBACManage Permission = "rbac:manage"
+	// PermAPIKeyManage allows creating, listing and revoking service-account API keys
+	PermAPIKeyManage Permission = "apikey:manage"
+	// PermOpsRead allows reading the operational state of the service, e.g. cache statistics
+	PermOpsRead Permission = "ops:read"
+)
+
+// rolePermissions maps each role to the permissions it grants
+var rolePermissions = map[Role][]Permission{
+	RoleViewer: {
+		PermConfigRead,
+		PermTypeRead,
+	},
+	RoleEditor: {
+		PermConfigRead,
+		PermConfigWrite,
+		PermConfigDelete,
+		PermTypeRead,
+	},
+	RoleSecretReader: {
+		PermConfigRead,
+		PermSecretRead,
+		PermTypeRead,
+	},
+	RoleReviewer: {
+		PermConfigRead,
+		PermConfigReview,
+		PermTypeRead,
+	},
+	RoleAdmin: {
+		PermConfigRead,
+		PermConfigWrite,
+		PermConfigDelete,
+		PermConfigReview,
+		PermSecretRead,
+		PermTypeRead,
+		PermTypeManage,
+		PermRBACManage,
+		PermAPIKeyManage,
+		PermOpsRead,
+	},
+}
+
+// IsValidRole reports whether the role is known
+func IsValidRole(role Role) bool {
+	_, ok := rolePermissions[role]
+	return ok
+}
+
+// RoleGrants reports whether the role grants the permission
+func RoleGrants(role Role, perm Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
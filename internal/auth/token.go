@@ -0,0 +1,84 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrExpiredToken = errors.New("token expired")
+)
+
+// Claims are the JWT claims the config service relies on
+type Claims struct {
+	Subject   string   `json:"sub"`
+	TenantID  string   `json:"tenant_id"`
+	Roles     []string `json:"roles,omitempty"`
+	ExpiresAt int64    `json:"exp,omitempty"`
+}
+
+// TokenVerifier verifies HS256 signed JWTs issued by the identity service
+type TokenVerifier struct {
+	secret []byte
+}
+
+// NewTokenVerifier creates a new TokenVerifier with the shared signing secret
+func NewTokenVerifier(secret string) *TokenVerifier {
+	return &TokenVerifier{secret: []byte(secret)}
+}
+
+// Verify checks the token signature and expiry and returns its claims
+func (v *TokenVerifier) Verify(token string) (Claims, error) {
+	var claims Claims
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || len(v.secret) == 0 {
+		return claims, ErrInvalidToken
+	}
+
+	// Only HS256 is accepted
+	headerJSON, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return claims, ErrInvalidToken
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := json.Unmarshal(headerJSON, &header); err != nil || header.Alg != "HS256" {
+		return claims, ErrInvalidToken
+	}
+
+	// Verify the signature over "header.payload"
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return claims, ErrInvalidToken
+	}
+	mac := hmac.New(sha256.New, v.secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return claims, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return claims, ErrInvalidToken
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return claims, ErrInvalidToken
+	}
+
+	if claims.Subject == "" || claims.TenantID == "" {
+		return claims, ErrInvalidToken
+	}
+	if claims.ExpiresAt != 0 && time.Now().Unix() >= claims.ExpiresAt {
+		return claims, ErrExpiredToken
+	}
+
+	return claims, nil
+}
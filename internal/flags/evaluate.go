@@ -0,0 +1,180 @@
+package flags
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"makatom-api-config/internal/models"
+)
+
+// Evaluation reasons, named after the OpenFeature resolution reasons
+const (
+	ReasonStatic         = "STATIC"
+	ReasonDefault        = "DEFAULT"
+	ReasonTargetingMatch = "TARGETING_MATCH"
+	ReasonSplit          = "SPLIT"
+	ReasonDisabled       = "DISABLED"
+	ReasonError          = "ERROR"
+)
+
+// ErrorTargetingKeyMissing is reported when a rollout has no attribute to bucket by
+const ErrorTargetingKeyMissing = "TARGETING_KEY_MISSING"
+
+// buckets is the resolution of rollouts: weights are honoured to 0.01%
+const buckets = 10000
+
+// Result is the outcome of evaluating a flag for a context
+type Result struct {
+	Variant   string      `json:"variant"`
+	Value     interface{} `json:"value"`
+	Reason    string      `json:"reason"`
+	Rule      string      `json:"rule,omitempty"`
+	ErrorCode string      `json:"error_code,omitempty"`
+}
+
+// Evaluate picks the variant of the flag for the evaluation context. key
+// identifies the flag and salts the rollout buckets, so contexts land in
+// different buckets for different flags.
+func (f Flag) Evaluate(key string, context map[string]interface{}) Result {
+	context = models.PlainValue(context).(map[string]interface{})
+
+	if f.Enabled != nil && !*f.Enabled {
+		variant := f.OffVariant
+		if variant == "" {
+			variant = f.DefaultVariant
+		}
+		return f.serve(variant, ReasonDisabled, "")
+	}
+
+	for i, rule := range f.Rules {
+		if !rule.matches(context) {
+			continue
+		}
+		name := rule.Name
+		if name == "" {
+			name = fmt.Sprintf("rule %d", i+1)
+		}
+		if rule.Variant != "" {
+			return f.serve(rule.Variant, ReasonTargetingMatch, name)
+		}
+		return f.split(key, rule.Rollout, context, name)
+	}
+
+	if len(f.Rollout) > 0 {
+		return f.split(key, f.Rollout, context, "")
+	}
+	if len(f.Rules) == 0 {
+		return f.serve(f.DefaultVariant, ReasonStatic, "")
+	}
+	return f.serve(f.DefaultVariant, ReasonDefault, "")
+}
+
+func (f Flag) serve(variant, reason, rule string) Result {
+	return Result{
+		Variant: variant,
+		Value:   f.Variants[variant],
+		Reason:  reason,
+		Rule:    rule,
+	}
+}
+
+// split serves the rollout variant whose cumulative weight covers the context's bucket
+func (f Flag) split(key string, rollout []Split, context map[string]interface{}, rule string) Result {
+	attribute := f.BucketBy
+	if attribute == "" {
+		attribute = TargetingKey
+	}
+	value, present := context[attribute]
+	if !present || value == nil || value == "" {
+		result := f.serve(f.DefaultVariant, ReasonError, rule)
+		result.ErrorCode = ErrorTargetingKeyMissing
+		return result
+	}
+
+	bucket := Bucket(key, fmt.Sprint(value))
+	cumulative := 0.0
+	for _, split := range rollout {
+		cumulative += split.Weight * buckets / 100
+		if float64(bucket) < cumulative {
+			return f.serve(split.Variant, ReasonSplit, rule)
+		}
+	}
+	// Only reachable through rounding when the weights add up to 100
+	return f.serve(rollout[len(rollout)-1].Variant, ReasonSplit, rule)
+}
+
+// Bucket consistently maps a flag key and bucketing value to [0, 10000)
+func Bucket(key, value string) int {
+	sum := sha256.Sum256([]byte(key + "/" + value))
+	return int(binary.BigEndian.Uint64(sum[:8]) % buckets)
+}
+
+// matches reports whether all conditions of the rule hold for the context
+func (r Rule) matches(context map[string]interface{}) bool {
+	for _, condition := range r.Conditions {
+		if !condition.matches(context) {
+			return false
+		}
+	}
+	return true
+}
+
+// matches evaluates the condition; a missing attribute only satisfies neq and not_in
+func (c Condition) matches(context map[string]interface{}) bool {
+	actual, present := context[c.Attribute]
+	if !present || actual == nil {
+		return c.Operator == "neq" || c.Operator == "not_in"
+	}
+
+	switch c.Operator {
+	case "eq":
+		return reflect.DeepEqual(actual, c.Value)
+	case "neq":
+		return !reflect.DeepEqual(actual, c.Value)
+	case "in", "not_in":
+		found := false
+		for _, candidate := range c.Value.([]interface{}) {
+			if reflect.DeepEqual(actual, candidate) {
+				found = true
+				break
+			}
+		}
+		return found == (c.Operator == "in")
+	case "contains", "starts_with", "ends_with", "matches":
+		text, ok := actual.(string)
+		if !ok {
+			return false
+		}
+		operand := c.Value.(string)
+		switch c.Operator {
+		case "contains":
+			return strings.Contains(text, operand)
+		case "starts_with":
+			return strings.HasPrefix(text, operand)
+		case "ends_with":
+			return strings.HasSuffix(text, operand)
+		}
+		matched, err := regexp.MatchString(operand, text)
+		return err == nil && matched
+	case "gt", "gte", "lt", "lte":
+		number, ok := actual.(float64)
+		if !ok {
+			return false
+		}
+		operand := c.Value.(float64)
+		switch c.Operator {
+		case "gt":
+			return number > operand
+		case "gte":
+			return number >= operand
+		case "lt":
+			return number < operand
+		}
+		return number <= operand
+	}
+	return false
+}
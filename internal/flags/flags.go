@@ -0,0 +1,292 @@
+// Package flags implements the built-in feature_flag config type.
+//
+// A flag's metadata lists its variants and how one is chosen for an evaluation
+// context: a disabled flag serves its off variant; otherwise the first rule
+// whose conditions all match the context serves its variant or splits its
+// rollout; failing that, the flag-level rollout or the default variant is
+// served. Rollouts bucket the context consistently by hashing the flag key
+// with the bucketing attribute, so a context keeps its variant while the
+// rollout percentages only grow.
+package flags
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sort"
+
+	"makatom-api-config/internal/models"
+)
+
+// TypeName is the name of the built-in feature flag config type
+const TypeName = "feature_flag"
+
+const (
+	SubtypeBoolean      = "boolean"
+	SubtypeMultivariate = "multivariate"
+	SubtypeJSON         = "json"
+)
+
+// TargetingKey is the context attribute rollouts bucket by unless bucket_by says otherwise
+const TargetingKey = "targeting_key"
+
+// Flag is the metadata of a feature flag config
+type Flag struct {
+	Enabled        *bool                  `json:"enabled"`
+	Variants       map[string]interface{} `json:"variants"`
+	DefaultVariant string                 `json:"default_variant"`
+	OffVariant     string                 `json:"off_variant,omitempty"`
+	Rules          []Rule                 `json:"rules,omitempty"`
+	Rollout        []Split                `json:"rollout,omitempty"`
+	BucketBy       string                 `json:"bucket_by,omitempty"`
+}
+
+// Rule serves Variant, or splits Rollout, when all of its conditions match
+type Rule struct {
+	Name       string      `json:"name,omitempty"`
+	Conditions []Condition `json:"conditions"`
+	Variant    string      `json:"variant,omitempty"`
+	Rollout    []Split     `json:"rollout,omitempty"`
+}
+
+// Condition compares a context attribute with a value
+type Condition struct {
+	Attribute string      `json:"attribute"`
+	Operator  string      `json:"operator"`
+	Value     interface{} `json:"value"`
+}
+
+// Split assigns a percentage of the contexts to a variant
+type Split struct {
+	Variant string  `json:"variant"`
+	Weight  float64 `json:"weight"`
+}
+
+// operators lists the supported condition operators
+var operators = map[string]bool{
+	"eq":          true,
+	"neq":         true,
+	"in":          true,
+	"not_in":      true,
+	"contains":    true,
+	"starts_with": true,
+	"ends_with":   true,
+	"matches":     true,
+	"gt":          true,
+	"gte":         true,
+	"lt":          true,
+	"lte":         true,
+}
+
+// IsSubtype reports whether the subtype is one of the built-in flag subtypes
+func IsSubtype(subtype string) bool {
+	return subtype == SubtypeBoolean || subtype == SubtypeMultivariate || subtype == SubtypeJSON
+}
+
+// TypeView describes the feature_flag type in the merged registry view
+func TypeView() models.TypeView {
+	descriptions := map[string]string{
+		SubtypeBoolean:      "Feature flag with boolean variants",
+		SubtypeMultivariate: "Feature flag with string or number variants",
+		SubtypeJSON:         "Feature flag with JSON object or array variants",
+	}
+
+	view := models.TypeView{
+		Name:        TypeName,
+		Description: "Feature flags evaluated against a context with targeting rules and percentage rollouts",
+		Source:      models.TypeSourceBuiltin,
+		Subtypes:    make(map[string]models.SubtypeView),
+	}
+	for name, description := range descriptions {
+		view.Subtypes[name] = models.SubtypeView{
+			Name:           name,
+			Description:    description,
+			Source:         models.TypeSourceBuiltin,
+			MetadataSchema: schema(),
+		}
+	}
+	return view
+}
+
+// schema is the metadata schema shared by the flag subtypes; the variant
+// values and the rule structure are checked by Validate
+func schema() models.MetadataSchema {
+	return models.MetadataSchema{Properties: map[string]models.FieldSchema{
+		"enabled":         {Type: "boolean", Required: true, Description: "Whether the flag is evaluated; disabled flags serve the off variant"},
+		"variants":        {Type: "object", Required: true, Description: "Variant names mapped to the values they serve"},
+		"default_variant": {Type: "string", Required: true, Description: "Variant served when no rule matches"},
+		"off_variant":     {Type: "string", Description: "Variant served while disabled; defaults to default_variant"},
+		"rules":           {Type: "array", Description: "Targeting rules, evaluated in order"},
+		"rollout":         {Type: "array", Description: "Percentage split served when no rule matches"},
+		"bucket_by":       {Type: "string", Default: TargetingKey, Description: "Context attribute rollouts bucket by"},
+	}}
+}
+
+// Parse decodes and validates flag metadata of the given subtype
+func Parse(subtype string, metadata map[string]interface{}) (Flag, []string) {
+	if !IsSubtype(subtype) {
+		return Flag{}, []string{fmt.Sprintf("feature flags must have subtype %s, %s or %s", SubtypeBoolean, SubtypeMultivariate, SubtypeJSON)}
+	}
+
+	encoded, err := json.Marshal(models.PlainValue(metadata))
+	if err != nil {
+		return Flag{}, []string{fmt.Sprintf("invalid flag: %v", err)}
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.DisallowUnknownFields()
+	var flag Flag
+	if err := decoder.Decode(&flag); err != nil {
+		return Flag{}, []string{fmt.Sprintf("invalid flag: %v", err)}
+	}
+
+	return flag, flag.validate(subtype)
+}
+
+// Validate checks flag metadata of the given subtype
+func Validate(subtype string, metadata map[string]interface{}) []string {
+	_, errs := Parse(subtype, metadata)
+	return errs
+}
+
+func (f Flag) validate(subtype string) []string {
+	var errs []string
+	if f.Enabled == nil {
+		errs = append(errs, `field "enabled" is required`)
+	}
+	if len(f.Variants) == 0 {
+		errs = append(errs, `field "variants" must define at least one variant`)
+	}
+	for _, name := range sortedKeys(f.Variants) {
+		if !matchesSubtype(subtype, f.Variants[name]) {
+			errs = append(errs, fmt.Sprintf("variant %q does not hold a %s value", name, subtype))
+		}
+	}
+
+	if f.DefaultVariant == "" {
+		errs = append(errs, `field "default_variant" is required`)
+	} else if _, exists := f.Variants[f.DefaultVariant]; !exists {
+		errs = append(errs, fmt.Sprintf("default_variant %q is not a variant", f.DefaultVariant))
+	}
+	if f.OffVariant != "" {
+		if _, exists := f.Variants[f.OffVariant]; !exists {
+			errs = append(errs, fmt.Sprintf("off_variant %q is not a variant", f.OffVariant))
+		}
+	}
+
+	for i, rule := range f.Rules {
+		label := fmt.Sprintf("rule %d", i+1)
+		if rule.Name != "" {
+			label = fmt.Sprintf("rule %q", rule.Name)
+		}
+		if len(rule.Conditions) == 0 {
+			errs = append(errs, fmt.Sprintf("%s must have at least one condition", label))
+		}
+		for j, condition := range rule.Conditions {
+			if err := condition.validate(); err != "" {
+				errs = append(errs, fmt.Sprintf("%s condition %d: %s", label, j+1, err))
+			}
+		}
+		switch {
+		case rule.Variant != "" && len(rule.Rollout) > 0:
+			errs = append(errs, fmt.Sprintf("%s must serve either a variant or a rollout", label))
+		case rule.Variant != "":
+			if _, exists := f.Variants[rule.Variant]; !exists {
+				errs = append(errs, fmt.Sprintf("%s serves unknown variant %q", label, rule.Variant))
+			}
+		case len(rule.Rollout) > 0:
+			errs = append(errs, f.validateRollout(label+" rollout", rule.Rollout)...)
+		default:
+			errs = append(errs, fmt.Sprintf("%s must serve a variant or a rollout", label))
+		}
+	}
+
+	if len(f.Rollout) > 0 {
+		errs = append(errs, f.validateRollout("rollout", f.Rollout)...)
+	}
+	return errs
+}
+
+// validateRollout checks that a split serves known variants and adds up to 100%
+func (f Flag) validateRollout(label string, rollout []Split) []string {
+	var errs []string
+	total := 0.0
+	for _, split := range rollout {
+		if _, exists := f.Variants[split.Variant]; !exists {
+			errs = append(errs, fmt.Sprintf("%s serves unknown variant %q", label, split.Variant))
+		}
+		if split.Weight < 0 {
+			errs = append(errs, fmt.Sprintf("%s weight of %q must not be negative", label, split.Variant))
+		}
+		total += split.Weight
+	}
+	if math.Abs(total-100) > 1e-9 {
+		errs = append(errs, fmt.Sprintf("%s weights must add up to 100, got %v", label, total))
+	}
+	return errs
+}
+
+func (c Condition) validate() string {
+	if c.Attribute == "" {
+		return "attribute is required"
+	}
+	if !operators[c.Operator] {
+		return fmt.Sprintf("unsupported operator %q", c.Operator)
+	}
+	switch c.Operator {
+	case "in", "not_in":
+		if _, ok := c.Value.([]interface{}); !ok {
+			return fmt.Sprintf("operator %s needs an array value", c.Operator)
+		}
+	case "contains", "starts_with", "ends_with":
+		if _, ok := c.Value.(string); !ok {
+			return fmt.Sprintf("operator %s needs a string value", c.Operator)
+		}
+	case "matches":
+		pattern, ok := c.Value.(string)
+		if !ok {
+			return "operator matches needs a string value"
+		}
+		if _, err := regexp.Compile(pattern); err != nil {
+			return fmt.Sprintf("invalid pattern: %v", err)
+		}
+	case "gt", "gte", "lt", "lte":
+		if _, ok := c.Value.(float64); !ok {
+			return fmt.Sprintf("operator %s needs a number value", c.Operator)
+		}
+	}
+	return ""
+}
+
+// matchesSubtype reports whether a variant value fits the flag subtype
+func matchesSubtype(subtype string, value interface{}) bool {
+	switch subtype {
+	case SubtypeBoolean:
+		_, ok := value.(bool)
+		return ok
+	case SubtypeMultivariate:
+		switch value.(type) {
+		case string, float64:
+			return true
+		}
+		return false
+	case SubtypeJSON:
+		if value == nil {
+			return false
+		}
+		kind := reflect.TypeOf(value).Kind()
+		return kind == reflect.Map || kind == reflect.Slice
+	}
+	return false
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
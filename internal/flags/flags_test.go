@@ -0,0 +1,299 @@
+package flags
+
+import (
+	"fmt"
+	"testing"
+
+	"makatom-api-config/internal/models"
+)
+
+func TestBucket(t *testing.T) {
+	tests := []struct {
+		key   string
+		value string
+		want  int
+	}{
+		// Buckets are persisted implicitly by every rollout; changing them moves users
+		{"new-checkout", "user-42", 3218},
+		{"new-checkout", "user-43", 3169},
+		{"dark-mode", "user-42", 2081},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key+"/"+tt.value, func(t *testing.T) {
+			for i := 0; i < 3; i++ {
+				if got := Bucket(tt.key, tt.value); got != tt.want {
+					t.Fatalf("Bucket(%q, %q) = %d, want %d", tt.key, tt.value, got, tt.want)
+				}
+			}
+		})
+	}
+
+	for i := 0; i < 1000; i++ {
+		if bucket := Bucket("range", fmt.Sprintf("user-%d", i)); bucket < 0 || bucket >= buckets {
+			t.Fatalf("Bucket(%q, %q) = %d, out of [0, %d)", "range", fmt.Sprintf("user-%d", i), bucket, buckets)
+		}
+	}
+}
+
+func TestEvaluateSplitBoundaries(t *testing.T) {
+	const key = "new-checkout"
+	// user-42 lands in bucket 3218 of new-checkout, i.e. at 32.18%
+	context := map[string]interface{}{TargetingKey: "user-42"}
+
+	tests := []struct {
+		name    string
+		weights [2]float64
+		want    string
+	}{
+		{"below the first split", [2]float64{32.19, 67.81}, "on"},
+		{"at the boundary", [2]float64{32.18, 67.82}, "off"},
+		{"above the first split", [2]float64{32.17, 67.83}, "off"},
+		{"all on", [2]float64{100, 0}, "on"},
+		{"all off", [2]float64{0, 100}, "off"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flag := parseFlag(t, SubtypeBoolean, map[string]interface{}{
+				"enabled":         true,
+				"variants":        map[string]interface{}{"on": true, "off": false},
+				"default_variant": "off",
+				"rollout": []interface{}{
+					map[string]interface{}{"variant": "on", "weight": tt.weights[0]},
+					map[string]interface{}{"variant": "off", "weight": tt.weights[1]},
+				},
+			})
+			result := flag.Evaluate(key, context)
+			if result.Variant != tt.want || result.Reason != ReasonSplit {
+				t.Fatalf("got variant %q reason %s, want %q reason %s", result.Variant, result.Reason, tt.want, ReasonSplit)
+			}
+		})
+	}
+}
+
+func TestEvaluateBucketBy(t *testing.T) {
+	flag := parseFlag(t, SubtypeBoolean, map[string]interface{}{
+		"enabled":         true,
+		"variants":        map[string]interface{}{"on": true, "off": false},
+		"default_variant": "off",
+		"bucket_by":       "org",
+		"rollout": []interface{}{
+			map[string]interface{}{"variant": "on", "weight": 50.0},
+			map[string]interface{}{"variant": "off", "weight": 50.0},
+		},
+	})
+
+	// Every user of an organization gets the same variant
+	first := flag.Evaluate("new-checkout", map[string]interface{}{"org": "acme", TargetingKey: "user-1"})
+	for i := 2; i < 20; i++ {
+		result := flag.Evaluate("new-checkout", map[string]interface{}{"org": "acme", TargetingKey: fmt.Sprintf("user-%d", i)})
+		if result.Variant != first.Variant {
+			t.Fatalf("user-%d got %q, user-1 got %q", i, result.Variant, first.Variant)
+		}
+	}
+}
+
+func TestConditionMatches(t *testing.T) {
+	tests := []struct {
+		name     string
+		operator string
+		value    interface{}
+		actual   interface{}
+		want     bool
+	}{
+		{"in listed", "in", []interface{}{"DE", "FR"}, "DE", true},
+		{"in unlisted", "in", []interface{}{"DE", "FR"}, "US", false},
+		{"in number", "in", []interface{}{1.0, 2.0}, 2, true},
+		{"not_in listed", "not_in", []interface{}{"DE", "FR"}, "DE", false},
+		{"not_in unlisted", "not_in", []interface{}{"DE", "FR"}, "US", true},
+		{"matches", "matches", `^user-\d+$`, "user-42", true},
+		{"matches partially", "matches", `\d+`, "user-42", true},
+		{"matches not", "matches", `^admin-`, "user-42", false},
+		{"matches non-string", "matches", `^4`, 42.0, false},
+		{"gt greater", "gt", 10.0, 11.0, true},
+		{"gt equal", "gt", 10.0, 10.0, false},
+		{"gt smaller", "gt", 10.0, 9.5, false},
+		{"gt int attribute", "gt", 10.0, int64(11), true},
+		{"gt string attribute", "gt", 10.0, "11", false},
+		{"gte equal", "gte", 10.0, 10.0, true},
+		{"lt smaller", "lt", 10.0, 9.0, true},
+		{"lte equal", "lte", 10.0, 10.0, true},
+		{"eq", "eq", "beta", "beta", true},
+		{"neq", "neq", "beta", "stable", true},
+		{"contains", "contains", "@example", "dev@example.com", true},
+		{"starts_with", "starts_with", "dev@", "dev@example.com", true},
+		{"ends_with", "ends_with", ".org", "dev@example.com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			condition := Condition{Attribute: "attr", Operator: tt.operator, Value: tt.value}
+			if err := condition.validate(); err != "" {
+				t.Fatalf("invalid condition: %s", err)
+			}
+			context := models.PlainValue(map[string]interface{}{"attr": tt.actual}).(map[string]interface{})
+			if got := condition.matches(context); got != tt.want {
+				t.Fatalf("%s %v against %v = %v, want %v", tt.operator, tt.value, tt.actual, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConditionMissingAttribute(t *testing.T) {
+	tests := []struct {
+		operator string
+		value    interface{}
+		want     bool
+	}{
+		{"eq", "beta", false},
+		{"neq", "beta", true},
+		{"in", []interface{}{"beta"}, false},
+		{"not_in", []interface{}{"beta"}, true},
+		{"contains", "beta", false},
+		{"starts_with", "beta", false},
+		{"ends_with", "beta", false},
+		{"matches", ".*", false},
+		{"gt", 0.0, false},
+		{"gte", 0.0, false},
+		{"lt", 0.0, false},
+		{"lte", 0.0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.operator, func(t *testing.T) {
+			condition := Condition{Attribute: "attr", Operator: tt.operator, Value: tt.value}
+			for _, context := range []map[string]interface{}{{}, {"attr": nil}} {
+				if got := condition.matches(context); got != tt.want {
+					t.Fatalf("%s against %v = %v, want %v", tt.operator, context, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestEvaluateMissingTargetingKey(t *testing.T) {
+	flag := parseFlag(t, SubtypeBoolean, map[string]interface{}{
+		"enabled":         true,
+		"variants":        map[string]interface{}{"on": true, "off": false},
+		"default_variant": "off",
+		"rollout": []interface{}{
+			map[string]interface{}{"variant": "on", "weight": 100.0},
+		},
+	})
+
+	tests := []struct {
+		name    string
+		context map[string]interface{}
+	}{
+		{"absent", map[string]interface{}{"country": "DE"}},
+		{"null", map[string]interface{}{TargetingKey: nil}},
+		{"empty", map[string]interface{}{TargetingKey: ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := flag.Evaluate("new-checkout", tt.context)
+			if result.Variant != "off" || result.Reason != ReasonError || result.ErrorCode != ErrorTargetingKeyMissing {
+				t.Fatalf("got variant %q reason %s error %q, want the default variant with %s",
+					result.Variant, result.Reason, result.ErrorCode, ErrorTargetingKeyMissing)
+			}
+		})
+	}
+}
+
+func TestEvaluateRules(t *testing.T) {
+	flag := parseFlag(t, SubtypeMultivariate, map[string]interface{}{
+		"enabled":         true,
+		"variants":        map[string]interface{}{"blue": "blue", "green": "green", "red": "red"},
+		"default_variant": "blue",
+		"off_variant":     "red",
+		"rules": []interface{}{
+			map[string]interface{}{
+				"name":       "beta-testers",
+				"conditions": []interface{}{map[string]interface{}{"attribute": "group", "operator": "eq", "value": "beta"}},
+				"variant":    "green",
+			},
+			map[string]interface{}{
+				"conditions": []interface{}{map[string]interface{}{"attribute": "country", "operator": "in", "value": []interface{}{"DE"}}},
+				"variant":    "red",
+			},
+		},
+	})
+
+	tests := []struct {
+		name    string
+		context map[string]interface{}
+		variant string
+		reason  string
+		rule    string
+	}{
+		{"first rule", map[string]interface{}{"group": "beta", "country": "DE"}, "green", ReasonTargetingMatch, "beta-testers"},
+		{"unnamed rule", map[string]interface{}{"country": "DE"}, "red", ReasonTargetingMatch, "rule 2"},
+		{"no rule", map[string]interface{}{"country": "US"}, "blue", ReasonDefault, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := flag.Evaluate("color", tt.context)
+			if result.Variant != tt.variant || result.Reason != tt.reason || result.Rule != tt.rule {
+				t.Fatalf("got %q %s %q, want %q %s %q", result.Variant, result.Reason, result.Rule, tt.variant, tt.reason, tt.rule)
+			}
+		})
+	}
+
+	disabled := false
+	flag.Enabled = &disabled
+	if result := flag.Evaluate("color", map[string]interface{}{"group": "beta"}); result.Variant != "red" || result.Reason != ReasonDisabled {
+		t.Fatalf("disabled flag served %q %s, want the off variant", result.Variant, result.Reason)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() map[string]interface{} {
+		return map[string]interface{}{
+			"enabled":         true,
+			"variants":        map[string]interface{}{"on": true, "off": false},
+			"default_variant": "off",
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(map[string]interface{})
+		errs   int
+	}{
+		{"valid", func(map[string]interface{}) {}, 0},
+		{"unknown default", func(m map[string]interface{}) { m["default_variant"] = "maybe" }, 1},
+		{"non-boolean variant", func(m map[string]interface{}) { m["variants"].(map[string]interface{})["on"] = "yes" }, 1},
+		{"rollout not 100", func(m map[string]interface{}) {
+			m["rollout"] = []interface{}{map[string]interface{}{"variant": "on", "weight": 40.0}}
+		}, 1},
+		{"bad operator", func(m map[string]interface{}) {
+			m["rules"] = []interface{}{map[string]interface{}{
+				"conditions": []interface{}{map[string]interface{}{"attribute": "a", "operator": "like", "value": "x"}},
+				"variant":    "on",
+			}}
+		}, 1},
+		{"gt with a string", func(m map[string]interface{}) {
+			m["rules"] = []interface{}{map[string]interface{}{
+				"conditions": []interface{}{map[string]interface{}{"attribute": "a", "operator": "gt", "value": "10"}},
+				"variant":    "on",
+			}}
+		}, 1},
+		{"unknown field", func(m map[string]interface{}) { m["percentage"] = 10 }, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata := valid()
+			tt.modify(metadata)
+			if errs := Validate(SubtypeBoolean, metadata); len(errs) != tt.errs {
+				t.Fatalf("got errors %v, want %d", errs, tt.errs)
+			}
+		})
+	}
+}
+
+// parseFlag parses flag metadata that must be valid
+func parseFlag(t *testing.T, subtype string, metadata map[string]interface{}) Flag {
+	t.Helper()
+	flag, errs := Parse(subtype, metadata)
+	if len(errs) > 0 {
+		t.Fatalf("invalid flag: %v", errs)
+	}
+	return flag
+}
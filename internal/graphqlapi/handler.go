@@ -0,0 +1,107 @@
+// Package graphqlapi serves configs, their archives and references and the
+// type registry through a GraphQL endpoint. Fields are resolved through the
+// config and type services, so permissions and decryption match the HTTP API.
+// Related data of the configs returned together is loaded in one query per
+// field rather than one per config.
+package graphqlapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"makatom-api-config/internal/auth"
+	"makatom-api-config/internal/models"
+	"makatom-api-config/internal/services"
+	"makatom/common/pkg/handlers"
+	"makatom/common/pkg/types"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+// maxDepth bounds query nesting, which references would otherwise leave unbounded
+const maxDepth = 12
+
+// Handler serves GraphQL requests
+type Handler struct {
+	schema *graphql.Schema
+}
+
+// NewHandler parses the schema over the given services
+func NewHandler(configService *services.ConfigService, typeService *services.TypeDefinitionService) *Handler {
+	root := &rootResolver{configService: configService, typeService: typeService}
+	return &Handler{
+		schema: graphql.MustParseSchema(schema, root, graphql.MaxDepth(maxDepth)),
+	}
+}
+
+// ServeHTTP executes a query posted as {"query", "operationName", "variables"}
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var params struct {
+		Query         string                 `json:"query"`
+		OperationName string                 `json:"operationName"`
+		Variables     map[string]interface{} `json:"variables"`
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"errors": []map[string]string{{"message": "invalid request body: " + err.Error()}},
+		})
+		return
+	}
+
+	ctx := context.WithValue(r.Context(), requestKey{}, &request{})
+	response := h.schema.Exec(ctx, params.Query, params.OperationName, params.Variables)
+	json.NewEncoder(w).Encode(response)
+}
+
+// unauthorized is the failure of a request without a principal
+var unauthorized = handlers.ServiceResponse{
+	StatusCode: http.StatusUnauthorized,
+	Error:      "unauthenticated",
+}
+
+type requestKey struct{}
+
+// request holds the data loaded once per GraphQL request
+type request struct {
+	typesOnce sync.Once
+	types     map[string]models.TypeView
+	typesErr  error
+}
+
+// requestFrom returns the request state of ctx
+func requestFrom(ctx context.Context) *request {
+	if req, ok := ctx.Value(requestKey{}).(*request); ok {
+		return req
+	}
+	return &request{}
+}
+
+// registry loads the merged type registry of the tenant once per request. Like
+// the type routes, it requires type:read.
+func (r *rootResolver) registry(ctx context.Context) (map[string]models.TypeView, error) {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return nil, serviceError{unauthorized}
+	}
+	if !principal.HasPermission(auth.PermTypeRead) {
+		return nil, serviceError{handlers.ServiceResponse{
+			StatusCode: http.StatusForbidden,
+			Error:      "permission denied: " + string(auth.PermTypeRead),
+		}}
+	}
+
+	req := requestFrom(ctx)
+	req.typesOnce.Do(func() {
+		resp := r.typeService.GetAllTypes(ctx, types.EmptyRequest{})
+		if resp.Error != "" {
+			req.typesErr = serviceError{resp}
+			return
+		}
+		req.types, _ = resp.Data.(map[string]models.TypeView)
+	})
+	return req.types, req.typesErr
+}
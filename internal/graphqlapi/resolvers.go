@@ -0,0 +1,468 @@
+package graphqlapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+
+	"makatom-api-config/internal/models"
+	"makatom-api-config/internal/services"
+	"makatom/common/pkg/handlers"
+
+	"github.com/graph-gophers/graphql-go"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// serviceError reports a failed service response, keeping its status
+type serviceError struct {
+	resp handlers.ServiceResponse
+}
+
+func (e serviceError) Error() string {
+	return e.resp.Error
+}
+
+// Extensions adds the HTTP status of the failure to the GraphQL error
+func (e serviceError) Extensions() map[string]interface{} {
+	return map[string]interface{}{"status": e.resp.StatusCode}
+}
+
+// JSON is the scalar of free-form values such as metadata
+type JSON struct {
+	Value interface{}
+}
+
+// ImplementsGraphQLType maps JSON to the JSON scalar
+func (JSON) ImplementsGraphQLType(name string) bool {
+	return name == "JSON"
+}
+
+// UnmarshalGraphQL accepts any input value
+func (j *JSON) UnmarshalGraphQL(input interface{}) error {
+	j.Value = input
+	return nil
+}
+
+// MarshalJSON encodes the value itself
+func (j JSON) MarshalJSON() ([]byte, error) {
+	return json.Marshal(j.Value)
+}
+
+// optionalJSON returns nil for an absent value
+func optionalJSON(value interface{}) *JSON {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case map[string]interface{}:
+		if v == nil {
+			return nil
+		}
+	case models.EnvOverlays:
+		if v == nil {
+			return nil
+		}
+	}
+	return &JSON{Value: value}
+}
+
+// valueOf returns the value of an optional argument, or the empty string
+func valueOf(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
+
+// nonNil turns an absent list into an empty one for non-null list fields
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
+
+// rootResolver resolves the Query type
+type rootResolver struct {
+	configService *services.ConfigService
+	typeService   *services.TypeDefinitionService
+}
+
+// Config resolves config(id, env, resolve); a missing config is null
+func (r *rootResolver) Config(ctx context.Context, args struct {
+	ID      graphql.ID
+	Env     *string
+	Resolve bool
+}) (*configResolver, error) {
+	resp := r.configService.GetConfigByID(ctx, models.GetConfigRequest{
+		ID:      string(args.ID),
+		Env:     valueOf(args.Env),
+		Resolve: args.Resolve,
+	})
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if resp.Error != "" {
+		return nil, serviceError{resp}
+	}
+	config, _ := resp.Data.(models.ConfigResponse)
+	return newConfigGroup(r, []models.ConfigResponse{config})[0], nil
+}
+
+// configFilter is the ConfigFilter input
+type configFilter struct {
+	Name            *string
+	Namespace       *string
+	NamespacePrefix *string
+	Type            *string
+	Subtype         *string
+	Tag             *string
+}
+
+// Configs resolves configs(filter, limit, skip)
+func (r *rootResolver) Configs(ctx context.Context, args struct {
+	Filter *configFilter
+	Limit  int32
+	Skip   int32
+}) (*connectionResolver, error) {
+	query := models.ConfigQuery{Limit: int64(args.Limit), Skip: int64(args.Skip)}
+	if filter := args.Filter; filter != nil {
+		query.Name = valueOf(filter.Name)
+		query.Namespace = valueOf(filter.Namespace)
+		query.NamespacePrefix = valueOf(filter.NamespacePrefix)
+		query.Type = valueOf(filter.Type)
+		query.Subtype = valueOf(filter.Subtype)
+		query.Tag = valueOf(filter.Tag)
+	}
+
+	resp := r.configService.GetConfigs(ctx, query)
+	if resp.Error != "" {
+		return nil, serviceError{resp}
+	}
+	data, _ := resp.Data.(map[string]interface{})
+	configs, _ := data["configs"].([]models.ConfigResponse)
+	total, _ := data["total"].(int64)
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+
+	return &connectionResolver{
+		nodes: newConfigGroup(r, configs),
+		total: total,
+		limit: limit,
+		skip:  query.Skip,
+	}, nil
+}
+
+// Types resolves types, sorted by name
+func (r *rootResolver) Types(ctx context.Context) ([]*typeResolver, error) {
+	registry, err := r.registry(ctx)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	resolvers := make([]*typeResolver, len(names))
+	for i, name := range names {
+		resolvers[i] = &typeResolver{view: registry[name]}
+	}
+	return resolvers, nil
+}
+
+// Type resolves type(name); an unknown type is null
+func (r *rootResolver) Type(ctx context.Context, args struct{ Name string }) (*typeResolver, error) {
+	registry, err := r.registry(ctx)
+	if err != nil {
+		return nil, err
+	}
+	view, exists := registry[args.Name]
+	if !exists {
+		return nil, nil
+	}
+	return &typeResolver{view: view}, nil
+}
+
+// connectionResolver resolves a page of configs
+type connectionResolver struct {
+	nodes []*configResolver
+	total int64
+	limit int64
+	skip  int64
+}
+
+func (c *connectionResolver) Nodes() []*configResolver { return c.nodes }
+func (c *connectionResolver) Total() int32             { return int32(c.total) }
+func (c *connectionResolver) Limit() int32             { return int32(c.limit) }
+func (c *connectionResolver) Skip() int32              { return int32(c.skip) }
+func (c *connectionResolver) HasMore() bool            { return c.skip+int64(len(c.nodes)) < c.total }
+
+// configGroup is a set of configs resolved together. The first config asking
+// for its archives or references loads them for the whole group.
+type configGroup struct {
+	root    *rootResolver
+	configs []models.ConfigResponse
+
+	archivesOnce sync.Once
+	archives     map[primitive.ObjectID][]models.ConfigArchiveResponse
+	archivesErr  error
+
+	referencesOnce sync.Once
+	references     map[models.ConfigKey]*configResolver
+	referencesErr  error
+}
+
+// newConfigGroup returns the resolvers of configs loaded together
+func newConfigGroup(root *rootResolver, configs []models.ConfigResponse) []*configResolver {
+	group := &configGroup{root: root, configs: configs}
+	resolvers := make([]*configResolver, len(configs))
+	for i := range configs {
+		resolvers[i] = &configResolver{config: configs[i], group: group}
+	}
+	return resolvers
+}
+
+// loadArchives loads the archives of every config of the group
+func (g *configGroup) loadArchives(ctx context.Context) {
+	ids := make([]primitive.ObjectID, len(g.configs))
+	for i, config := range g.configs {
+		ids[i] = config.ID
+	}
+	archives, errResp := g.root.configService.ArchivesByConfig(ctx, ids)
+	if errResp != nil {
+		g.archivesErr = serviceError{*errResp}
+		return
+	}
+	g.archives = archives
+}
+
+// loadReferences loads the configs referenced by any config of the group;
+// they form a group of their own, so their fields load together too
+func (g *configGroup) loadReferences(ctx context.Context) {
+	keys := make([]models.ConfigKey, 0)
+	for _, config := range g.configs {
+		for _, key := range config.References {
+			keys = append(keys, models.ConfigKey{Namespace: config.Namespace, Key: key})
+		}
+	}
+	referenced, errResp := g.root.configService.ConfigsByKey(ctx, keys)
+	if errResp != nil {
+		g.referencesErr = serviceError{*errResp}
+		return
+	}
+
+	referencedKeys := make([]models.ConfigKey, 0, len(referenced))
+	configs := make([]models.ConfigResponse, 0, len(referenced))
+	for key, config := range referenced {
+		referencedKeys = append(referencedKeys, key)
+		configs = append(configs, config)
+	}
+	resolvers := newConfigGroup(g.root, configs)
+	g.references = make(map[models.ConfigKey]*configResolver, len(resolvers))
+	for i, key := range referencedKeys {
+		g.references[key] = resolvers[i]
+	}
+}
+
+// configResolver resolves a Config
+type configResolver struct {
+	config models.ConfigResponse
+	group  *configGroup
+}
+
+func (c *configResolver) ID() graphql.ID        { return graphql.ID(c.config.ID.Hex()) }
+func (c *configResolver) Name() string          { return c.config.Name }
+func (c *configResolver) Namespace() string     { return c.config.Namespace }
+func (c *configResolver) Type() string          { return c.config.Type }
+func (c *configResolver) Subtype() string       { return c.config.Subtype }
+func (c *configResolver) Tags() []string        { return nonNil(c.config.Tags) }
+func (c *configResolver) TenantID() string      { return c.config.TenantID }
+func (c *configResolver) CreatedBy() string     { return c.config.CreatedBy }
+func (c *configResolver) LastUpdatedBy() string { return c.config.LastUpdatedBy }
+func (c *configResolver) Metadata() *JSON       { return optionalJSON(c.config.Metadata) }
+func (c *configResolver) SchemaVersion() string { return c.config.SchemaVersion }
+func (c *configResolver) EncryptedFields() []string {
+	return nonNil(c.config.EncryptedFields)
+}
+func (c *configResolver) Overlays() *JSON         { return optionalJSON(c.config.Overlays) }
+func (c *configResolver) CreatedAt() graphql.Time { return graphql.Time{Time: c.config.CreatedAt} }
+func (c *configResolver) UpdatedAt() graphql.Time { return graphql.Time{Time: c.config.UpdatedAt} }
+
+func (c *configResolver) Env() *string {
+	if c.config.Env == "" {
+		return nil
+	}
+	return &c.config.Env
+}
+
+// DecryptionError resolves decryptionError, null unless decryption failed
+func (c *configResolver) DecryptionError() *string {
+	if c.config.DecryptionError == "" {
+		return nil
+	}
+	return &c.config.DecryptionError
+}
+
+// Archives resolves the archives of the config, loaded for its whole group
+func (c *configResolver) Archives(ctx context.Context) ([]*archiveResolver, error) {
+	c.group.archivesOnce.Do(func() { c.group.loadArchives(ctx) })
+	if c.group.archivesErr != nil {
+		return nil, c.group.archivesErr
+	}
+
+	archives := c.group.archives[c.config.ID]
+	resolvers := make([]*archiveResolver, len(archives))
+	for i := range archives {
+		resolvers[i] = &archiveResolver{archive: archives[i]}
+	}
+	return resolvers, nil
+}
+
+// References resolves the readable configs the config references, loaded for its whole group
+func (c *configResolver) References(ctx context.Context) ([]*configResolver, error) {
+	c.group.referencesOnce.Do(func() { c.group.loadReferences(ctx) })
+	if c.group.referencesErr != nil {
+		return nil, c.group.referencesErr
+	}
+
+	resolvers := make([]*configResolver, 0, len(c.config.References))
+	for _, key := range c.config.References {
+		if resolver, found := c.group.references[models.ConfigKey{Namespace: c.config.Namespace, Key: key}]; found {
+			resolvers = append(resolvers, resolver)
+		}
+	}
+	return resolvers, nil
+}
+
+// ConfigType resolves the registry type of the config
+func (c *configResolver) ConfigType(ctx context.Context) (*typeResolver, error) {
+	registry, err := c.group.root.registry(ctx)
+	if err != nil {
+		return nil, err
+	}
+	view, exists := registry[c.config.Type]
+	if !exists {
+		return nil, nil
+	}
+	return &typeResolver{view: view}, nil
+}
+
+// SubtypeDefinition resolves the registry subtype of the config
+func (c *configResolver) SubtypeDefinition(ctx context.Context) (*subtypeResolver, error) {
+	registry, err := c.group.root.registry(ctx)
+	if err != nil {
+		return nil, err
+	}
+	view, exists := registry[c.config.Type].Subtypes[c.config.Subtype]
+	if !exists {
+		return nil, nil
+	}
+	return &subtypeResolver{view: view}, nil
+}
+
+// archiveResolver resolves a ConfigArchive
+type archiveResolver struct {
+	archive models.ConfigArchiveResponse
+}
+
+func (a *archiveResolver) ID() graphql.ID           { return graphql.ID(a.archive.ID.Hex()) }
+func (a *archiveResolver) ConfigID() graphql.ID     { return graphql.ID(a.archive.ConfigID.Hex()) }
+func (a *archiveResolver) Name() string             { return a.archive.Name }
+func (a *archiveResolver) Namespace() string        { return a.archive.Namespace }
+func (a *archiveResolver) Type() string             { return a.archive.Type }
+func (a *archiveResolver) Subtype() string          { return a.archive.Subtype }
+func (a *archiveResolver) Tags() []string           { return nonNil(a.archive.Tags) }
+func (a *archiveResolver) Metadata() *JSON          { return optionalJSON(a.archive.Metadata) }
+func (a *archiveResolver) Version() int32           { return int32(a.archive.Version) }
+func (a *archiveResolver) SchemaVersion() string    { return a.archive.SchemaVersion }
+func (a *archiveResolver) CreatedBy() string        { return a.archive.CreatedBy }
+func (a *archiveResolver) LastUpdatedBy() string    { return a.archive.LastUpdatedBy }
+func (a *archiveResolver) ArchivedAt() graphql.Time { return graphql.Time{Time: a.archive.ArchivedAt} }
+func (a *archiveResolver) ArchivedBy() string       { return a.archive.ArchivedBy }
+func (a *archiveResolver) CreatedAt() graphql.Time  { return graphql.Time{Time: a.archive.CreatedAt} }
+
+// typeResolver resolves a ConfigType
+type typeResolver struct {
+	view models.TypeView
+}
+
+func (t *typeResolver) Name() string        { return t.view.Name }
+func (t *typeResolver) Description() string { return t.view.Description }
+func (t *typeResolver) Source() string      { return t.view.Source }
+
+// Subtypes resolves the subtypes of the type, sorted by name
+func (t *typeResolver) Subtypes() []*subtypeResolver {
+	names := make([]string, 0, len(t.view.Subtypes))
+	for name := range t.view.Subtypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	resolvers := make([]*subtypeResolver, len(names))
+	for i, name := range names {
+		resolvers[i] = &subtypeResolver{view: t.view.Subtypes[name]}
+	}
+	return resolvers
+}
+
+// Subtype resolves subtype(name); an unknown subtype is null
+func (t *typeResolver) Subtype(args struct{ Name string }) *subtypeResolver {
+	view, exists := t.view.Subtypes[args.Name]
+	if !exists {
+		return nil
+	}
+	return &subtypeResolver{view: view}
+}
+
+// subtypeResolver resolves a Subtype
+type subtypeResolver struct {
+	view models.SubtypeView
+}
+
+func (s *subtypeResolver) Name() string        { return s.view.Name }
+func (s *subtypeResolver) Description() string { return s.view.Description }
+func (s *subtypeResolver) Source() string      { return s.view.Source }
+
+// Fields resolves the metadata fields of the subtype schema, sorted by name
+func (s *subtypeResolver) Fields() []*fieldResolver {
+	names := make([]string, 0, len(s.view.MetadataSchema.Properties))
+	for name := range s.view.MetadataSchema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	resolvers := make([]*fieldResolver, len(names))
+	for i, name := range names {
+		resolvers[i] = &fieldResolver{name: name, schema: s.view.MetadataSchema.Properties[name]}
+	}
+	return resolvers
+}
+
+// fieldResolver resolves a Field
+type fieldResolver struct {
+	name   string
+	schema models.FieldSchema
+}
+
+func (f *fieldResolver) Name() string        { return f.name }
+func (f *fieldResolver) Type() string        { return f.schema.Type }
+func (f *fieldResolver) Required() bool      { return f.schema.Required }
+func (f *fieldResolver) Encryption() bool    { return f.schema.Encryption }
+func (f *fieldResolver) Description() string { return f.schema.Description }
+func (f *fieldResolver) Default() *JSON      { return optionalJSON(f.schema.Default) }
+
+func (f *fieldResolver) Enum() *[]JSON {
+	if f.schema.Enum == nil {
+		return nil
+	}
+	values := make([]JSON, len(f.schema.Enum))
+	for i, value := range f.schema.Enum {
+		values[i] = JSON{Value: value}
+	}
+	return &values
+}
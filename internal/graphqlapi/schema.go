@@ -0,0 +1,117 @@
+package graphqlapi
+
+// schema is the GraphQL schema served at /graphql
+const schema = `
+schema {
+	query: Query
+}
+
+"Arbitrary JSON, such as config metadata"
+scalar JSON
+
+"An RFC 3339 timestamp"
+scalar Time
+
+type Query {
+	"A config by ID, optionally merged with an environment overlay and with its references resolved"
+	config(id: ID!, env: String, resolve: Boolean = false): Config
+	"The configs matching the filter that the caller may read"
+	configs(filter: ConfigFilter, limit: Int = 10, skip: Int = 0): ConfigConnection!
+	"Every type in the merged built-in and tenant registry"
+	types: [ConfigType!]!
+	"A type of the merged registry"
+	type(name: String!): ConfigType
+}
+
+input ConfigFilter {
+	name: String
+	namespace: String
+	namespacePrefix: String
+	type: String
+	subtype: String
+	tag: String
+}
+
+type ConfigConnection {
+	nodes: [Config!]!
+	total: Int!
+	limit: Int!
+	skip: Int!
+	hasMore: Boolean!
+}
+
+type Config {
+	id: ID!
+	name: String!
+	namespace: String!
+	type: String!
+	subtype: String!
+	tags: [String!]!
+	tenantId: String!
+	createdBy: String!
+	lastUpdatedBy: String!
+	"Decrypted for callers holding secret:read on the config"
+	metadata: JSON
+	schemaVersion: String!
+	encryptedFields: [String!]!
+	overlays: JSON
+	"The environment merged into metadata, when one was requested"
+	env: String
+	"Set on a listed config that could not be decrypted; metadata then holds the ciphertext"
+	decryptionError: String
+	createdAt: Time!
+	updatedAt: Time!
+	"Archived versions, newest first"
+	archives: [ConfigArchive!]!
+	"The configs referenced from the metadata that the caller may read"
+	references: [Config!]!
+	configType: ConfigType
+	subtypeDefinition: Subtype
+}
+
+type ConfigArchive {
+	id: ID!
+	configId: ID!
+	name: String!
+	namespace: String!
+	type: String!
+	subtype: String!
+	tags: [String!]!
+	"As stored, with encrypted fields encrypted"
+	metadata: JSON
+	version: Int!
+	schemaVersion: String!
+	createdBy: String!
+	lastUpdatedBy: String!
+	archivedAt: Time!
+	archivedBy: String!
+	createdAt: Time!
+}
+
+type ConfigType {
+	name: String!
+	description: String!
+	"builtin or tenant"
+	source: String!
+	subtypes: [Subtype!]!
+	subtype(name: String!): Subtype
+}
+
+type Subtype {
+	name: String!
+	description: String!
+	"builtin or tenant"
+	source: String!
+	fields: [Field!]!
+}
+
+type Field {
+	name: String!
+	type: String!
+	required: Boolean!
+	encryption: Boolean!
+	description: String!
+	default: JSON
+	enum: [JSON!]
+}
+`
@@ -0,0 +1,82 @@
+package grpcapi
+
+import (
+	"context"
+	"net/http"
+
+	"makatom-api-config/internal/auth"
+	"makatom-api-config/pkg/configpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// methodPermissions guards every RPC of the config service like its HTTP route.
+// Methods of other services, reflection and health, need no credentials.
+var methodPermissions = map[string]auth.Permission{
+	configpb.ConfigService_CreateConfig_FullMethodName:  auth.PermConfigWrite,
+	configpb.ConfigService_GetConfig_FullMethodName:     auth.PermConfigRead,
+	configpb.ConfigService_ListConfigs_FullMethodName:   auth.PermConfigRead,
+	configpb.ConfigService_UpdateConfig_FullMethodName:  auth.PermConfigWrite,
+	configpb.ConfigService_DeleteConfig_FullMethodName:  auth.PermConfigDelete,
+	configpb.ConfigService_ListArchives_FullMethodName:  auth.PermConfigRead,
+	configpb.ConfigService_RestoreConfig_FullMethodName: auth.PermConfigWrite,
+	configpb.ConfigService_DecryptField_FullMethodName:  auth.PermSecretRead,
+	configpb.ConfigService_Watch_FullMethodName:         auth.PermConfigRead,
+}
+
+// authorize authenticates the "authorization" metadata of a call and returns
+// ctx carrying the principal, if it holds the permission of the method
+func authorize(ctx context.Context, authorizer *auth.Authorizer, method string) (context.Context, error) {
+	perm, guarded := methodPermissions[method]
+	if !guarded {
+		return ctx, nil
+	}
+
+	var header string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			header = values[0]
+		}
+	}
+	principal, status, msg := authorizer.Authenticate(ctx, header)
+	if principal == nil {
+		return nil, statusError(status, msg, nil)
+	}
+	if !principal.HasPermission(perm) {
+		return nil, statusError(http.StatusForbidden, "permission denied: "+string(perm), nil)
+	}
+	return auth.WithPrincipal(ctx, principal), nil
+}
+
+// unaryInterceptor authorizes unary calls
+func unaryInterceptor(authorizer *auth.Authorizer) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authorize(ctx, authorizer, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// streamInterceptor authorizes streaming calls
+func streamInterceptor(authorizer *auth.Authorizer) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authorize(stream.Context(), authorizer, info.FullMethod)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &principalStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// principalStream is a server stream whose context carries the principal
+type principalStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *principalStream) Context() context.Context {
+	return s.ctx
+}
@@ -0,0 +1,265 @@
+// Package grpcapi serves the config service over gRPC. Every RPC calls the
+// same service methods as the HTTP API, so validation, encryption,
+// authorization and archiving behave identically; only the encoding differs.
+package grpcapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"makatom-api-config/internal/auth"
+	"makatom-api-config/internal/models"
+	"makatom-api-config/internal/services"
+	"makatom-api-config/pkg/configpb"
+	"makatom/common/pkg/handlers"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Server implements configpb.ConfigServiceServer on top of ConfigService
+type Server struct {
+	configpb.UnimplementedConfigServiceServer
+	configService *services.ConfigService
+}
+
+// NewServer creates a gRPC server exposing the config service, with the
+// standard reflection and health services registered alongside it
+func NewServer(configService *services.ConfigService, authorizer *auth.Authorizer) *grpc.Server {
+	grpcServer := grpc.NewServer(
+		grpc.UnaryInterceptor(unaryInterceptor(authorizer)),
+		grpc.StreamInterceptor(streamInterceptor(authorizer)),
+	)
+	configpb.RegisterConfigServiceServer(grpcServer, &Server{configService: configService})
+
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus(configpb.ConfigService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	reflection.Register(grpcServer)
+
+	return grpcServer
+}
+
+// CreateConfig creates a new config
+func (s *Server) CreateConfig(ctx context.Context, req *configpb.CreateConfigRequest) (*configpb.Config, error) {
+	if err := required("name", req.GetName(), "type", req.GetType()); err != nil {
+		return nil, err
+	}
+	resp := s.configService.CreateConfig(ctx, models.CreateConfigRequest{
+		Name:      req.GetName(),
+		Namespace: req.GetNamespace(),
+		Type:      req.GetType(),
+		Subtype:   req.GetSubtype(),
+		Tags:      req.GetTags(),
+		Metadata:  structMap(req.GetMetadata()),
+		Overlays:  overlays(req.GetOverlays(), false),
+	})
+	return decode(resp, &configpb.Config{})
+}
+
+// GetConfig returns a config, optionally merged with an environment overlay
+// and with its references resolved
+func (s *Server) GetConfig(ctx context.Context, req *configpb.GetConfigRequest) (*configpb.Config, error) {
+	if err := required("id", req.GetId()); err != nil {
+		return nil, err
+	}
+	resp := s.configService.GetConfigByID(ctx, models.GetConfigRequest{
+		ID:      req.GetId(),
+		Env:     req.GetEnv(),
+		Resolve: req.GetResolve(),
+	})
+	return decode(resp, &configpb.Config{})
+}
+
+// ListConfigs returns the configs matching the request that the caller may read
+func (s *Server) ListConfigs(ctx context.Context, req *configpb.ListConfigsRequest) (*configpb.ListConfigsResponse, error) {
+	resp := s.configService.GetConfigs(ctx, models.ConfigQuery{
+		Name:            req.GetName(),
+		Namespace:       req.GetNamespace(),
+		NamespacePrefix: req.GetNamespacePrefix(),
+		Type:            req.GetType(),
+		Subtype:         req.GetSubtype(),
+		Tag:             req.GetTag(),
+		Limit:           req.GetLimit(),
+		Skip:            req.GetSkip(),
+	})
+	return decode(resp, &configpb.ListConfigsResponse{})
+}
+
+// UpdateConfig updates the tags, metadata and overlays of a config
+func (s *Server) UpdateConfig(ctx context.Context, req *configpb.UpdateConfigRequest) (*configpb.Config, error) {
+	if err := required("id", req.GetId()); err != nil {
+		return nil, err
+	}
+	// nil leaves tags and overlays unchanged, as an absent JSON field does
+	tags := req.GetTags()
+	if req.GetReplaceTags() && tags == nil {
+		tags = []string{}
+	}
+	resp := s.configService.UpdateConfig(ctx, models.UpdateConfigWithIDRequest{
+		ID:       req.GetId(),
+		Tags:     tags,
+		Metadata: structMap(req.GetMetadata()),
+		Overlays: overlays(req.GetOverlays(), req.GetReplaceOverlays()),
+	})
+	return decode(resp, &configpb.Config{})
+}
+
+// DeleteConfig deletes a config and its archives
+func (s *Server) DeleteConfig(ctx context.Context, req *configpb.DeleteConfigRequest) (*configpb.DeleteConfigResponse, error) {
+	if err := required("id", req.GetId()); err != nil {
+		return nil, err
+	}
+	resp := s.configService.DeleteConfig(ctx, models.ConfigIDRequest{ID: req.GetId()})
+	return decode(resp, &configpb.DeleteConfigResponse{})
+}
+
+// ListArchives returns the archived versions of a config
+func (s *Server) ListArchives(ctx context.Context, req *configpb.ListArchivesRequest) (*configpb.ListArchivesResponse, error) {
+	if err := required("config_id", req.GetConfigId()); err != nil {
+		return nil, err
+	}
+	resp := s.configService.GetConfigArchives(ctx, models.ConfigIDRequest{ID: req.GetConfigId()})
+	return decode(resp, &configpb.ListArchivesResponse{})
+}
+
+// RestoreConfig restores a config from one of its archives
+func (s *Server) RestoreConfig(ctx context.Context, req *configpb.RestoreConfigRequest) (*configpb.Config, error) {
+	if err := required("id", req.GetId(), "archive_id", req.GetArchiveId()); err != nil {
+		return nil, err
+	}
+	resp := s.configService.RestoreConfig(ctx, models.RestoreConfigRequest{
+		ID:        req.GetId(),
+		ArchiveID: req.GetArchiveId(),
+	})
+	return decode(resp, &configpb.Config{})
+}
+
+// DecryptField decrypts a single encrypted metadata field
+func (s *Server) DecryptField(ctx context.Context, req *configpb.DecryptFieldRequest) (*configpb.DecryptFieldResponse, error) {
+	if err := required("config_id", req.GetConfigId(), "field_name", req.GetFieldName()); err != nil {
+		return nil, err
+	}
+	resp := s.configService.DecryptConfigField(ctx, models.DecryptFieldRequest{
+		ConfigID:  req.GetConfigId(),
+		FieldName: req.GetFieldName(),
+	})
+	return decode(resp, &configpb.DecryptFieldResponse{})
+}
+
+// eventKinds maps config event kinds to their protobuf enum
+var eventKinds = map[string]configpb.ConfigEvent_Kind{
+	models.ConfigEventCreated: configpb.ConfigEvent_KIND_CREATED,
+	models.ConfigEventUpdated: configpb.ConfigEvent_KIND_UPDATED,
+	models.ConfigEventDeleted: configpb.ConfigEvent_KIND_DELETED,
+}
+
+// Watch streams the changes of the configs matching the request until the
+// client cancels the call
+func (s *Server) Watch(req *configpb.WatchRequest, stream grpc.ServerStreamingServer[configpb.ConfigEvent]) error {
+	query := models.ConfigQuery{
+		Namespace: req.GetNamespace(),
+		Type:      req.GetType(),
+		Subtype:   req.GetSubtype(),
+		Name:      req.GetName(),
+		Tag:       req.GetTag(),
+	}
+	errResp := s.configService.WatchConfigs(stream.Context(), query, func(event models.ConfigEvent) error {
+		config, err := decode(handlers.ServiceResponse{StatusCode: http.StatusOK, Data: event.Config}, &configpb.Config{})
+		if err != nil {
+			return err
+		}
+		return stream.Send(&configpb.ConfigEvent{
+			Kind:       eventKinds[event.Kind],
+			ConfigId:   event.ConfigID,
+			Config:     config,
+			OccurredAt: timestamppb.New(event.OccurredAt),
+		})
+	})
+	if errResp != nil {
+		return statusError(errResp.StatusCode, errResp.Error, errResp.Data)
+	}
+	return nil
+}
+
+// decode converts a service response into its protobuf message, or a failed
+// one into a status error. The data goes through its JSON encoding, whose
+// field names the messages share; fields the messages lack are dropped.
+func decode[M proto.Message](resp handlers.ServiceResponse, message M) (M, error) {
+	if resp.StatusCode >= http.StatusBadRequest || resp.Error != "" {
+		return message, statusError(resp.StatusCode, resp.Error, resp.Data)
+	}
+	body, err := json.Marshal(resp.Data)
+	if err != nil {
+		return message, status.Errorf(codes.Internal, "failed to encode response: %v", err)
+	}
+	if err := (protojson.UnmarshalOptions{DiscardUnknown: true}).Unmarshal(body, message); err != nil {
+		return message, status.Errorf(codes.Internal, "failed to convert response: %v", err)
+	}
+	return message, nil
+}
+
+// statusError converts an HTTP status into a gRPC status error. Details such
+// as validation errors are appended to the message as JSON.
+func statusError(httpStatus int, msg string, details interface{}) error {
+	code := codes.Internal
+	switch httpStatus {
+	case http.StatusBadRequest:
+		code = codes.InvalidArgument
+	case http.StatusUnauthorized:
+		code = codes.Unauthenticated
+	case http.StatusForbidden:
+		code = codes.PermissionDenied
+	case http.StatusNotFound:
+		code = codes.NotFound
+	case http.StatusConflict, http.StatusUnprocessableEntity:
+		code = codes.FailedPrecondition
+	}
+	if details != nil {
+		if encoded, err := json.Marshal(details); err == nil {
+			msg = fmt.Sprintf("%s: %s", msg, encoded)
+		}
+	}
+	return status.Error(code, msg)
+}
+
+// required checks name/value pairs of required fields, which the HTTP API
+// enforces through validate tags
+func required(fields ...string) error {
+	for i := 0; i+1 < len(fields); i += 2 {
+		if fields[i+1] == "" {
+			return status.Errorf(codes.InvalidArgument, "%s is required", fields[i])
+		}
+	}
+	return nil
+}
+
+// structMap converts a Struct to metadata; an absent Struct stays nil
+func structMap(s *structpb.Struct) map[string]interface{} {
+	if s == nil {
+		return nil
+	}
+	return s.AsMap()
+}
+
+// overlays converts overlay Structs; none stays nil unless replace is set
+func overlays(structs map[string]*structpb.Struct, replace bool) models.EnvOverlays {
+	if len(structs) == 0 && !replace {
+		return nil
+	}
+	result := make(models.EnvOverlays, len(structs))
+	for env, overlay := range structs {
+		result[env] = overlay.AsMap()
+	}
+	return result
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"time"
+
+	"makatom/common/pkg/types"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	APIKeyScopeReadOnly  = "read-only"
+	APIKeyScopeReadWrite = "read-write"
+)
+
+// APIKeyTypeFilter restricts an API key to a config type and optionally a subtype
+type APIKeyTypeFilter struct {
+	Type    string `bson:"type" json:"type" validate:"required"`
+	Subtype string `bson:"subtype,omitempty" json:"subtype,omitempty"`
+}
+
+// APIKey represents a tenant-scoped service-account key. Only the hash of the
+// key is stored; the plaintext is returned once on creation.
+type APIKey struct {
+	*types.Base  `bson:",inline"`
+	TenantID     string             `bson:"tenant_id" json:"tenant_id"`
+	Name         string             `bson:"name" json:"name"`
+	Prefix       string             `bson:"prefix" json:"prefix"`
+	KeyHash      string             `bson:"key_hash" json:"-"`
+	Scope        string             `bson:"scope" json:"scope"`
+	AllowedTypes []APIKeyTypeFilter `bson:"allowed_types,omitempty" json:"allowed_types,omitempty"`
+	AllowSecrets bool               `bson:"allow_secrets" json:"allow_secrets"`
+	ExpiresAt    *time.Time         `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	LastUsedAt   *time.Time         `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+	Revoked      bool               `bson:"revoked" json:"revoked"`
+	RevokedAt    *time.Time         `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	CreatedBy    string             `bson:"created_by" json:"created_by"`
+}
+
+// CreateAPIKeyRequest represents the request payload for creating an API key
+type CreateAPIKeyRequest struct {
+	Name         string             `json:"name" validate:"required"`
+	Scope        string             `json:"scope" validate:"required"`
+	AllowedTypes []APIKeyTypeFilter `json:"allowed_types,omitempty"`
+	// AllowSecrets lets the key decrypt encrypted fields within its allow-list
+	AllowSecrets bool       `json:"allow_secrets,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+}
+
+// APIKeyQuery represents query parameters for listing API keys
+type APIKeyQuery struct {
+	IncludeRevoked bool `param:"include_revoked,omitempty"`
+}
+
+// APIKeyIDRequest represents request with API key ID
+type APIKeyIDRequest struct {
+	ID string `param:"id" validate:"required"`
+}
+
+// APIKeyResponse represents the response payload for API key operations
+type APIKeyResponse struct {
+	ID           primitive.ObjectID `json:"id"`
+	TenantID     string             `json:"tenant_id"`
+	Name         string             `json:"name"`
+	Prefix       string             `json:"prefix"`
+	Scope        string             `json:"scope"`
+	AllowedTypes []APIKeyTypeFilter `json:"allowed_types,omitempty"`
+	AllowSecrets bool               `json:"allow_secrets"`
+	ExpiresAt    *time.Time         `json:"expires_at,omitempty"`
+	LastUsedAt   *time.Time         `json:"last_used_at,omitempty"`
+	Revoked      bool               `json:"revoked"`
+	RevokedAt    *time.Time         `json:"revoked_at,omitempty"`
+	CreatedBy    string             `json:"created_by"`
+	CreatedAt    time.Time          `json:"created_at"`
+}
+
+// CreatedAPIKeyResponse is returned once on creation and carries the plaintext key
+type CreatedAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+// ToResponse converts an APIKey to APIKeyResponse
+func (k *APIKey) ToResponse() APIKeyResponse {
+	return APIKeyResponse{
+		ID:           k.ID,
+		TenantID:     k.TenantID,
+		Name:         k.Name,
+		Prefix:       k.Prefix,
+		Scope:        k.Scope,
+		AllowedTypes: k.AllowedTypes,
+		AllowSecrets: k.AllowSecrets,
+		ExpiresAt:    k.ExpiresAt,
+		LastUsedAt:   k.LastUsedAt,
+		Revoked:      k.Revoked,
+		RevokedAt:    k.RevokedAt,
+		CreatedBy:    k.CreatedBy,
+		CreatedAt:    k.CreatedAt,
+	}
+}
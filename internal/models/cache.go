@@ -0,0 +1,14 @@
+package models
+
+// CacheStats describes the config cache of one replica. The counters grow
+// from the start of the process.
+type CacheStats struct {
+	Enabled       bool    `json:"enabled"`
+	Capacity      int     `json:"capacity"`
+	Size          int     `json:"size"`
+	Hits          uint64  `json:"hits"`
+	Misses        uint64  `json:"misses"`
+	Evictions     uint64  `json:"evictions"`
+	Invalidations uint64  `json:"invalidations"`
+	HitRatio      float64 `json:"hit_ratio"`
+}
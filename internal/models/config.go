@@ -3,48 +3,64 @@ package models
 import (
 	"time"
 
+	"makatom-api-config/internal/auth"
 	"makatom/common/pkg/types"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// EnvOverlays maps environment names to metadata deep-merged over the base
+// metadata of a config when that environment is read
+type EnvOverlays map[string]map[string]interface{}
+
 // Config represents a configuration entity
 type Config struct {
-	*types.Base   `bson:",inline"`
-	Name          string                 `bson:"name" json:"name" validate:"required"`
-	Type          string                 `bson:"type" json:"type" validate:"required"`
-	Subtype       string                 `bson:"subtype" json:"subtype,omitempty"`
-	Tags          []string               `bson:"tags" json:"tags,omitempty"`
-	TenantID      string                 `bson:"tenant_id" json:"tenant_id" validate:"required"`
-	CreatedBy     string                 `bson:"created_by" json:"created_by" validate:"required"`
-	LastUpdatedBy string                 `bson:"last_updated_by" json:"last_updated_by" validate:"required"`
-	Metadata      map[string]interface{} `bson:"metadata" json:"metadata,omitempty"`
+	*types.Base     `bson:",inline"`
+	Name            string                 `bson:"name" json:"name" validate:"required"`
+	Namespace       string                 `bson:"namespace,omitempty" json:"namespace,omitempty"`
+	Type            string                 `bson:"type" json:"type" validate:"required"`
+	Subtype         string                 `bson:"subtype" json:"subtype,omitempty"`
+	Tags            []string               `bson:"tags" json:"tags,omitempty"`
+	TenantID        string                 `bson:"tenant_id" json:"tenant_id" validate:"required"`
+	CreatedBy       string                 `bson:"created_by" json:"created_by" validate:"required"`
+	LastUpdatedBy   string                 `bson:"last_updated_by" json:"last_updated_by" validate:"required"`
+	Metadata        map[string]interface{} `bson:"metadata" json:"metadata,omitempty"`
+	SchemaVersion   string                 `bson:"schema_version,omitempty" json:"schema_version,omitempty"`
+	EncryptedFields []string               `bson:"encrypted_fields,omitempty" json:"encrypted_fields,omitempty"`
+	References      []string               `bson:"references,omitempty" json:"references,omitempty"`
+	Overlays        EnvOverlays            `bson:"overlays,omitempty" json:"overlays,omitempty"`
 }
 
 // ConfigArchive represents a configuration archive entry
 type ConfigArchive struct {
-	*types.Base   `bson:",inline"`
-	ConfigID      primitive.ObjectID     `bson:"config_id" json:"config_id"`
-	Name          string                 `bson:"name" json:"name"`
-	Type          string                 `bson:"type" json:"type"`
-	Subtype       string                 `bson:"subtype" json:"subtype,omitempty"`
-	Tags          []string               `bson:"tags" json:"tags,omitempty"`
-	TenantID      string                 `bson:"tenant_id" json:"tenant_id"`
-	CreatedBy     string                 `bson:"created_by" json:"created_by"`
-	LastUpdatedBy string                 `bson:"last_updated_by" json:"last_updated_by"`
-	Metadata      map[string]interface{} `bson:"metadata" json:"metadata,omitempty"`
-	Version       int                    `bson:"version" json:"version"`
-	ArchivedAt    time.Time              `bson:"archived_at" json:"archived_at"`
-	ArchivedBy    string                 `bson:"archived_by" json:"archived_by"`
+	*types.Base     `bson:",inline"`
+	ConfigID        primitive.ObjectID     `bson:"config_id" json:"config_id"`
+	Name            string                 `bson:"name" json:"name"`
+	Namespace       string                 `bson:"namespace,omitempty" json:"namespace,omitempty"`
+	Type            string                 `bson:"type" json:"type"`
+	Subtype         string                 `bson:"subtype" json:"subtype,omitempty"`
+	Tags            []string               `bson:"tags" json:"tags,omitempty"`
+	TenantID        string                 `bson:"tenant_id" json:"tenant_id"`
+	CreatedBy       string                 `bson:"created_by" json:"created_by"`
+	LastUpdatedBy   string                 `bson:"last_updated_by" json:"last_updated_by"`
+	Metadata        map[string]interface{} `bson:"metadata" json:"metadata,omitempty"`
+	Version         int                    `bson:"version" json:"version"`
+	ArchivedAt      time.Time              `bson:"archived_at" json:"archived_at"`
+	ArchivedBy      string                 `bson:"archived_by" json:"archived_by"`
+	SchemaVersion   string                 `bson:"schema_version,omitempty" json:"schema_version,omitempty"`
+	EncryptedFields []string               `bson:"encrypted_fields,omitempty" json:"encrypted_fields,omitempty"`
+	Overlays        EnvOverlays            `bson:"overlays,omitempty" json:"overlays,omitempty"`
 }
 
 // CreateConfigRequest represents the request payload for creating a config
 type CreateConfigRequest struct {
-	Name     string                 `json:"name" validate:"required"`
-	Type     string                 `json:"type" validate:"required"`
-	Subtype  string                 `json:"subtype,omitempty"`
-	Tags     []string               `json:"tags,omitempty"`
-	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	Name      string                 `json:"name" validate:"required"`
+	Namespace string                 `json:"namespace,omitempty"`
+	Type      string                 `json:"type" validate:"required"`
+	Subtype   string                 `json:"subtype,omitempty"`
+	Tags      []string               `json:"tags,omitempty"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	Overlays  EnvOverlays            `json:"overlays,omitempty"`
 }
 
 // UpdateConfigRequest represents the request payload for updating a config
@@ -56,7 +72,9 @@ type UpdateConfigRequest struct {
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
 }
 
-// UpdateConfigWithIDRequest represents the request payload for updating a config with ID
+// UpdateConfigWithIDRequest represents the request payload for updating a config with ID.
+// When present, Overlays replaces all environment overlays of the config, and
+// ApplyAt schedules the update instead of applying it immediately.
 type UpdateConfigWithIDRequest struct {
 	ID       string                 `param:"id" validate:"required"`
 	Name     string                 `json:"name,omitempty"`
@@ -64,15 +82,28 @@ type UpdateConfigWithIDRequest struct {
 	Subtype  string                 `json:"subtype,omitempty"`
 	Tags     []string               `json:"tags,omitempty"`
 	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	Overlays EnvOverlays            `json:"overlays,omitempty"`
+	ApplyAt  *time.Time             `json:"apply_at,omitempty"`
 }
 
 // ConfigQuery represents query parameters for filtering configs
 type ConfigQuery struct {
-	Type    string `param:"type,omitempty"`
-	Subtype string `param:"subtype,omitempty"`
-	Tag     string `param:"tag,omitempty"`
-	Limit   int64  `param:"limit,omitempty"`
-	Skip    int64  `param:"skip,omitempty"`
+	Name            string `param:"name,omitempty"`
+	Namespace       string `param:"namespace,omitempty"`
+	NamespacePrefix string `param:"namespace_prefix,omitempty"`
+	Type            string `param:"type,omitempty"`
+	Subtype         string `param:"subtype,omitempty"`
+	Tag             string `param:"tag,omitempty"`
+	Limit           int64  `param:"limit,omitempty"`
+	Skip            int64  `param:"skip,omitempty"`
+}
+
+// GetConfigRequest represents request to get a config, optionally merged with an
+// environment overlay and with its references resolved
+type GetConfigRequest struct {
+	ID      string `param:"id" validate:"required"`
+	Env     string `param:"env,omitempty"`
+	Resolve bool   `param:"resolve,omitempty"`
 }
 
 // ConfigIDRequest represents request with config ID from path
@@ -80,19 +111,34 @@ type ConfigIDRequest struct {
 	ID string `param:"id" validate:"required"`
 }
 
+// RestoreConfigRequest represents request to restore a config from one of its archives
+type RestoreConfigRequest struct {
+	ID        string `param:"id" validate:"required"`
+	ArchiveID string `param:"archive_id" validate:"required"`
+}
+
 // ConfigResponse represents the response payload for config operations
 type ConfigResponse struct {
-	ID            primitive.ObjectID     `json:"id"`
-	Name          string                 `json:"name"`
-	Type          string                 `json:"type"`
-	Subtype       string                 `json:"subtype,omitempty"`
-	Tags          []string               `json:"tags,omitempty"`
-	TenantID      string                 `json:"tenant_id"`
-	CreatedBy     string                 `json:"created_by"`
-	LastUpdatedBy string                 `json:"last_updated_by"`
-	Metadata      map[string]interface{} `json:"metadata,omitempty"`
-	CreatedAt     time.Time              `json:"created_at"`
-	UpdatedAt     time.Time              `json:"updated_at"`
+	ID              primitive.ObjectID     `json:"id"`
+	Name            string                 `json:"name"`
+	Namespace       string                 `json:"namespace,omitempty"`
+	Type            string                 `json:"type"`
+	Subtype         string                 `json:"subtype,omitempty"`
+	Tags            []string               `json:"tags,omitempty"`
+	TenantID        string                 `json:"tenant_id"`
+	CreatedBy       string                 `json:"created_by"`
+	LastUpdatedBy   string                 `json:"last_updated_by"`
+	Metadata        map[string]interface{} `json:"metadata,omitempty"`
+	SchemaVersion   string                 `json:"schema_version,omitempty"`
+	EncryptedFields []string               `json:"encrypted_fields,omitempty"`
+	References      []string               `json:"references,omitempty"`
+	Overlays        EnvOverlays            `json:"overlays,omitempty"`
+	Env             string                 `json:"env,omitempty"`
+	// DecryptionError is set on a listed config that could not be decrypted;
+	// its metadata and overlays are then the stored ciphertext
+	DecryptionError string    `json:"decryption_error,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
 }
 
 // ConfigArchiveResponse represents the response payload for config archive operations
@@ -100,6 +146,7 @@ type ConfigArchiveResponse struct {
 	ID            primitive.ObjectID     `json:"id"`
 	ConfigID      primitive.ObjectID     `json:"config_id"`
 	Name          string                 `json:"name"`
+	Namespace     string                 `json:"namespace,omitempty"`
 	Type          string                 `json:"type"`
 	Subtype       string                 `json:"subtype,omitempty"`
 	Tags          []string               `json:"tags,omitempty"`
@@ -108,6 +155,7 @@ type ConfigArchiveResponse struct {
 	LastUpdatedBy string                 `json:"last_updated_by"`
 	Metadata      map[string]interface{} `json:"metadata,omitempty"`
 	Version       int                    `json:"version"`
+	SchemaVersion string                 `json:"schema_version,omitempty"`
 	ArchivedAt    time.Time              `json:"archived_at"`
 	ArchivedBy    string                 `json:"archived_by"`
 	CreatedAt     time.Time              `json:"created_at"`
@@ -116,17 +164,22 @@ type ConfigArchiveResponse struct {
 // ToResponse converts a Config to ConfigResponse
 func (c *Config) ToResponse() ConfigResponse {
 	return ConfigResponse{
-		ID:            c.ID,
-		Name:          c.Name,
-		Type:          c.Type,
-		Subtype:       c.Subtype,
-		Tags:          c.Tags,
-		TenantID:      c.TenantID,
-		CreatedBy:     c.CreatedBy,
-		LastUpdatedBy: c.LastUpdatedBy,
-		Metadata:      c.Metadata,
-		CreatedAt:     c.CreatedAt,
-		UpdatedAt:     c.UpdatedAt,
+		ID:              c.ID,
+		Name:            c.Name,
+		Namespace:       c.Namespace,
+		Type:            c.Type,
+		Subtype:         c.Subtype,
+		Tags:            c.Tags,
+		TenantID:        c.TenantID,
+		CreatedBy:       c.CreatedBy,
+		LastUpdatedBy:   c.LastUpdatedBy,
+		Metadata:        c.Metadata,
+		SchemaVersion:   c.SchemaVersion,
+		EncryptedFields: c.EncryptedFields,
+		References:      c.References,
+		Overlays:        c.Overlays,
+		CreatedAt:       c.CreatedAt,
+		UpdatedAt:       c.UpdatedAt,
 	}
 }
 
@@ -136,6 +189,7 @@ func (ca *ConfigArchive) ToArchiveResponse() ConfigArchiveResponse {
 		ID:            ca.ID,
 		ConfigID:      ca.ConfigID,
 		Name:          ca.Name,
+		Namespace:     ca.Namespace,
 		Type:          ca.Type,
 		Subtype:       ca.Subtype,
 		Tags:          ca.Tags,
@@ -144,33 +198,108 @@ func (ca *ConfigArchive) ToArchiveResponse() ConfigArchiveResponse {
 		LastUpdatedBy: ca.LastUpdatedBy,
 		Metadata:      ca.Metadata,
 		Version:       ca.Version,
+		SchemaVersion: ca.SchemaVersion,
 		ArchivedAt:    ca.ArchivedAt,
 		ArchivedBy:    ca.ArchivedBy,
 		CreatedAt:     ca.CreatedAt,
 	}
 }
 
+// Resource returns the authorization resource describing the config
+func (c *Config) Resource() auth.Resource {
+	return auth.Resource{
+		Type:    c.Type,
+		Subtype: c.Subtype,
+		Tags:    c.Tags,
+	}
+}
+
 // ToArchive converts a Config to ConfigArchive
 func (c *Config) ToArchive(version int, archivedBy string) ConfigArchive {
 	return ConfigArchive{
-		Base:          &types.Base{},
-		ConfigID:      c.ID,
-		Name:          c.Name,
-		Type:          c.Type,
-		Subtype:       c.Subtype,
-		Tags:          c.Tags,
-		TenantID:      c.TenantID,
-		CreatedBy:     c.CreatedBy,
-		LastUpdatedBy: c.LastUpdatedBy,
-		Metadata:      c.Metadata,
-		Version:       version,
-		ArchivedAt:    time.Now(),
-		ArchivedBy:    archivedBy,
+		Base:            &types.Base{},
+		ConfigID:        c.ID,
+		Name:            c.Name,
+		Namespace:       c.Namespace,
+		Type:            c.Type,
+		Subtype:         c.Subtype,
+		Tags:            c.Tags,
+		TenantID:        c.TenantID,
+		CreatedBy:       c.CreatedBy,
+		LastUpdatedBy:   c.LastUpdatedBy,
+		Metadata:        c.Metadata,
+		Version:         version,
+		ArchivedAt:      time.Now(),
+		ArchivedBy:      archivedBy,
+		SchemaVersion:   c.SchemaVersion,
+		EncryptedFields: c.EncryptedFields,
+		Overlays:        c.Overlays,
 	}
 }
 
+// ConfigKey identifies a config by its namespace and natural key, type/subtype/name
+type ConfigKey struct {
+	Namespace string
+	Key       string
+}
+
 // DecryptFieldRequest represents a request to decrypt a specific field
 type DecryptFieldRequest struct {
 	ConfigID  string `json:"config_id" validate:"required"`
 	FieldName string `json:"field_name" validate:"required"`
 }
+
+// Config event kinds
+const (
+	ConfigEventCreated = "created"
+	ConfigEventUpdated = "updated"
+	ConfigEventDeleted = "deleted"
+)
+
+// ConfigEvent is a change of a config streamed to watchers. Config is the
+// config after the change; for deletions, the config before it.
+type ConfigEvent struct {
+	Kind       string         `json:"kind"`
+	ConfigID   string         `json:"config_id"`
+	Config     ConfigResponse `json:"config"`
+	OccurredAt time.Time      `json:"occurred_at"`
+}
+
+// PlainValue converts a metadata value decoded from BSON into the types JSON
+// decoding produces: documents at any depth become maps and slices, so a
+// primitive.D is an object rather than an array, and numbers become float64
+func PlainValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[key] = PlainValue(item)
+		}
+		return m
+	case primitive.M:
+		return PlainValue(map[string]interface{}(v))
+	case primitive.D:
+		m := make(map[string]interface{}, len(v))
+		for _, elem := range v {
+			m[elem.Key] = PlainValue(elem.Value)
+		}
+		return m
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = PlainValue(item)
+		}
+		return items
+	case primitive.A:
+		return PlainValue([]interface{}(v))
+	case int:
+		return float64(v)
+	case int32:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float32:
+		return float64(v)
+	}
+	return value
+}
@@ -0,0 +1,26 @@
+package models
+
+// ConsulKVRequest represents a read of the Consul KV compatible API. Key is
+// type/subtype/name, optionally followed by a metadata field path. Recurse,
+// Keys and Raw follow Consul's query parameters of the same names; Index and
+// Wait make it a blocking query. Namespace uses Consul's ns parameter.
+type ConsulKVRequest struct {
+	Key       string `param:"key"`
+	Recurse   bool   `param:"recurse,omitempty"`
+	Keys      bool   `param:"keys,omitempty"`
+	Raw       bool   `param:"raw,omitempty"`
+	Index     int64  `param:"index,omitempty"`
+	Wait      string `param:"wait,omitempty"`
+	Namespace string `param:"ns,omitempty"`
+}
+
+// ConsulKVPair is a key/value entry in the shape of Consul's KV API. Value is
+// encoded as base64 by encoding/json, like in Consul.
+type ConsulKVPair struct {
+	LockIndex   uint64
+	Key         string
+	Flags       uint64
+	Value       []byte
+	CreateIndex uint64
+	ModifyIndex uint64
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"time"
+
+	"makatom-api-config/internal/auth"
+	"makatom/common/pkg/types"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	DraftStatusPending    = "pending"
+	DraftStatusApproved   = "approved"
+	DraftStatusRejected   = "rejected"
+	DraftStatusPublishing = "publishing"
+	DraftStatusPublished  = "published"
+	DraftStatusWithdrawn  = "withdrawn"
+)
+
+const (
+	DraftDecisionApprove = "approve"
+	DraftDecisionReject  = "reject"
+)
+
+const (
+	DraftChangeAdded   = "added"
+	DraftChangeRemoved = "removed"
+	DraftChangeChanged = "changed"
+)
+
+// ConfigDraft is a proposed change to a config awaiting review. Nil Tags,
+// Metadata or Overlays leave that part of the config unchanged; metadata and
+// overlays are stored encrypted like the config itself.
+type ConfigDraft struct {
+	*types.Base       `bson:",inline"`
+	ConfigID          primitive.ObjectID     `bson:"config_id" json:"config_id"`
+	TenantID          string                 `bson:"tenant_id" json:"tenant_id"`
+	Name              string                 `bson:"name" json:"name"`
+	Namespace         string                 `bson:"namespace,omitempty" json:"namespace,omitempty"`
+	Type              string                 `bson:"type" json:"type"`
+	Subtype           string                 `bson:"subtype" json:"subtype,omitempty"`
+	ConfigTags        []string               `bson:"tags" json:"config_tags,omitempty"`
+	Tags              []string               `bson:"proposed_tags,omitempty" json:"tags,omitempty"`
+	Metadata          map[string]interface{} `bson:"metadata,omitempty" json:"metadata,omitempty"`
+	Overlays          EnvOverlays            `bson:"overlays,omitempty" json:"overlays,omitempty"`
+	Comment           string                 `bson:"comment,omitempty" json:"comment,omitempty"`
+	BaseUpdatedAt     time.Time              `bson:"base_updated_at" json:"base_updated_at"`
+	SecretChange      bool                   `bson:"secret_change" json:"secret_change"`
+	RequiredApprovals int                    `bson:"required_approvals" json:"required_approvals"`
+	Status            string                 `bson:"status" json:"status"`
+	Reviews           []DraftReview          `bson:"reviews" json:"reviews"`
+	CreatedBy         string                 `bson:"created_by" json:"created_by"`
+	ClaimedAt         *time.Time             `bson:"claimed_at,omitempty" json:"-"`
+	PublishedBy       string                 `bson:"published_by,omitempty" json:"published_by,omitempty"`
+	PublishedAt       *time.Time             `bson:"published_at,omitempty" json:"published_at,omitempty"`
+}
+
+// DraftReview is one reviewer's decision on a draft
+type DraftReview struct {
+	Reviewer   string    `bson:"reviewer" json:"reviewer"`
+	Decision   string    `bson:"decision" json:"decision"`
+	Comment    string    `bson:"comment,omitempty" json:"comment,omitempty"`
+	ReviewedAt time.Time `bson:"reviewed_at" json:"reviewed_at"`
+}
+
+// Approvals counts the approving reviews of the draft
+func (d *ConfigDraft) Approvals() int {
+	approvals := 0
+	for _, review := range d.Reviews {
+		if review.Decision == DraftDecisionApprove {
+			approvals++
+		}
+	}
+	return approvals
+}
+
+// Resource returns the authorization resource of the config the draft changes
+func (d *ConfigDraft) Resource() auth.Resource {
+	return auth.Resource{
+		Type:    d.Type,
+		Subtype: d.Subtype,
+		Tags:    d.ConfigTags,
+	}
+}
+
+// CreateDraftRequest represents the request payload for proposing a change to a config
+type CreateDraftRequest struct {
+	ID       string                 `param:"id" validate:"required"`
+	Tags     []string               `json:"tags,omitempty"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	Overlays EnvOverlays            `json:"overlays,omitempty"`
+	Comment  string                 `json:"comment,omitempty"`
+}
+
+// DraftQuery represents query parameters for filtering drafts
+type DraftQuery struct {
+	ConfigID string `param:"config_id,omitempty"`
+	Status   string `param:"status,omitempty"`
+	Limit    int64  `param:"limit,omitempty"`
+	Skip     int64  `param:"skip,omitempty"`
+}
+
+// DraftIDRequest represents request with draft ID from path
+type DraftIDRequest struct {
+	ID string `param:"id" validate:"required"`
+}
+
+// ReviewDraftRequest represents a reviewer's decision on a draft
+type ReviewDraftRequest struct {
+	ID      string `param:"id" validate:"required"`
+	Comment string `json:"comment,omitempty"`
+}
+
+// DraftChange is a single difference between a config and a draft. Path is
+// tags, metadata.<field> or overlays.<env>.<field>, dotted for nested fields.
+type DraftChange struct {
+	Path     string      `json:"path"`
+	Change   string      `json:"change"`
+	Before   interface{} `json:"before,omitempty"`
+	After    interface{} `json:"after,omitempty"`
+	Redacted bool        `json:"redacted,omitempty"`
+}
+
+// DraftResponse is a draft with its review state and, for a single draft, the
+// diff against the current config
+type DraftResponse struct {
+	ConfigDraft
+	Approvals int           `json:"approvals"`
+	Stale     bool          `json:"stale"`
+	Diff      []DraftChange `json:"diff,omitempty"`
+}
@@ -0,0 +1,54 @@
+package models
+
+// EvaluateFlagRequest represents the payload for evaluating a feature flag.
+// The flag is the feature_flag config named Key in Namespace; Env selects the
+// environment overlay the flag is evaluated with.
+type EvaluateFlagRequest struct {
+	Key       string                 `json:"key" validate:"required"`
+	Namespace string                 `json:"namespace,omitempty"`
+	Env       string                 `json:"env,omitempty"`
+	Context   map[string]interface{} `json:"context"`
+}
+
+// FlagEvaluation is the variant a feature flag serves for an evaluation context
+// and the reason it was chosen
+type FlagEvaluation struct {
+	Key       string      `json:"key"`
+	ConfigID  string      `json:"config_id"`
+	Variant   string      `json:"variant"`
+	Value     interface{} `json:"value"`
+	Reason    string      `json:"reason"`
+	Rule      string      `json:"rule,omitempty"`
+	ErrorCode string      `json:"error_code,omitempty"`
+}
+
+// OFREPEvaluateRequest represents an OpenFeature Remote Evaluation Protocol
+// request for a single flag. Namespace and Env are extensions selecting the
+// flags and their environment overlay.
+type OFREPEvaluateRequest struct {
+	Key       string                 `param:"key" validate:"required"`
+	Namespace string                 `param:"namespace,omitempty"`
+	Env       string                 `param:"env,omitempty"`
+	Context   map[string]interface{} `json:"context"`
+}
+
+// OFREPBulkEvaluateRequest represents an OFREP request evaluating every flag
+// the caller may read. IfNoneMatch is the ETag of a previous bulk response.
+type OFREPBulkEvaluateRequest struct {
+	Namespace   string                 `param:"namespace,omitempty"`
+	Env         string                 `param:"env,omitempty"`
+	IfNoneMatch string                 `header:"If-None-Match"`
+	Context     map[string]interface{} `json:"context"`
+}
+
+// OFREPEvaluation is an OFREP flag evaluation; ErrorCode and ErrorDetails are
+// set instead of the value when the flag could not be evaluated
+type OFREPEvaluation struct {
+	Key          string                 `json:"key"`
+	Value        interface{}            `json:"value,omitempty"`
+	Reason       string                 `json:"reason,omitempty"`
+	Variant      string                 `json:"variant,omitempty"`
+	Metadata     map[string]interface{} `json:"metadata,omitempty"`
+	ErrorCode    string                 `json:"errorCode,omitempty"`
+	ErrorDetails string                 `json:"errorDetails,omitempty"`
+}
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"time"
+
+	"makatom/common/pkg/types"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// MigrationOpRename moves the value of Field to To
+	MigrationOpRename = "rename"
+	// MigrationOpDefault sets Field to Value when it is missing
+	MigrationOpDefault = "default"
+	// MigrationOpEncrypt encrypts Field, which must be marked for encryption in the schema
+	MigrationOpEncrypt = "encrypt"
+)
+
+const (
+	MigrationStatusCompleted           = "completed"
+	MigrationStatusCompletedWithErrors = "completed_with_errors"
+	MigrationStatusDryRun              = "dry_run"
+)
+
+const (
+	MigrationResultMigrated  = "migrated"
+	MigrationResultUnchanged = "unchanged"
+	MigrationResultFailed    = "failed"
+	// MigrationResultSkipped marks configs the caller may not write or that
+	// are protected, which only change through reviewed drafts
+	MigrationResultSkipped = "skipped"
+)
+
+// MigrationTransform is a single declared change applied to config metadata
+type MigrationTransform struct {
+	Op    string      `bson:"op" json:"op" validate:"required"`
+	Field string      `bson:"field" json:"field" validate:"required"`
+	To    string      `bson:"to,omitempty" json:"to,omitempty"`
+	Value interface{} `bson:"value,omitempty" json:"value,omitempty"`
+}
+
+// MigrationConfigResult is the outcome of a migration for a single config
+type MigrationConfigResult struct {
+	ConfigID primitive.ObjectID `bson:"config_id" json:"config_id"`
+	Name     string             `bson:"name" json:"name"`
+	Status   string             `bson:"status" json:"status"`
+	Errors   []string           `bson:"errors,omitempty" json:"errors,omitempty"`
+}
+
+// Migration records a run of declared transforms across the configs of a subtype
+type Migration struct {
+	*types.Base   `bson:",inline"`
+	TenantID      string                  `bson:"tenant_id" json:"tenant_id"`
+	Type          string                  `bson:"type" json:"type"`
+	Subtype       string                  `bson:"subtype" json:"subtype"`
+	Transforms    []MigrationTransform    `bson:"transforms" json:"transforms"`
+	DryRun        bool                    `bson:"dry_run" json:"dry_run"`
+	SchemaVersion string                  `bson:"schema_version" json:"schema_version"`
+	Status        string                  `bson:"status" json:"status"`
+	Matched       int                     `bson:"matched" json:"matched"`
+	Migrated      int                     `bson:"migrated" json:"migrated"`
+	Unchanged     int                     `bson:"unchanged" json:"unchanged"`
+	Failed        int                     `bson:"failed" json:"failed"`
+	Skipped       int                     `bson:"skipped" json:"skipped"`
+	Results       []MigrationConfigResult `bson:"results" json:"results"`
+	CreatedBy     string                  `bson:"created_by" json:"created_by"`
+	CompletedAt   time.Time               `bson:"completed_at" json:"completed_at"`
+}
+
+// RunMigrationRequest represents the request payload for running a migration
+type RunMigrationRequest struct {
+	Type       string               `param:"type" validate:"required"`
+	Subtype    string               `param:"subtype" validate:"required"`
+	Transforms []MigrationTransform `json:"transforms"`
+	DryRun     bool                 `json:"dry_run,omitempty"`
+}
+
+// CompatibilityIssue describes why a stored config does not fit the current schema
+type CompatibilityIssue struct {
+	ConfigID          primitive.ObjectID     `json:"config_id"`
+	Name              string                 `json:"name"`
+	SchemaVersion     string                 `json:"schema_version,omitempty"`
+	Validation        interface{}            `json:"validation,omitempty"`
+	Overlays          map[string]interface{} `json:"overlays,omitempty"`
+	UnencryptedFields []string               `json:"unencrypted_fields,omitempty"`
+	Errors            []string               `json:"errors,omitempty"`
+}
+
+// CompatibilityReport lists the configs of a subtype that fail its current schema
+type CompatibilityReport struct {
+	Type          string               `json:"type"`
+	Subtype       string               `json:"subtype"`
+	SchemaVersion string               `json:"schema_version"`
+	Checked       int                  `json:"checked"`
+	Outdated      int                  `json:"outdated"`
+	Incompatible  int                  `json:"incompatible"`
+	Configs       []CompatibilityIssue `json:"configs"`
+}
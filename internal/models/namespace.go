@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// InheritedConfigRequest represents query parameters for reading a config merged
+// with the configs of the same type, subtype and name in the ancestor namespaces
+type InheritedConfigRequest struct {
+	Namespace string `param:"namespace" validate:"required"`
+	Type      string `param:"type" validate:"required"`
+	Subtype   string `param:"subtype,omitempty"`
+	Name      string `param:"name" validate:"required"`
+}
+
+// InheritedConfigResponse is the deep-merged metadata of a config and its ancestors
+type InheritedConfigResponse struct {
+	Namespace string                 `json:"namespace"`
+	Name      string                 `json:"name"`
+	Type      string                 `json:"type"`
+	Subtype   string                 `json:"subtype,omitempty"`
+	Metadata  map[string]interface{} `json:"metadata"`
+	// Provenance maps every metadata key (dotted for nested keys) to the namespace that supplied it
+	Provenance map[string]string `json:"provenance"`
+	Sources    []InheritedSource `json:"sources"`
+}
+
+// InheritedSource is one level that contributed to an inherited config, root first
+type InheritedSource struct {
+	Namespace     string    `json:"namespace"`
+	ConfigID      string    `json:"config_id"`
+	SchemaVersion string    `json:"schema_version,omitempty"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
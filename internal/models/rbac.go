@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"makatom-api-config/internal/auth"
+	"makatom/common/pkg/types"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RoleBinding grants a role to a subject within a tenant, optionally scoped
+// to a config type/subtype or tag
+type RoleBinding struct {
+	*types.Base `bson:",inline"`
+	TenantID    string     `bson:"tenant_id" json:"tenant_id"`
+	Subject     string     `bson:"subject" json:"subject"`
+	Role        auth.Role  `bson:"role" json:"role"`
+	Scope       auth.Scope `bson:"scope" json:"scope"`
+	CreatedBy   string     `bson:"created_by" json:"created_by"`
+}
+
+// CreateRoleBindingRequest represents the request payload for creating a role binding
+type CreateRoleBindingRequest struct {
+	Subject string `json:"subject" validate:"required"`
+	Role    string `json:"role" validate:"required"`
+	Type    string `json:"type,omitempty"`
+	Subtype string `json:"subtype,omitempty"`
+	Tag     string `json:"tag,omitempty"`
+}
+
+// RoleBindingQuery represents query parameters for listing role bindings
+type RoleBindingQuery struct {
+	Subject string `param:"subject,omitempty"`
+}
+
+// RoleBindingIDRequest represents request with role binding ID
+type RoleBindingIDRequest struct {
+	ID string `param:"id" validate:"required"`
+}
+
+// RoleBindingResponse represents the response payload for role binding operations
+type RoleBindingResponse struct {
+	ID        primitive.ObjectID `json:"id"`
+	TenantID  string             `json:"tenant_id"`
+	Subject   string             `json:"subject"`
+	Role      auth.Role          `json:"role"`
+	Scope     auth.Scope         `json:"scope"`
+	CreatedBy string             `json:"created_by"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+// ToResponse converts a RoleBinding to RoleBindingResponse
+func (b *RoleBinding) ToResponse() RoleBindingResponse {
+	return RoleBindingResponse{
+		ID:        b.ID,
+		TenantID:  b.TenantID,
+		Subject:   b.Subject,
+		Role:      b.Role,
+		Scope:     b.Scope,
+		CreatedBy: b.CreatedBy,
+		CreatedAt: b.CreatedAt,
+	}
+}
+
+// ToBinding converts a RoleBinding to the auth.Binding used for permission checks
+func (b *RoleBinding) ToBinding() auth.Binding {
+	return auth.Binding{
+		Role:  b.Role,
+		Scope: b.Scope,
+	}
+}
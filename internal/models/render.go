@@ -0,0 +1,55 @@
+package models
+
+// RenderConfigsRequest represents query parameters for rendering configs as manifests.
+// The config filters match ConfigQuery; config_name and config_namespace filter by
+// config name and namespace because name and namespace are those of the rendered
+// manifests.
+type RenderConfigsRequest struct {
+	Format                string `param:"format" validate:"required"`
+	Name                  string `param:"name,omitempty"`
+	Namespace             string `param:"namespace,omitempty"`
+	Labels                string `param:"labels,omitempty"`
+	PrefixKeys            bool   `param:"prefix_keys,omitempty"`
+	ConfigName            string `param:"config_name,omitempty"`
+	ConfigNamespace       string `param:"config_namespace,omitempty"`
+	ConfigNamespacePrefix string `param:"config_namespace_prefix,omitempty"`
+	Type                  string `param:"type,omitempty"`
+	Subtype               string `param:"subtype,omitempty"`
+	Tag                   string `param:"tag,omitempty"`
+}
+
+// Query returns the config filters of the request
+func (r RenderConfigsRequest) Query() ConfigQuery {
+	return ConfigQuery{
+		Name:            r.ConfigName,
+		Namespace:       r.ConfigNamespace,
+		NamespacePrefix: r.ConfigNamespacePrefix,
+		Type:            r.Type,
+		Subtype:         r.Subtype,
+		Tag:             r.Tag,
+	}
+}
+
+// RawResponse is returned as ServiceResponse data by endpoints that answer with
+// their own document format instead of the JSON envelope
+type RawResponse struct {
+	ContentType string
+	Headers     map[string]string
+	Body        []byte
+}
+
+// K8sManifest is a Kubernetes ConfigMap or Secret
+type K8sManifest struct {
+	APIVersion string            `yaml:"apiVersion"`
+	Kind       string            `yaml:"kind"`
+	Metadata   K8sObjectMeta     `yaml:"metadata"`
+	Type       string            `yaml:"type,omitempty"`
+	Data       map[string]string `yaml:"data"`
+}
+
+// K8sObjectMeta is the metadata of a Kubernetes object
+type K8sObjectMeta struct {
+	Name      string            `yaml:"name"`
+	Namespace string            `yaml:"namespace,omitempty"`
+	Labels    map[string]string `yaml:"labels,omitempty"`
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	"makatom-api-config/internal/auth"
+	"makatom/common/pkg/types"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	ScheduleStatusPending   = "pending"
+	ScheduleStatusApplying  = "applying"
+	ScheduleStatusApplied   = "applied"
+	ScheduleStatusFailed    = "failed"
+	ScheduleStatusCancelled = "cancelled"
+)
+
+// ScheduledChange is an update of a config that the scheduler applies at ApplyAt.
+// It is applied with the bindings its author holds when it is due. Nil Tags,
+// Metadata or Overlays leave that part of the config unchanged; metadata and
+// overlays are stored encrypted like the config itself.
+type ScheduledChange struct {
+	*types.Base `bson:",inline"`
+	ConfigID    primitive.ObjectID     `bson:"config_id" json:"config_id"`
+	TenantID    string                 `bson:"tenant_id" json:"tenant_id"`
+	Name        string                 `bson:"name" json:"name"`
+	Namespace   string                 `bson:"namespace,omitempty" json:"namespace,omitempty"`
+	Type        string                 `bson:"type" json:"type"`
+	Subtype     string                 `bson:"subtype" json:"subtype,omitempty"`
+	ConfigTags  []string               `bson:"tags" json:"config_tags,omitempty"`
+	Tags        []string               `bson:"proposed_tags,omitempty" json:"tags,omitempty"`
+	Metadata    map[string]interface{} `bson:"metadata,omitempty" json:"metadata,omitempty"`
+	Overlays    EnvOverlays            `bson:"overlays,omitempty" json:"overlays,omitempty"`
+	ApplyAt     time.Time              `bson:"apply_at" json:"apply_at"`
+	Status      string                 `bson:"status" json:"status"`
+	CreatedBy   string                 `bson:"created_by" json:"created_by"`
+	ClaimedAt   *time.Time             `bson:"claimed_at,omitempty" json:"-"`
+	AppliedAt   *time.Time             `bson:"applied_at,omitempty" json:"applied_at,omitempty"`
+	CancelledBy string                 `bson:"cancelled_by,omitempty" json:"cancelled_by,omitempty"`
+	Error       string                 `bson:"error,omitempty" json:"error,omitempty"`
+}
+
+// Resource returns the authorization resource of the config the change applies to
+func (c *ScheduledChange) Resource() auth.Resource {
+	return auth.Resource{
+		Type:    c.Type,
+		Subtype: c.Subtype,
+		Tags:    c.ConfigTags,
+	}
+}
+
+// ScheduleQuery represents query parameters for filtering scheduled changes
+type ScheduleQuery struct {
+	ConfigID string `param:"config_id,omitempty"`
+	Status   string `param:"status,omitempty"`
+	Limit    int64  `param:"limit,omitempty"`
+	Skip     int64  `param:"skip,omitempty"`
+}
+
+// ScheduleIDRequest represents request with scheduled change ID from path
+type ScheduleIDRequest struct {
+	ID string `param:"id" validate:"required"`
+}
@@ -0,0 +1,28 @@
+package models
+
+// SpringEnvironmentRequest represents a Spring Cloud Config Server request.
+// Profile is a comma-separated list of active profiles; Label selects the
+// version of the configs.
+type SpringEnvironmentRequest struct {
+	Application string `param:"application" validate:"required"`
+	Profile     string `param:"profile" validate:"required"`
+	Label       string `param:"label,omitempty"`
+	Namespace   string `param:"namespace,omitempty"`
+}
+
+// SpringEnvironment is the Environment document of Spring Cloud Config.
+// Property sources are ordered from highest to lowest precedence.
+type SpringEnvironment struct {
+	Name            string                 `json:"name"`
+	Profiles        []string               `json:"profiles"`
+	Label           *string                `json:"label"`
+	Version         *string                `json:"version"`
+	State           *string                `json:"state"`
+	PropertySources []SpringPropertySource `json:"propertySources"`
+}
+
+// SpringPropertySource is a named set of flattened properties
+type SpringPropertySource struct {
+	Name   string                 `json:"name"`
+	Source map[string]interface{} `json:"source"`
+}
@@ -0,0 +1,152 @@
+package models
+
+import (
+	"time"
+
+	"makatom/common/pkg/types"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// TypeSourceBuiltin marks types and subtypes from the in-process registry
+	TypeSourceBuiltin = "builtin"
+	// TypeSourceTenant marks types and subtypes stored for a tenant in MongoDB
+	TypeSourceTenant = "tenant"
+)
+
+// FieldSchema describes a single metadata field of a subtype
+type FieldSchema struct {
+	Type        string        `bson:"type" json:"type" validate:"required"`
+	Required    bool          `bson:"required" json:"required"`
+	Encryption  bool          `bson:"encryption" json:"encryption"`
+	Description string        `bson:"description,omitempty" json:"description,omitempty"`
+	Default     interface{}   `bson:"default,omitempty" json:"default,omitempty"`
+	Enum        []interface{} `bson:"enum,omitempty" json:"enum,omitempty"`
+}
+
+// MetadataSchema describes the metadata accepted by a subtype
+type MetadataSchema struct {
+	Properties map[string]FieldSchema `bson:"properties" json:"properties"`
+}
+
+// SubtypeDefinition is a tenant-defined subtype with its metadata schema
+type SubtypeDefinition struct {
+	Name           string         `bson:"name" json:"name"`
+	Description    string         `bson:"description,omitempty" json:"description,omitempty"`
+	MetadataSchema MetadataSchema `bson:"metadata_schema" json:"metadata_schema"`
+	CreatedBy      string         `bson:"created_by" json:"created_by"`
+	UpdatedBy      string         `bson:"updated_by" json:"updated_by"`
+	UpdatedAt      time.Time      `bson:"updated_at" json:"updated_at"`
+}
+
+// TypeDefinition is a tenant-defined config type stored in MongoDB. When Name
+// matches a built-in type, the document only contributes additional subtypes.
+type TypeDefinition struct {
+	*types.Base `bson:",inline"`
+	TenantID    string                       `bson:"tenant_id" json:"tenant_id"`
+	Name        string                       `bson:"name" json:"name"`
+	Description string                       `bson:"description,omitempty" json:"description,omitempty"`
+	Subtypes    map[string]SubtypeDefinition `bson:"subtypes" json:"subtypes"`
+	CreatedBy   string                       `bson:"created_by" json:"created_by"`
+	UpdatedBy   string                       `bson:"updated_by" json:"updated_by"`
+}
+
+// SubtypeView is a subtype in the merged built-in and tenant registry view
+type SubtypeView struct {
+	Name           string         `json:"name"`
+	Description    string         `json:"description,omitempty"`
+	Source         string         `json:"source"`
+	MetadataSchema MetadataSchema `json:"metadata_schema"`
+}
+
+// TypeView is a type in the merged built-in and tenant registry view
+type TypeView struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty"`
+	Source      string                 `json:"source"`
+	Subtypes    map[string]SubtypeView `json:"subtypes"`
+}
+
+// CreateTypeRequest represents the request payload for creating a tenant type
+type CreateTypeRequest struct {
+	Name        string `json:"name" validate:"required"`
+	Description string `json:"description,omitempty"`
+}
+
+// UpdateTypeRequest represents the request payload for updating a tenant type
+type UpdateTypeRequest struct {
+	Type        string `param:"type" validate:"required"`
+	Description string `json:"description"`
+}
+
+// TypeNameRequest represents request with type name from path
+type TypeNameRequest struct {
+	Type string `param:"type" validate:"required"`
+}
+
+// SubtypeNameRequest represents request with type and subtype names from path
+type SubtypeNameRequest struct {
+	Type    string `param:"type" validate:"required"`
+	Subtype string `param:"subtype" validate:"required"`
+}
+
+// CreateSubtypeRequest represents the request payload for creating a tenant subtype
+type CreateSubtypeRequest struct {
+	Type           string         `param:"type" validate:"required"`
+	Name           string         `json:"name" validate:"required"`
+	Description    string         `json:"description,omitempty"`
+	MetadataSchema MetadataSchema `json:"metadata_schema"`
+}
+
+// UpdateSubtypeRequest represents the request payload for updating a tenant subtype
+type UpdateSubtypeRequest struct {
+	Type           string         `param:"type" validate:"required"`
+	Subtype        string         `param:"subtype" validate:"required"`
+	Description    string         `json:"description,omitempty"`
+	MetadataSchema MetadataSchema `json:"metadata_schema"`
+}
+
+// ImportJSONSchemaRequest represents the request payload for defining a tenant
+// subtype from a JSON Schema document
+type ImportJSONSchemaRequest struct {
+	Type       string                 `param:"type" validate:"required"`
+	Subtype    string                 `param:"subtype" validate:"required"`
+	JSONSchema map[string]interface{} `json:"json_schema" validate:"required"`
+}
+
+// ValidateMetadataRequest represents the request payload for validating metadata
+// against the merged registry view
+type ValidateMetadataRequest struct {
+	Type     string                 `json:"type" validate:"required"`
+	Subtype  string                 `json:"subtype,omitempty"`
+	Metadata map[string]interface{} `json:"metadata"`
+}
+
+// TypeDefinitionResponse represents the response payload for tenant type operations
+type TypeDefinitionResponse struct {
+	ID          primitive.ObjectID           `json:"id"`
+	TenantID    string                       `json:"tenant_id"`
+	Name        string                       `json:"name"`
+	Description string                       `json:"description,omitempty"`
+	Subtypes    map[string]SubtypeDefinition `json:"subtypes"`
+	CreatedBy   string                       `json:"created_by"`
+	UpdatedBy   string                       `json:"updated_by"`
+	CreatedAt   time.Time                    `json:"created_at"`
+	UpdatedAt   time.Time                    `json:"updated_at"`
+}
+
+// ToResponse converts a TypeDefinition to TypeDefinitionResponse
+func (t *TypeDefinition) ToResponse() TypeDefinitionResponse {
+	return TypeDefinitionResponse{
+		ID:          t.ID,
+		TenantID:    t.TenantID,
+		Name:        t.Name,
+		Description: t.Description,
+		Subtypes:    t.Subtypes,
+		CreatedBy:   t.CreatedBy,
+		UpdatedBy:   t.UpdatedBy,
+		CreatedAt:   t.CreatedAt,
+		UpdatedAt:   t.UpdatedAt,
+	}
+}
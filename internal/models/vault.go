@@ -0,0 +1,57 @@
+package models
+
+// VaultSecretRequest represents a read of the Vault KV v2 compatible API. Path
+// is type/subtype/name, or type/name for configs without a subtype; Namespace
+// comes from Vault's namespace header.
+type VaultSecretRequest struct {
+	Path      string `param:"path"`
+	Version   int    `param:"version,omitempty"`
+	Namespace string `header:"X-Vault-Namespace"`
+}
+
+// VaultResponse is the response wrapper of the Vault HTTP API
+type VaultResponse struct {
+	RequestID     string      `json:"request_id"`
+	LeaseID       string      `json:"lease_id"`
+	Renewable     bool        `json:"renewable"`
+	LeaseDuration int         `json:"lease_duration"`
+	Data          interface{} `json:"data"`
+	WrapInfo      interface{} `json:"wrap_info"`
+	Warnings      []string    `json:"warnings"`
+	Auth          interface{} `json:"auth"`
+}
+
+// VaultSecret is the data of a KV v2 read: the secret and its version metadata
+type VaultSecret struct {
+	Data     map[string]interface{} `json:"data"`
+	Metadata VaultVersion           `json:"metadata"`
+}
+
+// VaultVersion describes a single version of a KV v2 secret
+type VaultVersion struct {
+	CreatedTime    string            `json:"created_time"`
+	CustomMetadata map[string]string `json:"custom_metadata"`
+	DeletionTime   string            `json:"deletion_time"`
+	Destroyed      bool              `json:"destroyed"`
+	Version        int               `json:"version"`
+}
+
+// VaultSecretMetadata is the data of a KV v2 metadata read
+type VaultSecretMetadata struct {
+	CasRequired        bool                         `json:"cas_required"`
+	CreatedTime        string                       `json:"created_time"`
+	CurrentVersion     int                          `json:"current_version"`
+	CustomMetadata     map[string]string            `json:"custom_metadata"`
+	DeleteVersionAfter string                       `json:"delete_version_after"`
+	MaxVersions        int                          `json:"max_versions"`
+	OldestVersion      int                          `json:"oldest_version"`
+	UpdatedTime        string                       `json:"updated_time"`
+	Versions           map[string]VaultVersionState `json:"versions"`
+}
+
+// VaultVersionState is a version entry of KV v2 secret metadata
+type VaultVersionState struct {
+	CreatedTime  string `json:"created_time"`
+	DeletionTime string `json:"deletion_time"`
+	Destroyed    bool   `json:"destroyed"`
+}
@@ -0,0 +1,259 @@
+// Package references parses and resolves references between configs.
+//
+// A metadata string may embed ${ref:type/subtype/name.field} (or
+// ${ref:type/name.field} for configs without a subtype). When the whole string
+// is a single reference, the resolved value keeps its type. A schema field of
+// type config_ref holds a bare reference, type/subtype/name[.field], which
+// resolves to the referenced field or, without a field, to the whole metadata.
+package references
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"makatom-api-config/internal/models"
+)
+
+// FieldType is the schema field type holding a bare config reference
+const FieldType = "config_ref"
+
+// MaxDepth bounds how many references are followed while resolving
+const MaxDepth = 16
+
+var exprPattern = regexp.MustCompile(`\$\{ref:([^}]*)\}`)
+
+// Ref points at a config and optionally one of its metadata fields
+type Ref struct {
+	Type    string
+	Subtype string
+	Name    string
+	Field   string
+}
+
+// Key identifies the referenced config; configs store the keys they reference
+func (r Ref) Key() string {
+	return Key(r.Type, r.Subtype, r.Name)
+}
+
+// String returns the reference in its written form
+func (r Ref) String() string {
+	path := r.Type + "/"
+	if r.Subtype != "" {
+		path += r.Subtype + "/"
+	}
+	path += r.Name
+	if r.Field != "" {
+		path += "." + r.Field
+	}
+	return path
+}
+
+// Key identifies a config by its natural key
+func Key(configType, subtype, name string) string {
+	return configType + "/" + subtype + "/" + name
+}
+
+// Parse parses type/[subtype/]name[.field]
+func Parse(expr string) (Ref, error) {
+	slash := strings.LastIndex(expr, "/")
+	if slash < 0 {
+		return Ref{}, fmt.Errorf("invalid reference %q, expected type/[subtype/]name[.field]", expr)
+	}
+
+	var ref Ref
+	ref.Name = expr[slash+1:]
+	if dot := strings.Index(ref.Name, "."); dot >= 0 {
+		ref.Name, ref.Field = ref.Name[:dot], ref.Name[dot+1:]
+		if ref.Field == "" {
+			return Ref{}, fmt.Errorf("invalid reference %q, empty field", expr)
+		}
+	}
+
+	parts := strings.Split(expr[:slash], "/")
+	switch len(parts) {
+	case 1:
+		ref.Type = parts[0]
+	case 2:
+		ref.Type, ref.Subtype = parts[0], parts[1]
+	default:
+		return Ref{}, fmt.Errorf("invalid reference %q, expected type/[subtype/]name[.field]", expr)
+	}
+	if ref.Type == "" || ref.Name == "" || (len(parts) == 2 && ref.Subtype == "") {
+		return Ref{}, fmt.Errorf("invalid reference %q, expected type/[subtype/]name[.field]", expr)
+	}
+	return ref, nil
+}
+
+// Collect returns the references made by metadata, with a message for every
+// malformed one. Embedded ${ref:} expressions must name a field.
+func Collect(schema models.MetadataSchema, metadata map[string]interface{}) ([]Ref, []string) {
+	var (
+		refs []Ref
+		errs []string
+	)
+
+	for _, name := range sortedKeys(metadata) {
+		value := metadata[name]
+		if schema.Properties[name].Type == FieldType {
+			expr, ok := value.(string)
+			if !ok {
+				continue
+			}
+			ref, err := Parse(expr)
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("field %q: %v", name, err))
+				continue
+			}
+			refs = append(refs, ref)
+			continue
+		}
+
+		walk(models.PlainValue(value), func(s string) {
+			for _, match := range exprPattern.FindAllStringSubmatch(s, -1) {
+				ref, err := Parse(match[1])
+				if err == nil && ref.Field == "" {
+					err = fmt.Errorf("reference %q must name a field", match[1])
+				}
+				if err != nil {
+					errs = append(errs, fmt.Sprintf("field %q: %v", name, err))
+					continue
+				}
+				refs = append(refs, ref)
+			}
+		})
+	}
+	return refs, errs
+}
+
+// Keys returns the sorted, distinct config keys of refs
+func Keys(refs []Ref) []string {
+	seen := make(map[string]bool)
+	keys := make([]string, 0)
+	for _, ref := range refs {
+		if !seen[ref.Key()] {
+			seen[ref.Key()] = true
+			keys = append(keys, ref.Key())
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Lookup returns the value a reference points at
+type Lookup func(ref Ref) (interface{}, error)
+
+// Resolve returns a copy of metadata with every reference replaced by its value
+func Resolve(schema models.MetadataSchema, metadata map[string]interface{}, lookup Lookup) (map[string]interface{}, error) {
+	resolved := make(map[string]interface{}, len(metadata))
+	for name, value := range metadata {
+		if schema.Properties[name].Type == FieldType {
+			if expr, ok := value.(string); ok {
+				ref, err := Parse(expr)
+				if err != nil {
+					return nil, fmt.Errorf("field %q: %w", name, err)
+				}
+				target, err := lookup(ref)
+				if err != nil {
+					return nil, fmt.Errorf("field %q: %w", name, err)
+				}
+				resolved[name] = target
+				continue
+			}
+		}
+
+		value, err := interpolate(models.PlainValue(value), lookup)
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %w", name, err)
+		}
+		resolved[name] = value
+	}
+	return resolved, nil
+}
+
+// interpolate replaces ${ref:} expressions in the strings of a plain value
+func interpolate(value interface{}, lookup Lookup) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		matches := exprPattern.FindAllStringSubmatchIndex(v, -1)
+		if len(matches) == 0 {
+			return v, nil
+		}
+
+		// A value that is exactly one reference keeps the type of the target
+		if len(matches) == 1 && matches[0][0] == 0 && matches[0][1] == len(v) {
+			ref, err := Parse(v[matches[0][2]:matches[0][3]])
+			if err != nil {
+				return nil, err
+			}
+			return lookup(ref)
+		}
+
+		var out strings.Builder
+		last := 0
+		for _, match := range matches {
+			out.WriteString(v[last:match[0]])
+			ref, err := Parse(v[match[2]:match[3]])
+			if err != nil {
+				return nil, err
+			}
+			target, err := lookup(ref)
+			if err != nil {
+				return nil, err
+			}
+			out.WriteString(fmt.Sprint(target))
+			last = match[1]
+		}
+		out.WriteString(v[last:])
+		return out.String(), nil
+
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			resolved, err := interpolate(item, lookup)
+			if err != nil {
+				return nil, err
+			}
+			out[key] = resolved
+		}
+		return out, nil
+
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			resolved, err := interpolate(item, lookup)
+			if err != nil {
+				return nil, err
+			}
+			out[i] = resolved
+		}
+		return out, nil
+	}
+	return value, nil
+}
+
+// walk calls fn for every string in a plain value
+func walk(value interface{}, fn func(string)) {
+	switch v := value.(type) {
+	case string:
+		fn(v)
+	case map[string]interface{}:
+		for _, item := range v {
+			walk(item, fn)
+		}
+	case []interface{}:
+		for _, item := range v {
+			walk(item, fn)
+		}
+	}
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
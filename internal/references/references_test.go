@@ -0,0 +1,202 @@
+package references
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"makatom-api-config/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		expr    string
+		want    Ref
+		wantErr bool
+	}{
+		{expr: "database/postgres/main", want: Ref{Type: "database", Subtype: "postgres", Name: "main"}},
+		{expr: "database/postgres/main.host", want: Ref{Type: "database", Subtype: "postgres", Name: "main", Field: "host"}},
+		{expr: "database/postgres/main.tls.ca", want: Ref{Type: "database", Subtype: "postgres", Name: "main", Field: "tls.ca"}},
+		{expr: "service/api", want: Ref{Type: "service", Name: "api"}},
+		{expr: "service/api.url", want: Ref{Type: "service", Name: "api", Field: "url"}},
+		{expr: "main", wantErr: true},
+		{expr: "database/postgres/main.", wantErr: true},
+		{expr: "a/b/c/d", wantErr: true},
+		{expr: "/postgres/main", wantErr: true},
+		{expr: "database//main", wantErr: true},
+		{expr: "database/postgres/", wantErr: true},
+		{expr: "database/postgres/.host", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			ref, err := Parse(tt.expr)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Parse(%q) = %+v, want an error", tt.expr, ref)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Parse(%q) failed: %v", tt.expr, err)
+			}
+			if ref != tt.want {
+				t.Fatalf("Parse(%q) = %+v, want %+v", tt.expr, ref, tt.want)
+			}
+			if ref.String() != tt.expr {
+				t.Fatalf("String() = %q, want %q", ref.String(), tt.expr)
+			}
+		})
+	}
+}
+
+func TestCollect(t *testing.T) {
+	schema := models.MetadataSchema{Properties: map[string]models.FieldSchema{
+		"database": {Type: FieldType},
+		"url":      {Type: "string"},
+		"options":  {Type: "object"},
+	}}
+
+	tests := []struct {
+		name     string
+		metadata map[string]interface{}
+		keys     []string
+		errs     int
+	}{
+		{
+			name:     "bare reference",
+			metadata: map[string]interface{}{"database": "database/postgres/main"},
+			keys:     []string{"database/postgres/main"},
+		},
+		{
+			name:     "embedded references",
+			metadata: map[string]interface{}{"url": "postgres://${ref:database/postgres/main.host}:${ref:database/postgres/main.port}/app"},
+			keys:     []string{"database/postgres/main"},
+		},
+		{
+			name: "nested in BSON documents",
+			metadata: map[string]interface{}{"options": primitive.D{
+				{Key: "hosts", Value: primitive.A{"${ref:cache/redis/sessions.host}"}},
+				{Key: "api", Value: primitive.M{"url": "${ref:service/api.url}"}},
+			}},
+			keys: []string{"cache/redis/sessions", "service//api"},
+		},
+		{
+			name:     "embedded reference without a field",
+			metadata: map[string]interface{}{"url": "${ref:database/postgres/main}"},
+			keys:     []string{},
+			errs:     1,
+		},
+		{
+			name:     "malformed references",
+			metadata: map[string]interface{}{"database": "main", "url": "${ref:a/b/c/d.e}"},
+			keys:     []string{},
+			errs:     2,
+		},
+		{
+			name:     "no references",
+			metadata: map[string]interface{}{"url": "postgres://localhost/app", "options": map[string]interface{}{"ssl": true}},
+			keys:     []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			refs, errs := Collect(schema, tt.metadata)
+			if len(errs) != tt.errs {
+				t.Fatalf("got errors %v, want %d", errs, tt.errs)
+			}
+			if keys := Keys(refs); !reflect.DeepEqual(keys, tt.keys) {
+				t.Fatalf("got keys %v, want %v", keys, tt.keys)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	schema := models.MetadataSchema{Properties: map[string]models.FieldSchema{
+		"database": {Type: FieldType},
+	}}
+	targets := map[string]map[string]interface{}{
+		"database/postgres/main": {"host": "db.internal", "port": 5432.0},
+	}
+	lookup := func(ref Ref) (interface{}, error) {
+		metadata, exists := targets[ref.Key()]
+		if !exists {
+			return nil, errors.New("config " + ref.String() + " does not exist")
+		}
+		if ref.Field == "" {
+			return metadata, nil
+		}
+		return metadata[ref.Field], nil
+	}
+
+	tests := []struct {
+		name     string
+		metadata map[string]interface{}
+		want     map[string]interface{}
+		wantErr  bool
+	}{
+		{
+			name:     "bare reference to the whole config",
+			metadata: map[string]interface{}{"database": "database/postgres/main"},
+			want:     map[string]interface{}{"database": map[string]interface{}{"host": "db.internal", "port": 5432.0}},
+		},
+		{
+			name:     "bare reference to a field",
+			metadata: map[string]interface{}{"database": "database/postgres/main.host"},
+			want:     map[string]interface{}{"database": "db.internal"},
+		},
+		{
+			name:     "whole string keeps the type",
+			metadata: map[string]interface{}{"port": "${ref:database/postgres/main.port}"},
+			want:     map[string]interface{}{"port": 5432.0},
+		},
+		{
+			name:     "interpolated into a string",
+			metadata: map[string]interface{}{"url": "postgres://${ref:database/postgres/main.host}:${ref:database/postgres/main.port}/app"},
+			want:     map[string]interface{}{"url": "postgres://db.internal:5432/app"},
+		},
+		{
+			name: "nested in BSON documents",
+			metadata: map[string]interface{}{"pool": primitive.D{
+				{Key: "hosts", Value: primitive.A{"${ref:database/postgres/main.host}", "replica.internal"}},
+			}},
+			want: map[string]interface{}{"pool": map[string]interface{}{
+				"hosts": []interface{}{"db.internal", "replica.internal"},
+			}},
+		},
+		{
+			name:     "values without references are kept",
+			metadata: map[string]interface{}{"ssl": true, "name": "app"},
+			want:     map[string]interface{}{"ssl": true, "name": "app"},
+		},
+		{
+			name:     "missing target",
+			metadata: map[string]interface{}{"url": "${ref:database/postgres/other.host}"},
+			wantErr:  true,
+		},
+		{
+			name:     "malformed bare reference",
+			metadata: map[string]interface{}{"database": "main"},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolved, err := Resolve(schema, tt.metadata, lookup)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Resolve = %v, want an error", resolved)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve failed: %v", err)
+			}
+			if !reflect.DeepEqual(resolved, tt.want) {
+				t.Fatalf("Resolve = %v, want %v", resolved, tt.want)
+			}
+		})
+	}
+}
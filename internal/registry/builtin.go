@@ -0,0 +1,75 @@
+package registry
+
+import (
+	"makatom-api-config/internal/flags"
+	"makatom-api-config/internal/models"
+	"makatom/common/pkg/types"
+)
+
+// builtinTypes returns the types of the in-process registry in the merged view
+// shape, along with the feature_flag type this service implements itself
+func builtinTypes() map[string]models.TypeView {
+	views := make(map[string]models.TypeView)
+	for name, configType := range types.GlobalConfigTypeRegistry.GetAllTypes() {
+		views[name] = builtinTypeView(configType)
+	}
+	views[flags.TypeName] = flags.TypeView()
+	return views
+}
+
+// builtinType returns a single built-in type in the merged view shape
+func builtinType(name string) (models.TypeView, bool) {
+	if name == flags.TypeName {
+		return flags.TypeView(), true
+	}
+	configType, exists := types.GlobalConfigTypeRegistry.GetType(name)
+	if !exists {
+		return models.TypeView{}, false
+	}
+	return builtinTypeView(configType), true
+}
+
+// builtinSubtype returns a single built-in subtype in the merged view shape
+func builtinSubtype(typeName, subtypeName string) (models.SubtypeView, bool) {
+	if typeName == flags.TypeName {
+		view, exists := flags.TypeView().Subtypes[subtypeName]
+		return view, exists
+	}
+	subtype, exists := types.GlobalConfigTypeRegistry.GetSubtype(typeName, subtypeName)
+	if !exists {
+		return models.SubtypeView{}, false
+	}
+	return builtinSubtypeView(subtype), true
+}
+
+func builtinTypeView(configType types.ConfigType) models.TypeView {
+	view := models.TypeView{
+		Name:        configType.Name,
+		Description: configType.Description,
+		Source:      models.TypeSourceBuiltin,
+		Subtypes:    make(map[string]models.SubtypeView),
+	}
+	for name, subtype := range configType.Subtypes {
+		view.Subtypes[name] = builtinSubtypeView(subtype)
+	}
+	return view
+}
+
+func builtinSubtypeView(subtype types.ConfigSubtype) models.SubtypeView {
+	schema := models.MetadataSchema{Properties: make(map[string]models.FieldSchema)}
+	for name, field := range subtype.MetadataSchema.Properties {
+		schema.Properties[name] = models.FieldSchema{
+			Type:        field.Type,
+			Required:    field.Required,
+			Encryption:  field.Encryption,
+			Description: field.Description,
+		}
+	}
+
+	return models.SubtypeView{
+		Name:           subtype.Name,
+		Description:    subtype.Description,
+		Source:         models.TypeSourceBuiltin,
+		MetadataSchema: schema,
+	}
+}
@@ -0,0 +1,78 @@
+package registry
+
+import (
+	"testing"
+
+	"makatom-api-config/internal/models"
+)
+
+func TestFieldCipher(t *testing.T) {
+	cipher, err := NewFieldCipher("test")
+	if err != nil {
+		t.Fatalf("failed to create cipher: %v", err)
+	}
+	location := FieldLocation("tenant-a", "database", "postgres", "password")
+	sealed, err := cipher.Encrypt("hunter2", location)
+	if err != nil {
+		t.Fatalf("failed to encrypt: %v", err)
+	}
+	if !IsEncrypted(sealed) {
+		t.Fatalf("IsEncrypted(%q) = false", sealed)
+	}
+
+	tests := []struct {
+		name     string
+		location string
+		wantErr  bool
+	}{
+		{"same location", location, false},
+		{"other tenant", FieldLocation("tenant-b", "database", "postgres", "password"), true},
+		{"other type", FieldLocation("tenant-a", "cache", "postgres", "password"), true},
+		{"other subtype", FieldLocation("tenant-a", "database", "mysql", "password"), true},
+		{"other field", FieldLocation("tenant-a", "database", "postgres", "token"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := cipher.Decrypt(sealed, tt.location)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Decrypt at %s = %v, want an error", tt.location, value)
+				}
+				return
+			}
+			if err != nil || value != "hunter2" {
+				t.Fatalf("Decrypt = %v, %v, want hunter2", value, err)
+			}
+		})
+	}
+}
+
+func TestValidateRejectsCiphertext(t *testing.T) {
+	schema := models.MetadataSchema{Properties: map[string]models.FieldSchema{
+		"password": {Type: "string", Encryption: true},
+		"note":     {Type: "string"},
+	}}
+
+	tests := []struct {
+		name  string
+		value interface{}
+		valid bool
+	}{
+		{"plaintext", "hunter2", true},
+		{"bound ciphertext", encryptedPrefix + "AAAA", false},
+		{"legacy ciphertext", legacyPrefix + "AAAA", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := validateAgainstSchema(schema, map[string]interface{}{"password": tt.value})
+			if result.Valid != tt.valid {
+				t.Fatalf("valid = %v, want %v: %v", result.Valid, tt.valid, result.Errors)
+			}
+		})
+	}
+
+	// Fields without encryption may hold any string
+	if result := validateAgainstSchema(schema, map[string]interface{}{"note": legacyPrefix + "AAAA"}); !result.Valid {
+		t.Fatalf("unencrypted field rejected: %v", result.Errors)
+	}
+}
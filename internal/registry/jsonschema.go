@@ -0,0 +1,211 @@
+package registry
+
+import (
+	"fmt"
+	"sort"
+
+	"makatom-api-config/internal/models"
+)
+
+const (
+	// JSONSchemaDialect is the JSON Schema draft emitted and accepted by the registry
+	JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+	// encryptedExtension marks fields stored encrypted
+	encryptedExtension = "x-encrypted"
+	// configRefExtension marks string fields holding a config_ref
+	configRefExtension = "x-config-ref"
+)
+
+var (
+	// jsonSchemaRootKeywords are the root keywords FromJSONSchema understands
+	jsonSchemaRootKeywords = keywordSet("$schema", "$id", "$comment", "title", "description",
+		"type", "properties", "required", "additionalProperties")
+	// jsonSchemaPropertyKeywords are the property keywords FromJSONSchema understands;
+	// annotations are accepted and dropped since they do not constrain values
+	jsonSchemaPropertyKeywords = keywordSet("type", "description", "default", "enum",
+		encryptedExtension, configRefExtension, "writeOnly", "readOnly", "title", "$comment",
+		"examples", "deprecated")
+)
+
+func keywordSet(keywords ...string) map[string]bool {
+	set := make(map[string]bool, len(keywords))
+	for _, keyword := range keywords {
+		set[keyword] = true
+	}
+	return set
+}
+
+// unsupportedKeywords lists the keywords of a schema object outside the known ones,
+// sorted. They would constrain values the registry cannot check.
+func unsupportedKeywords(object map[string]interface{}, known map[string]bool) []string {
+	var keywords []string
+	for keyword := range object {
+		if !known[keyword] {
+			keywords = append(keywords, keyword)
+		}
+	}
+	sort.Strings(keywords)
+	return keywords
+}
+
+// ToJSONSchema converts a subtype of the merged view into a JSON Schema document
+func ToJSONSchema(typeName string, subtype models.SubtypeView) map[string]interface{} {
+	properties := make(map[string]interface{}, len(subtype.MetadataSchema.Properties))
+	required := make([]string, 0)
+
+	for _, name := range sortedFieldNames(subtype.MetadataSchema) {
+		field := subtype.MetadataSchema.Properties[name]
+
+		property := map[string]interface{}{}
+		if field.Type == "config_ref" {
+			property["type"] = "string"
+			property[configRefExtension] = true
+		} else if field.Type != "" {
+			property["type"] = field.Type
+		}
+		if field.Description != "" {
+			property["description"] = field.Description
+		}
+		if field.Default != nil {
+			property["default"] = field.Default
+		}
+		if len(field.Enum) > 0 {
+			property["enum"] = field.Enum
+		}
+		if field.Encryption {
+			property[encryptedExtension] = true
+			property["writeOnly"] = true
+		}
+		properties[name] = property
+
+		if field.Required {
+			required = append(required, name)
+		}
+	}
+
+	document := map[string]interface{}{
+		"$schema":    JSONSchemaDialect,
+		"$id":        fmt.Sprintf("urn:makatom:config:%s:%s", typeName, subtype.Name),
+		"title":      fmt.Sprintf("%s/%s", typeName, subtype.Name),
+		"type":       "object",
+		"properties": properties,
+		"required":   required,
+	}
+	if subtype.Description != "" {
+		document["description"] = subtype.Description
+	}
+	// Tenant subtypes reject undeclared fields
+	if subtype.Source == models.TypeSourceTenant {
+		document["additionalProperties"] = false
+	}
+	return document
+}
+
+// FromJSONSchema converts an uploaded JSON Schema document into a metadata schema.
+// Only flat object schemas are supported: nested objects are typed "object", and
+// keywords the registry cannot enforce, such as pattern, minimum or items, are
+// reported as errors instead of being dropped.
+func FromJSONSchema(document map[string]interface{}) (models.MetadataSchema, string, []string) {
+	schema := models.MetadataSchema{Properties: map[string]models.FieldSchema{}}
+	var errs []string
+
+	for _, keyword := range unsupportedKeywords(document, jsonSchemaRootKeywords) {
+		errs = append(errs, fmt.Sprintf("keyword %q is not supported", keyword))
+	}
+
+	if dialect, ok := document["$schema"].(string); ok && dialect != JSONSchemaDialect {
+		errs = append(errs, fmt.Sprintf("unsupported $schema %q, expected %q", dialect, JSONSchemaDialect))
+	}
+	if rootType, ok := document["type"]; ok && rootType != "object" {
+		errs = append(errs, "root schema must be of type object")
+	}
+	description, _ := document["description"].(string)
+
+	required := map[string]bool{}
+	if list, ok := document["required"].([]interface{}); ok {
+		for _, entry := range list {
+			if name, ok := entry.(string); ok {
+				required[name] = true
+			}
+		}
+	}
+
+	properties, _ := document["properties"].(map[string]interface{})
+	names := make([]string, 0, len(properties))
+	for name := range properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		property, ok := properties[name].(map[string]interface{})
+		if !ok {
+			errs = append(errs, fmt.Sprintf("property %q must be an object", name))
+			continue
+		}
+		if _, hasRef := property["$ref"]; hasRef {
+			errs = append(errs, fmt.Sprintf("property %q: $ref is not supported", name))
+			continue
+		}
+		for _, keyword := range unsupportedKeywords(property, jsonSchemaPropertyKeywords) {
+			errs = append(errs, fmt.Sprintf("property %q: keyword %q is not supported", name, keyword))
+		}
+
+		fieldType, err := jsonSchemaType(property["type"])
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("property %q: %v", name, err))
+			continue
+		}
+
+		if isRef, _ := property[configRefExtension].(bool); isRef && fieldType == "string" {
+			fieldType = "config_ref"
+		}
+
+		field := models.FieldSchema{
+			Type:     fieldType,
+			Required: required[name],
+			Default:  property["default"],
+		}
+		field.Description, _ = property["description"].(string)
+		field.Encryption, _ = property[encryptedExtension].(bool)
+		if enum, ok := property["enum"].([]interface{}); ok {
+			field.Enum = enum
+		}
+		schema.Properties[name] = field
+	}
+
+	for name := range required {
+		if _, declared := schema.Properties[name]; !declared {
+			errs = append(errs, fmt.Sprintf("required property %q is not declared", name))
+		}
+	}
+
+	errs = append(errs, ValidateSchema(schema)...)
+	return schema, description, errs
+}
+
+// jsonSchemaType maps a JSON Schema "type" keyword onto a single field type.
+// Nullable unions such as ["string", "null"] are accepted.
+func jsonSchemaType(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case string:
+		if v == "null" {
+			return "", fmt.Errorf("type null is not supported")
+		}
+		return v, nil
+	case []interface{}:
+		var types []string
+		for _, entry := range v {
+			if name, ok := entry.(string); ok && name != "null" {
+				types = append(types, name)
+			}
+		}
+		if len(types) != 1 {
+			return "", fmt.Errorf("type unions are not supported")
+		}
+		return types[0], nil
+	case nil:
+		return "", fmt.Errorf("type is required")
+	}
+	return "", fmt.Errorf("invalid type keyword")
+}
@@ -0,0 +1,72 @@
+package registry
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"makatom-api-config/internal/models"
+)
+
+func TestJSONSchemaRoundTrip(t *testing.T) {
+	subtype := models.SubtypeView{
+		Name:   "postgres",
+		Source: models.TypeSourceTenant,
+		MetadataSchema: models.MetadataSchema{Properties: map[string]models.FieldSchema{
+			"host":     {Type: "string", Required: true, Description: "Database host"},
+			"port":     {Type: "integer", Default: 5432.0},
+			"mode":     {Type: "string", Enum: []interface{}{"disable", "require"}},
+			"password": {Type: "string", Encryption: true},
+			"replica":  {Type: "config_ref"},
+		}},
+	}
+
+	// Decode the document like an upload does
+	encoded, err := json.Marshal(ToJSONSchema("database", subtype))
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	var document map[string]interface{}
+	if err := json.Unmarshal(encoded, &document); err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+
+	schema, _, errs := FromJSONSchema(document)
+	if len(errs) > 0 {
+		t.Fatalf("FromJSONSchema failed: %v", errs)
+	}
+	if !reflect.DeepEqual(schema, subtype.MetadataSchema) {
+		t.Fatalf("got %+v, want %+v", schema, subtype.MetadataSchema)
+	}
+}
+
+func TestFromJSONSchemaUnsupportedKeywords(t *testing.T) {
+	tests := []struct {
+		name     string
+		property map[string]interface{}
+		root     map[string]interface{}
+		errs     int
+	}{
+		{name: "supported", property: map[string]interface{}{"type": "string", "title": "Host", "examples": []interface{}{"db"}}},
+		{name: "pattern", property: map[string]interface{}{"type": "string", "pattern": "^db"}, errs: 1},
+		{name: "bounds", property: map[string]interface{}{"type": "integer", "minimum": 1.0, "maximum": 65535.0}, errs: 2},
+		{name: "format and length", property: map[string]interface{}{"type": "string", "format": "hostname", "maxLength": 253.0}, errs: 2},
+		{name: "items", property: map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}}, errs: 1},
+		{name: "nested properties", property: map[string]interface{}{"type": "object", "properties": map[string]interface{}{}}, errs: 1},
+		{name: "root keyword", property: map[string]interface{}{"type": "string"}, root: map[string]interface{}{"minProperties": 1.0}, errs: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			document := map[string]interface{}{
+				"type":       "object",
+				"properties": map[string]interface{}{"field": tt.property},
+			}
+			for keyword, value := range tt.root {
+				document[keyword] = value
+			}
+			if _, _, errs := FromJSONSchema(document); len(errs) != tt.errs {
+				t.Fatalf("got errors %v, want %d", errs, tt.errs)
+			}
+		})
+	}
+}
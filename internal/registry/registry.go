@@ -0,0 +1,280 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"makatom-api-config/internal/flags"
+	"makatom-api-config/internal/models"
+	"makatom/common/pkg/database/mongodb"
+	"makatom/common/pkg/types"
+)
+
+// Registry is the merged view of the built-in type registry and the
+// tenant-defined types stored in MongoDB. Built-in subtypes always take
+// precedence; tenants may add new types and new subtypes to built-in types.
+type Registry struct {
+	repo   *mongodb.MongoRepository[models.TypeDefinition]
+	cipher *FieldCipher
+}
+
+// New creates a new Registry instance
+func New(typeCollection *mongo.Collection, cipher *FieldCipher) *Registry {
+	return &Registry{
+		repo:   mongodb.NewMongoRepository[models.TypeDefinition](typeCollection),
+		cipher: cipher,
+	}
+}
+
+// IsBuiltinType reports whether the type comes from the in-process registry
+func IsBuiltinType(name string) bool {
+	_, exists := builtinType(name)
+	return exists
+}
+
+// IsBuiltinSubtype reports whether the subtype comes from the in-process registry
+func IsBuiltinSubtype(typeName, subtypeName string) bool {
+	_, exists := builtinSubtype(typeName, subtypeName)
+	return exists
+}
+
+// GetAllTypes returns every type visible to the tenant
+func (r *Registry) GetAllTypes(ctx context.Context, tenantID string) (map[string]models.TypeView, error) {
+	views := builtinTypes()
+
+	definitions, err := r.repo.Find(ctx, bson.M{"tenant_id": tenantID}, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	for _, definition := range definitions {
+		views[definition.Name] = mergeDefinition(views[definition.Name], definition)
+	}
+
+	return views, nil
+}
+
+// GetType returns a single type visible to the tenant
+func (r *Registry) GetType(ctx context.Context, tenantID, name string) (models.TypeView, bool, error) {
+	view, builtin := builtinType(name)
+
+	definition, found, err := r.findDefinition(ctx, tenantID, name)
+	if err != nil {
+		return models.TypeView{}, false, err
+	}
+	if !builtin && !found {
+		return models.TypeView{}, false, nil
+	}
+	if found {
+		view = mergeDefinition(view, definition)
+	}
+
+	return view, true, nil
+}
+
+// GetSubtype returns a single subtype visible to the tenant
+func (r *Registry) GetSubtype(ctx context.Context, tenantID, typeName, subtypeName string) (models.SubtypeView, bool, error) {
+	if view, builtin := builtinSubtype(typeName, subtypeName); builtin {
+		return view, true, nil
+	}
+
+	definition, found, err := r.findDefinition(ctx, tenantID, typeName)
+	if err != nil || !found {
+		return models.SubtypeView{}, false, err
+	}
+
+	subtype, exists := definition.Subtypes[subtypeName]
+	if !exists {
+		return models.SubtypeView{}, false, nil
+	}
+	return tenantSubtypeView(subtype), true, nil
+}
+
+// ValidateMetadata validates metadata against the subtype schema. The returned
+// result is the validation detail to report back to the caller.
+func (r *Registry) ValidateMetadata(ctx context.Context, tenantID, typeName, subtypeName string, metadata map[string]interface{}) (bool, interface{}, error) {
+	if typeName == flags.TypeName && r.usesBuiltinSchema(typeName, subtypeName) {
+		errs := flags.Validate(subtypeName, metadata)
+		return len(errs) == 0, ValidationResult{Valid: len(errs) == 0, Errors: errs}, nil
+	}
+	if r.usesBuiltinSchema(typeName, subtypeName) {
+		result := types.GlobalConfigTypeRegistry.ValidateMetadata(typeName, subtypeName, metadata)
+		return result.Valid, result, nil
+	}
+
+	schema, err := r.tenantSchema(ctx, tenantID, typeName, subtypeName)
+	if err != nil {
+		return false, nil, err
+	}
+	result := validateAgainstSchema(schema, metadata)
+	return result.Valid, result, nil
+}
+
+// EncryptMetadata encrypts the metadata fields marked with encryption=true
+func (r *Registry) EncryptMetadata(ctx context.Context, tenantID, typeName, subtypeName string, metadata map[string]interface{}) (map[string]interface{}, error) {
+	// Feature flags have no encrypted fields
+	if typeName == flags.TypeName && r.usesBuiltinSchema(typeName, subtypeName) {
+		return metadata, nil
+	}
+	if r.usesBuiltinSchema(typeName, subtypeName) {
+		return types.GlobalConfigTypeRegistry.EncryptMetadata(typeName, subtypeName, metadata)
+	}
+
+	schema, err := r.tenantSchema(ctx, tenantID, typeName, subtypeName)
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted := make(map[string]interface{}, len(metadata))
+	for key, value := range metadata {
+		field, declared := schema.Properties[key]
+		if !declared || !field.Encryption || value == nil {
+			encrypted[key] = value
+			continue
+		}
+		// Stored ciphertexts are never accepted back; they must come in as plaintext
+		if IsEncrypted(value) {
+			return nil, fmt.Errorf("field %q: encrypted values are not accepted", key)
+		}
+		ciphertext, err := r.cipher.Encrypt(value, FieldLocation(tenantID, typeName, subtypeName, key))
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %v", key, err)
+		}
+		encrypted[key] = ciphertext
+	}
+	return encrypted, nil
+}
+
+// DecryptMetadata decrypts the metadata fields marked with encryption=true
+func (r *Registry) DecryptMetadata(ctx context.Context, tenantID, typeName, subtypeName string, metadata map[string]interface{}) (map[string]interface{}, error) {
+	decrypt, err := r.MetadataDecrypter(ctx, tenantID, typeName, subtypeName)
+	if err != nil {
+		return nil, err
+	}
+	return decrypt(metadata)
+}
+
+// Decrypter decrypts the metadata of one type/subtype
+type Decrypter func(metadata map[string]interface{}) (map[string]interface{}, error)
+
+// MetadataDecrypter resolves the schema of the type/subtype once, so that many
+// configs of it can be decrypted without further lookups. Only failures to load
+// the schema are returned; an unknown subtype yields a Decrypter that fails.
+func (r *Registry) MetadataDecrypter(ctx context.Context, tenantID, typeName, subtypeName string) (Decrypter, error) {
+	if typeName == flags.TypeName && r.usesBuiltinSchema(typeName, subtypeName) {
+		return func(metadata map[string]interface{}) (map[string]interface{}, error) {
+			return metadata, nil
+		}, nil
+	}
+	if r.usesBuiltinSchema(typeName, subtypeName) {
+		return func(metadata map[string]interface{}) (map[string]interface{}, error) {
+			return types.GlobalConfigTypeRegistry.DecryptMetadata(typeName, subtypeName, metadata)
+		}, nil
+	}
+
+	schema := models.MetadataSchema{}
+	if subtypeName != "" {
+		subtype, exists, err := r.GetSubtype(ctx, tenantID, typeName, subtypeName)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			return func(map[string]interface{}) (map[string]interface{}, error) {
+				return nil, fmt.Errorf("subtype %s/%s does not exist", typeName, subtypeName)
+			}, nil
+		}
+		schema = subtype.MetadataSchema
+	}
+	return r.schemaDecrypter(tenantID, typeName, subtypeName, schema), nil
+}
+
+// schemaDecrypter decrypts the fields the schema marks with encryption=true
+func (r *Registry) schemaDecrypter(tenantID, typeName, subtypeName string, schema models.MetadataSchema) Decrypter {
+	return func(metadata map[string]interface{}) (map[string]interface{}, error) {
+		decrypted := make(map[string]interface{}, len(metadata))
+		for key, value := range metadata {
+			field, declared := schema.Properties[key]
+			if !declared || !field.Encryption || !IsEncrypted(value) {
+				decrypted[key] = value
+				continue
+			}
+			plaintext, err := r.cipher.Decrypt(value.(string), FieldLocation(tenantID, typeName, subtypeName, key))
+			if err != nil {
+				return nil, fmt.Errorf("field %q: %v", key, err)
+			}
+			decrypted[key] = plaintext
+		}
+		return decrypted, nil
+	}
+}
+
+// usesBuiltinSchema reports whether metadata of the type/subtype is handled by
+// the in-process registry. Configs without a subtype of a built-in type are too.
+func (r *Registry) usesBuiltinSchema(typeName, subtypeName string) bool {
+	if subtypeName == "" {
+		return IsBuiltinType(typeName)
+	}
+	return IsBuiltinSubtype(typeName, subtypeName)
+}
+
+// tenantSchema returns the schema of a tenant-defined subtype. Configs of a
+// tenant type without a subtype have an empty, permissive schema.
+func (r *Registry) tenantSchema(ctx context.Context, tenantID, typeName, subtypeName string) (models.MetadataSchema, error) {
+	if subtypeName == "" {
+		return models.MetadataSchema{}, nil
+	}
+
+	subtype, exists, err := r.GetSubtype(ctx, tenantID, typeName, subtypeName)
+	if err != nil {
+		return models.MetadataSchema{}, err
+	}
+	if !exists {
+		return models.MetadataSchema{}, fmt.Errorf("subtype %s/%s does not exist", typeName, subtypeName)
+	}
+	return subtype.MetadataSchema, nil
+}
+
+// findDefinition loads the tenant definition of a type, if any
+func (r *Registry) findDefinition(ctx context.Context, tenantID, name string) (models.TypeDefinition, bool, error) {
+	definition, err := r.repo.FindOne(ctx, bson.M{"tenant_id": tenantID, "name": name})
+	if err != nil {
+		if err.Error() == "not found" {
+			return models.TypeDefinition{}, false, nil
+		}
+		return models.TypeDefinition{}, false, err
+	}
+	return definition, true, nil
+}
+
+// mergeDefinition layers a tenant definition over a (possibly empty) built-in view
+func mergeDefinition(view models.TypeView, definition models.TypeDefinition) models.TypeView {
+	if view.Name == "" {
+		view = models.TypeView{
+			Name:        definition.Name,
+			Description: definition.Description,
+			Source:      models.TypeSourceTenant,
+		}
+	}
+	if view.Subtypes == nil {
+		view.Subtypes = make(map[string]models.SubtypeView)
+	}
+
+	for name, subtype := range definition.Subtypes {
+		if _, builtin := view.Subtypes[name]; builtin {
+			continue
+		}
+		view.Subtypes[name] = tenantSubtypeView(subtype)
+	}
+	return view
+}
+
+func tenantSubtypeView(subtype models.SubtypeDefinition) models.SubtypeView {
+	return models.SubtypeView{
+		Name:           subtype.Name,
+		Description:    subtype.Description,
+		Source:         models.TypeSourceTenant,
+		MetadataSchema: subtype.MetadataSchema,
+	}
+}
@@ -0,0 +1,60 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+
+	"makatom-api-config/internal/models"
+)
+
+// SchemaInfo identifies the schema a config's metadata was written against
+type SchemaInfo struct {
+	Version         string
+	EncryptedFields []string
+	Schema          models.MetadataSchema
+}
+
+// SchemaVersion returns a stable fingerprint of a metadata schema. Any change
+// to a field (added, removed, renamed, retyped or newly encrypted) changes it.
+func SchemaVersion(schema models.MetadataSchema) string {
+	// encoding/json sorts map keys, so the encoding is canonical
+	encoded, _ := json.Marshal(schema.Properties)
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// EncryptedFields returns the sorted names of the fields marked with encryption=true
+func EncryptedFields(schema models.MetadataSchema) []string {
+	fields := make([]string, 0)
+	for name, field := range schema.Properties {
+		if field.Encryption {
+			fields = append(fields, name)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// Schema returns the current schema information of a type/subtype in the
+// merged view. Configs without a subtype have an empty schema.
+func (r *Registry) Schema(ctx context.Context, tenantID, typeName, subtypeName string) (SchemaInfo, error) {
+	schema := models.MetadataSchema{Properties: map[string]models.FieldSchema{}}
+	if subtypeName != "" {
+		subtype, exists, err := r.GetSubtype(ctx, tenantID, typeName, subtypeName)
+		if err != nil {
+			return SchemaInfo{}, err
+		}
+		if exists {
+			schema = subtype.MetadataSchema
+		}
+	}
+
+	return SchemaInfo{
+		Version:         SchemaVersion(schema),
+		EncryptedFields: EncryptedFields(schema),
+		Schema:          schema,
+	}, nil
+}
@@ -0,0 +1,177 @@
+package registry
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+
+	"makatom-api-config/internal/models"
+)
+
+// supportedFieldTypes lists the field types accepted in tenant-defined schemas
+var supportedFieldTypes = map[string]bool{
+	"string":     true,
+	"number":     true,
+	"integer":    true,
+	"boolean":    true,
+	"array":      true,
+	"object":     true,
+	"config_ref": true,
+}
+
+// ValidationResult is the outcome of validating metadata against a tenant-defined schema
+type ValidationResult struct {
+	Valid  bool     `json:"valid"`
+	Errors []string `json:"errors,omitempty"`
+}
+
+// ValidateSchema checks that a tenant-supplied metadata schema is well formed
+func ValidateSchema(schema models.MetadataSchema) []string {
+	var errs []string
+	for _, name := range sortedFieldNames(schema) {
+		field := schema.Properties[name]
+		if name == "" {
+			errs = append(errs, "field names must not be empty")
+			continue
+		}
+		if !supportedFieldTypes[field.Type] {
+			errs = append(errs, fmt.Sprintf("field %q has unsupported type %q", name, field.Type))
+			continue
+		}
+		if field.Type == "config_ref" && field.Encryption {
+			errs = append(errs, fmt.Sprintf("field %q: config_ref fields cannot be encrypted", name))
+		}
+		if field.Default != nil && !matchesType(field.Type, field.Default) {
+			errs = append(errs, fmt.Sprintf("field %q default does not match type %s", name, field.Type))
+		}
+		for _, value := range field.Enum {
+			if !matchesType(field.Type, value) {
+				errs = append(errs, fmt.Sprintf("field %q enum value %v does not match type %s", name, value, field.Type))
+			}
+		}
+	}
+	return errs
+}
+
+// validateAgainstSchema validates metadata against a tenant-defined schema
+func validateAgainstSchema(schema models.MetadataSchema, metadata map[string]interface{}) ValidationResult {
+	var errs []string
+
+	for _, name := range sortedFieldNames(schema) {
+		field := schema.Properties[name]
+		value, present := metadata[name]
+		if !present || value == nil {
+			if field.Required {
+				errs = append(errs, fmt.Sprintf("field %q is required", name))
+			}
+			continue
+		}
+
+		// Ciphertexts are only ever produced by the service; accepting one would
+		// let a caller copy another config's secret and read it back here
+		if field.Encryption && IsEncrypted(value) {
+			errs = append(errs, fmt.Sprintf("field %q must not hold an encrypted value", name))
+			continue
+		}
+
+		if !matchesType(field.Type, value) {
+			errs = append(errs, fmt.Sprintf("field %q must be of type %s", name, field.Type))
+			continue
+		}
+
+		if len(field.Enum) > 0 && !inEnum(field.Enum, value) {
+			errs = append(errs, fmt.Sprintf("field %q must be one of %v", name, field.Enum))
+		}
+	}
+
+	unknown := make([]string, 0)
+	for name := range metadata {
+		if _, declared := schema.Properties[name]; !declared {
+			unknown = append(unknown, name)
+		}
+	}
+	sort.Strings(unknown)
+	for _, name := range unknown {
+		errs = append(errs, fmt.Sprintf("field %q is not defined in the schema", name))
+	}
+
+	return ValidationResult{
+		Valid:  len(errs) == 0,
+		Errors: errs,
+	}
+}
+
+// matchesType reports whether a decoded JSON/BSON value matches a schema type
+func matchesType(fieldType string, value interface{}) bool {
+	value = models.PlainValue(value)
+	switch fieldType {
+	case "string", "config_ref":
+		_, ok := value.(string)
+		return ok
+	case "boolean":
+		_, ok := value.(bool)
+		return ok
+	case "number":
+		_, ok := toFloat(value)
+		return ok
+	case "integer":
+		f, ok := toFloat(value)
+		return ok && f == math.Trunc(f)
+	case "array":
+		if value == nil {
+			return false
+		}
+		kind := reflect.TypeOf(value).Kind()
+		return kind == reflect.Slice || kind == reflect.Array
+	case "object":
+		if value == nil {
+			return false
+		}
+		return reflect.TypeOf(value).Kind() == reflect.Map
+	}
+	return false
+}
+
+// toFloat converts the numeric types produced by JSON and BSON decoding
+func toFloat(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, true
+	case float32:
+		return float64(v), true
+	case int:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+// inEnum reports whether value equals one of the allowed values
+func inEnum(allowed []interface{}, value interface{}) bool {
+	for _, candidate := range allowed {
+		if reflect.DeepEqual(candidate, value) {
+			return true
+		}
+		// Numbers may differ in Go type between JSON and BSON decoding
+		cf, cok := toFloat(candidate)
+		vf, vok := toFloat(value)
+		if cok && vok && cf == vf {
+			return true
+		}
+	}
+	return false
+}
+
+// sortedFieldNames returns the schema field names in a stable order
+func sortedFieldNames(schema models.MetadataSchema) []string {
+	names := make([]string, 0, len(schema.Properties))
+	for name := range schema.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,89 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"makatom-api-config/internal/auth"
+	configServices "makatom-api-config/internal/services"
+)
+
+const (
+	// blockingDefaultWait and blockingMaxWait bound how long a read waits for a change
+	blockingDefaultWait = 60 * time.Second
+	blockingMaxWait     = 5 * time.Minute
+	// blockingWriteGrace is the time left to write the response after waiting
+	blockingWriteGrace = 15 * time.Second
+	// changeIndexHeader carries the change index of the tenant a read reflects
+	changeIndexHeader = "X-Config-Index"
+)
+
+// blockingRead lets clients long-poll a read. With ?wait_index=N the read waits
+// until the change index of the caller's tenant exceeds N, or ?wait (default
+// 60s, at most 5m) elapses, before running next. The index is read before the
+// data, so waiting on the returned index never misses a change.
+func blockingRead(configService *configServices.ConfigService, next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := auth.FromContext(r.Context())
+		if !ok {
+			writeEnvelopeError(w, http.StatusUnauthorized, "unauthenticated")
+			return
+		}
+
+		query := r.URL.Query()
+		var waitIndex int64
+		if raw := query.Get("wait_index"); raw != "" {
+			parsed, err := strconv.ParseInt(raw, 10, 64)
+			if err != nil || parsed < 0 {
+				writeEnvelopeError(w, http.StatusBadRequest, fmt.Sprintf("invalid wait_index %q", raw))
+				return
+			}
+			waitIndex = parsed
+		}
+		wait := blockingDefaultWait
+		if raw := query.Get("wait"); raw != "" {
+			parsed, err := time.ParseDuration(raw)
+			if err != nil || parsed <= 0 {
+				writeEnvelopeError(w, http.StatusBadRequest, fmt.Sprintf("invalid wait %q", raw))
+				return
+			}
+			wait = min(parsed, blockingMaxWait)
+		}
+
+		var index int64
+		var err error
+		if waitIndex > 0 {
+			extendWriteDeadline(w, wait)
+			index, err = configService.WaitForChange(r.Context(), principal.TenantID, waitIndex, wait)
+		} else {
+			index, err = configService.ChangeIndex(r.Context(), principal.TenantID)
+		}
+		if err != nil {
+			writeEnvelopeError(w, http.StatusInternalServerError, fmt.Sprintf("failed to get change index: %v", err))
+			return
+		}
+
+		w.Header().Set(changeIndexHeader, strconv.FormatInt(index, 10))
+		next.ServeHTTP(w, r)
+	}
+}
+
+// longPoll extends the write deadline of requests carrying the blocking query
+// parameter param, which may wait up to maxWait before answering
+func longPoll(param string, maxWait time.Duration, next http.Handler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Has(param) {
+			extendWriteDeadline(w, maxWait)
+		}
+		next.ServeHTTP(w, r)
+	}
+}
+
+// extendWriteDeadline lifts the server write timeout for a request that waits
+// before answering
+func extendWriteDeadline(w http.ResponseWriter, wait time.Duration) {
+	// Not every ResponseWriter supports deadlines; those have no timeout to lift
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(wait + blockingWriteGrace))
+}
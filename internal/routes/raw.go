@@ -0,0 +1,128 @@
+package routes
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"makatom-api-config/internal/models"
+	"makatom/common/pkg/handlers"
+)
+
+// rawHandler serves a service method whose data is a models.RawResponse, writing
+// the body verbatim instead of the JSON envelope. It is used by endpoints that
+// must answer in a foreign document format. Request fields are bound from the
+// JSON body, from the path and query through their param tags and from request
+// headers through their header tags. Service errors keep the envelope shape;
+// foreign error documents are returned as RawResponse data with an error status.
+func rawHandler[T any](fn func(context.Context, T) handlers.ServiceResponse) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req T
+		if r.Method == http.MethodPost || r.Method == http.MethodPut {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+				writeEnvelopeError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %v", err))
+				return
+			}
+		}
+		if err := bindParams(r, &req); err != nil {
+			writeEnvelopeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		resp := fn(r.Context(), req)
+		if resp.Error != "" {
+			writeEnvelopeError(w, resp.StatusCode, resp.Error)
+			return
+		}
+
+		raw, ok := resp.Data.(models.RawResponse)
+		if !ok {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(resp.StatusCode)
+			json.NewEncoder(w).Encode(map[string]interface{}{"data": resp.Data})
+			return
+		}
+
+		for key, value := range raw.Headers {
+			w.Header().Set(key, value)
+		}
+		if raw.ContentType != "" {
+			w.Header().Set("Content-Type", raw.ContentType)
+		}
+		w.WriteHeader(resp.StatusCode)
+		w.Write(raw.Body)
+	}
+}
+
+func writeEnvelopeError(w http.ResponseWriter, status int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+// bindParams fills the param-tagged fields of dst from the path values and the
+// query string, mirroring the binding of handlers.GenerateHandler, and the
+// header-tagged string fields from the request headers
+func bindParams(r *http.Request, dst interface{}) error {
+	v := reflect.ValueOf(dst).Elem()
+	t := v.Type()
+	query := r.URL.Query()
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if header := field.Tag.Get("header"); header != "" && v.Field(i).Kind() == reflect.String {
+			v.Field(i).SetString(r.Header.Get(header))
+			continue
+		}
+		tag := field.Tag.Get("param")
+		if tag == "" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+
+		values, present := query[name]
+		if pathValue := r.PathValue(name); pathValue != "" {
+			values = []string{pathValue}
+		}
+		// A boolean parameter without a value, like Consul's ?recurse, is set
+		if present && values[0] == "" && v.Field(i).Kind() == reflect.Bool {
+			v.Field(i).SetBool(true)
+			continue
+		}
+		if len(values) == 0 || values[0] == "" {
+			if strings.Contains(field.Tag.Get("validate"), "required") {
+				return fmt.Errorf("%s is required", name)
+			}
+			continue
+		}
+
+		target := v.Field(i)
+		switch target.Kind() {
+		case reflect.String:
+			target.SetString(values[0])
+		case reflect.Bool:
+			b, err := strconv.ParseBool(values[0])
+			if err != nil {
+				return fmt.Errorf("%s must be a boolean", name)
+			}
+			target.SetBool(b)
+		case reflect.Int, reflect.Int64:
+			n, err := strconv.ParseInt(values[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("%s must be an integer", name)
+			}
+			target.SetInt(n)
+		case reflect.Slice:
+			if target.Type().Elem().Kind() == reflect.String {
+				target.Set(reflect.ValueOf(append([]string(nil), values...)))
+			}
+		}
+	}
+	return nil
+}
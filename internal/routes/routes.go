@@ -1,20 +1,31 @@
 package routes
 
 import (
+	"context"
+	"log"
 	"net/http"
+	"os"
+	"strconv"
+	"time"
 
+	"makatom-api-config/internal/auth"
+	"makatom-api-config/internal/graphqlapi"
+	"makatom-api-config/internal/grpcapi"
 	"makatom-api-config/internal/models"
+	"makatom-api-config/internal/registry"
 	configServices "makatom-api-config/internal/services"
 	"makatom/common/pkg/config"
 	"makatom/common/pkg/database/mongodb"
 	"makatom/common/pkg/handlers"
-	commonServices "makatom/common/pkg/services"
 	"makatom/common/pkg/types"
+
+	"google.golang.org/grpc"
 )
 
 // RegisterConfigRoutes sets up and returns the main router for the config service.
-// It now uses the custom GenericRouter to handle dynamic path parameters.
-func RegisterConfigRoutes() http.Handler {
+// It now uses the custom GenericRouter to handle dynamic path parameters. The
+// gRPC server it returns serves the same services to gRPC clients.
+func RegisterConfigRoutes() (http.Handler, *grpc.Server) {
 	cfg := config.GetConfig()
 
 	// Initialize the type system
@@ -28,10 +39,60 @@ func RegisterConfigRoutes() http.Handler {
 	db := client.Database(cfg.MongoDatabase)
 	configCollection := db.Collection("configs")
 	archiveCollection := db.Collection("config_archives")
+	roleBindingCollection := db.Collection("role_bindings")
+	apiKeyCollection := db.Collection("api_keys")
+	typeCollection := db.Collection("config_types")
+	migrationCollection := db.Collection("config_migrations")
+	draftCollection := db.Collection("config_drafts")
+	scheduleCollection := db.Collection("config_schedules")
+	changeIndexCollection := db.Collection("config_change_indexes")
+
+	// Tenant-defined types are layered over the built-in registry
+	fieldCipher, err := registry.NewFieldCipher(os.Getenv("CONFIG_ENCRYPTION_KEY"))
+	if err != nil {
+		log.Fatalf("Failed to initialize field cipher: %v", err)
+	}
+	typeRegistry := registry.New(typeCollection, fieldCipher)
+
+	// Configs with protected tags only change through reviewed drafts
+	reviewPolicy, err := configServices.ReviewPolicyFromEnv()
+	if err != nil {
+		log.Fatalf("Invalid review policy: %v", err)
+	}
 
 	// Create services
-	configService := configServices.NewConfigService(configCollection, archiveCollection)
-	configTypeService := commonServices.NewConfigTypeService()
+	roleBindingService := configServices.NewRoleBindingService(roleBindingCollection)
+	apiKeyService := configServices.NewAPIKeyService(apiKeyCollection, roleBindingService)
+	configService := configServices.NewConfigService(configCollection, archiveCollection, scheduleCollection, changeIndexCollection, typeRegistry, reviewPolicy, roleBindingService, apiKeyService)
+	typeDefinitionService := configServices.NewTypeDefinitionService(typeCollection, configCollection, typeRegistry)
+	migrationService := configServices.NewMigrationService(configService, migrationCollection, typeRegistry)
+	renderService := configServices.NewRenderService(configService, typeRegistry)
+	draftService := configServices.NewDraftService(configService, draftCollection, typeRegistry)
+	flagService := configServices.NewFlagService(configService)
+	consulService := configServices.NewConsulService(configService)
+	springService := configServices.NewSpringService(configService)
+	vaultService := configServices.NewVaultService(configService)
+
+	// Scheduled updates are persisted, so changes due while the service was down apply on start
+	scheduleInterval := 30 * time.Second
+	if value := os.Getenv("CONFIG_SCHEDULER_INTERVAL"); value != "" {
+		if scheduleInterval, err = time.ParseDuration(value); err != nil || scheduleInterval <= 0 {
+			log.Fatalf("Invalid CONFIG_SCHEDULER_INTERVAL %q", value)
+		}
+	}
+	go configService.RunScheduler(context.Background(), scheduleInterval)
+
+	// Reads by ID are cached while a change stream keeps the cache current
+	cacheSize := 10000
+	if value := os.Getenv("CONFIG_CACHE_SIZE"); value != "" {
+		if cacheSize, err = strconv.Atoi(value); err != nil || cacheSize < 0 {
+			log.Fatalf("Invalid CONFIG_CACHE_SIZE %q", value)
+		}
+	}
+	go configService.RunCache(context.Background(), cacheSize)
+
+	// Every route is guarded by a permission; scoped checks happen in the services
+	authorizer := auth.NewAuthorizer(auth.NewTokenVerifier(os.Getenv("JWT_SECRET")), roleBindingService, apiKeyService)
 
 	// Define APIs directly using service functions.
 	// The Path field now includes the HTTP method, which the new router uses.
@@ -40,74 +101,327 @@ func RegisterConfigRoutes() http.Handler {
 		// Create config
 		{
 			Path:    "POST /config",
-			Handler: handlers.GenerateHandler(configService.CreateConfig, new(models.CreateConfigRequest)),
+			Handler: authorizer.Require(auth.PermConfigWrite, handlers.GenerateHandler(configService.CreateConfig, new(models.CreateConfigRequest))),
 		},
 
-		// Get all configs
+		// Get all configs, optionally blocking until the tenant's configs change
 		{
 			Path:    "GET /configs",
-			Handler: handlers.GenerateHandler(configService.GetConfigs, new(models.ConfigQuery)),
+			Handler: authorizer.Require(auth.PermConfigRead, blockingRead(configService, handlers.GenerateHandler(configService.GetConfigs, new(models.ConfigQuery)))),
+		},
+
+		// Render configs as Kubernetes manifests (plain YAML, not the JSON envelope)
+		{
+			Path:    "GET /configs/render",
+			Handler: authorizer.Require(auth.PermConfigRead, rawHandler(renderService.RenderConfigs)),
 		},
 
-		// Get config by ID
+		// Get config by ID, optionally blocking until the tenant's configs change
 		{
 			Path:    "GET /config",
-			Handler: handlers.GenerateHandler(configService.GetConfigByID, new(models.ConfigIDRequest)),
+			Handler: authorizer.Require(auth.PermConfigRead, blockingRead(configService, handlers.GenerateHandler(configService.GetConfigByID, new(models.GetConfigRequest)))),
+		},
+
+		// Config cache statistics of this replica
+		{
+			Path:    "GET /cache/stats",
+			Handler: authorizer.Require(auth.PermOpsRead, handlers.GenerateHandler(configService.GetCacheStats, new(types.EmptyRequest))),
+		},
+
+		// Get config merged with its ancestor namespaces
+		{
+			Path:    "GET /config/inherited",
+			Handler: authorizer.Require(auth.PermConfigRead, handlers.GenerateHandler(configService.GetInheritedConfig, new(models.InheritedConfigRequest))),
+		},
+
+		// Compare the environment overlays of a config
+		{
+			Path:    "GET /config/environments/diff",
+			Handler: authorizer.Require(auth.PermConfigRead, handlers.GenerateHandler(configService.GetEnvironmentDiff, new(models.ConfigIDRequest))),
 		},
 
 		// Update config
 		{
 			Path:    "PUT /config",
-			Handler: handlers.GenerateHandler(configService.UpdateConfig, new(models.UpdateConfigWithIDRequest)),
+			Handler: authorizer.Require(auth.PermConfigWrite, handlers.GenerateHandler(configService.UpdateConfig, new(models.UpdateConfigWithIDRequest))),
 		},
 
 		// Delete config
 		{
 			Path:    "DELETE /config",
-			Handler: handlers.GenerateHandler(configService.DeleteConfig, new(models.ConfigIDRequest)),
+			Handler: authorizer.Require(auth.PermConfigDelete, handlers.GenerateHandler(configService.DeleteConfig, new(models.ConfigIDRequest))),
+		},
+
+		// Propose a change to a config as a draft
+		{
+			Path:    "POST /config/drafts",
+			Handler: authorizer.Require(auth.PermConfigWrite, handlers.GenerateHandler(draftService.CreateDraft, new(models.CreateDraftRequest))),
+		},
+
+		// List drafts
+		{
+			Path:    "GET /config/drafts",
+			Handler: authorizer.Require(auth.PermConfigRead, handlers.GenerateHandler(draftService.GetDrafts, new(models.DraftQuery))),
+		},
+
+		// Get draft with its diff against the current config
+		{
+			Path:    "GET /config/draft",
+			Handler: authorizer.Require(auth.PermConfigRead, handlers.GenerateHandler(draftService.GetDraft, new(models.DraftIDRequest))),
+		},
+
+		// Approve draft
+		{
+			Path:    "POST /config/draft/approve",
+			Handler: authorizer.Require(auth.PermConfigReview, handlers.GenerateHandler(draftService.ApproveDraft, new(models.ReviewDraftRequest))),
+		},
+
+		// Reject draft
+		{
+			Path:    "POST /config/draft/reject",
+			Handler: authorizer.Require(auth.PermConfigReview, handlers.GenerateHandler(draftService.RejectDraft, new(models.ReviewDraftRequest))),
+		},
+
+		// Publish approved draft
+		{
+			Path:    "POST /config/draft/publish",
+			Handler: authorizer.Require(auth.PermConfigWrite, handlers.GenerateHandler(draftService.PublishDraft, new(models.DraftIDRequest))),
+		},
+
+		// Withdraw draft
+		{
+			Path:    "DELETE /config/draft",
+			Handler: authorizer.Require(auth.PermConfigWrite, handlers.GenerateHandler(draftService.WithdrawDraft, new(models.DraftIDRequest))),
+		},
+
+		// List scheduled updates
+		{
+			Path:    "GET /config/schedules",
+			Handler: authorizer.Require(auth.PermConfigRead, handlers.GenerateHandler(configService.GetScheduledChanges, new(models.ScheduleQuery))),
+		},
+
+		// Cancel a pending scheduled update
+		{
+			Path:    "DELETE /config/schedule",
+			Handler: authorizer.Require(auth.PermConfigWrite, handlers.GenerateHandler(configService.CancelScheduledChange, new(models.ScheduleIDRequest))),
 		},
 
 		// Get config archives
 		{
 			Path:    "GET /config/archives",
-			Handler: handlers.GenerateHandler(configService.GetConfigArchives, new(models.ConfigIDRequest)),
+			Handler: authorizer.Require(auth.PermConfigRead, handlers.GenerateHandler(configService.GetConfigArchives, new(models.ConfigIDRequest))),
+		},
+
+		// Restore config from archive
+		{
+			Path:    "POST /config/restore",
+			Handler: authorizer.Require(auth.PermConfigWrite, handlers.GenerateHandler(configService.RestoreConfig, new(models.RestoreConfigRequest))),
 		},
 
 		// Type APIs
 		// Get all types
 		{
 			Path:    "GET /types",
-			Handler: handlers.GenerateHandler[types.EmptyRequest](configTypeService.GetAllTypes, new(types.EmptyRequest)),
+			Handler: authorizer.Require(auth.PermTypeRead, handlers.GenerateHandler(typeDefinitionService.GetAllTypes, new(types.EmptyRequest))),
 		},
 
 		// Get specific type
 		{
 			Path:    "GET /types/{type}",
-			Handler: handlers.GenerateHandler(configTypeService.GetType, new(types.TypeRequest)),
+			Handler: authorizer.Require(auth.PermTypeRead, handlers.GenerateHandler(typeDefinitionService.GetType, new(models.TypeNameRequest))),
 		},
 
 		// Get subtypes for a type
 		{
 			Path:    "GET /types/{type}/subtypes",
-			Handler: handlers.GenerateHandler(configTypeService.GetSubtypes, new(types.TypeRequest)),
+			Handler: authorizer.Require(auth.PermTypeRead, handlers.GenerateHandler(typeDefinitionService.GetSubtypes, new(models.TypeNameRequest))),
 		},
 
 		// Get specific subtype
 		{
 			Path:    "GET /types/{type}/subtypes/{subtype}",
-			Handler: handlers.GenerateHandler(configTypeService.GetSubtype, new(types.SubtypeRequest)),
+			Handler: authorizer.Require(auth.PermTypeRead, handlers.GenerateHandler(typeDefinitionService.GetSubtype, new(models.SubtypeNameRequest))),
+		},
+
+		// Create tenant type
+		{
+			Path:    "POST /types",
+			Handler: authorizer.Require(auth.PermTypeManage, handlers.GenerateHandler(typeDefinitionService.CreateType, new(models.CreateTypeRequest))),
+		},
+
+		// Update tenant type
+		{
+			Path:    "PUT /types/{type}",
+			Handler: authorizer.Require(auth.PermTypeManage, handlers.GenerateHandler(typeDefinitionService.UpdateType, new(models.UpdateTypeRequest))),
+		},
+
+		// Delete tenant type
+		{
+			Path:    "DELETE /types/{type}",
+			Handler: authorizer.Require(auth.PermTypeManage, handlers.GenerateHandler(typeDefinitionService.DeleteType, new(models.TypeNameRequest))),
+		},
+
+		// Create tenant subtype
+		{
+			Path:    "POST /types/{type}/subtypes",
+			Handler: authorizer.Require(auth.PermTypeManage, handlers.GenerateHandler(typeDefinitionService.CreateSubtype, new(models.CreateSubtypeRequest))),
+		},
+
+		// Update tenant subtype
+		{
+			Path:    "PUT /types/{type}/subtypes/{subtype}",
+			Handler: authorizer.Require(auth.PermTypeManage, handlers.GenerateHandler(typeDefinitionService.UpdateSubtype, new(models.UpdateSubtypeRequest))),
+		},
+
+		// Delete tenant subtype
+		{
+			Path:    "DELETE /types/{type}/subtypes/{subtype}",
+			Handler: authorizer.Require(auth.PermTypeManage, handlers.GenerateHandler(typeDefinitionService.DeleteSubtype, new(models.SubtypeNameRequest))),
+		},
+
+		// Export subtype schema as JSON Schema
+		{
+			Path:    "GET /types/{type}/subtypes/{subtype}/json-schema",
+			Handler: authorizer.Require(auth.PermTypeRead, rawHandler(typeDefinitionService.GetSubtypeJSONSchema)),
+		},
+
+		// Create tenant subtype from JSON Schema
+		{
+			Path:    "POST /types/{type}/subtypes/{subtype}/json-schema",
+			Handler: authorizer.Require(auth.PermTypeManage, handlers.GenerateHandler(typeDefinitionService.ImportSubtypeJSONSchema, new(models.ImportJSONSchemaRequest))),
+		},
+
+		// Schema compatibility report for stored configs
+		{
+			Path:    "GET /types/{type}/subtypes/{subtype}/compatibility",
+			Handler: authorizer.Require(auth.PermConfigRead, handlers.GenerateHandler(migrationService.GetCompatibility, new(models.SubtypeNameRequest))),
+		},
+
+		// Run metadata migration
+		{
+			Path:    "POST /types/{type}/subtypes/{subtype}/migrations",
+			Handler: authorizer.Require(auth.PermTypeManage, handlers.GenerateHandler(migrationService.RunMigration, new(models.RunMigrationRequest))),
+		},
+
+		// Get migration runs
+		{
+			Path:    "GET /types/{type}/subtypes/{subtype}/migrations",
+			Handler: authorizer.Require(auth.PermTypeRead, handlers.GenerateHandler(migrationService.GetMigrations, new(models.SubtypeNameRequest))),
 		},
 
 		// Validate metadata
 		{
 			Path:    "POST /validate-metadata",
-			Handler: handlers.GenerateHandler(configTypeService.ValidateMetadata, new(types.ValidationRequest)),
+			Handler: authorizer.Require(auth.PermTypeRead, handlers.GenerateHandler(typeDefinitionService.ValidateMetadata, new(models.ValidateMetadataRequest))),
 		},
 
 		// Decrypt config field
 		{
 			Path:    "POST /config/decrypt",
-			Handler: handlers.GenerateHandler(configService.DecryptConfigField, new(models.DecryptFieldRequest)),
+			Handler: authorizer.Require(auth.PermSecretRead, handlers.GenerateHandler(configService.DecryptConfigField, new(models.DecryptFieldRequest))),
+		},
+
+		// RBAC APIs
+		// Create role binding
+		{
+			Path:    "POST /rbac/bindings",
+			Handler: authorizer.Require(auth.PermRBACManage, handlers.GenerateHandler(roleBindingService.CreateRoleBinding, new(models.CreateRoleBindingRequest))),
+		},
+
+		// Get role bindings
+		{
+			Path:    "GET /rbac/bindings",
+			Handler: authorizer.Require(auth.PermRBACManage, handlers.GenerateHandler(roleBindingService.GetRoleBindings, new(models.RoleBindingQuery))),
+		},
+
+		// Delete role binding
+		{
+			Path:    "DELETE /rbac/bindings",
+			Handler: authorizer.Require(auth.PermRBACManage, handlers.GenerateHandler(roleBindingService.DeleteRoleBinding, new(models.RoleBindingIDRequest))),
+		},
+
+		// Feature flag APIs
+		// Evaluate a feature flag for an evaluation context
+		{
+			Path:    "POST /flags/evaluate",
+			Handler: authorizer.Require(auth.PermConfigRead, handlers.GenerateHandler(flagService.EvaluateFlag, new(models.EvaluateFlagRequest))),
+		},
+
+		// OpenFeature Remote Evaluation Protocol: evaluate a single flag
+		{
+			Path:    "POST /ofrep/v1/evaluate/flags/{key}",
+			Handler: authorizer.Require(auth.PermConfigRead, rawHandler(flagService.OFREPEvaluateFlag)),
+		},
+
+		// OpenFeature Remote Evaluation Protocol: evaluate all readable flags
+		{
+			Path:    "POST /ofrep/v1/evaluate/flags",
+			Handler: authorizer.Require(auth.PermConfigRead, rawHandler(flagService.OFREPEvaluateFlags)),
+		},
+
+		// Consul KV compatible APIs
+		// Read a key, or every key under a prefix with ?recurse or ?keys
+		{
+			Path:    "GET /v1/kv/{key...}",
+			Handler: authorizer.Require(auth.PermConfigRead, longPoll("index", configServices.ConsulMaxWait, rawHandler(consulService.GetKV))),
+		},
+
+		// Spring Cloud Config compatible APIs, mounted under /spring because
+		// /{application}/{profile}/{label} would overlap the other routes
+		// Get the Environment of an application and profiles
+		{
+			Path:    "GET /spring/{application}/{profile}",
+			Handler: authorizer.Require(auth.PermConfigRead, rawHandler(springService.GetEnvironment)),
+		},
+
+		// Get the Environment of an application and profiles at a label
+		{
+			Path:    "GET /spring/{application}/{profile}/{label}",
+			Handler: authorizer.Require(auth.PermConfigRead, rawHandler(springService.GetEnvironment)),
+		},
+
+		// Vault KV v2 compatible APIs
+		// Read the decrypted encrypted fields of a config
+		{
+			Path:    "GET /v1/secret/data/{path...}",
+			Handler: authorizer.Require(auth.PermSecretRead, rawHandler(vaultService.ReadSecret)),
+		},
+
+		// Read the versions of a secret
+		{
+			Path:    "GET /v1/secret/metadata/{path...}",
+			Handler: authorizer.Require(auth.PermSecretRead, rawHandler(vaultService.ReadSecretMetadata)),
+		},
+
+		// Describe the secret mount, which Vault clients look up to detect KV v2
+		{
+			Path:    "GET /v1/sys/internal/ui/mounts/{path...}",
+			Handler: authorizer.Require(auth.PermSecretRead, rawHandler(vaultService.MountInfo)),
+		},
+
+		// GraphQL API over configs, archives, references and the type registry
+		{
+			Path:    "POST /graphql",
+			Handler: authorizer.Require(auth.PermConfigRead, graphqlapi.NewHandler(configService, typeDefinitionService)),
+		},
+
+		// API key APIs
+		// Create API key
+		{
+			Path:    "POST /apikeys",
+			Handler: authorizer.Require(auth.PermAPIKeyManage, handlers.GenerateHandler(apiKeyService.CreateAPIKey, new(models.CreateAPIKeyRequest))),
+		},
+
+		// Get API keys
+		{
+			Path:    "GET /apikeys",
+			Handler: authorizer.Require(auth.PermAPIKeyManage, handlers.GenerateHandler(apiKeyService.GetAPIKeys, new(models.APIKeyQuery))),
+		},
+
+		// Revoke API key
+		{
+			Path:    "DELETE /apikeys",
+			Handler: authorizer.Require(auth.PermAPIKeyManage, handlers.GenerateHandler(apiKeyService.RevokeAPIKey, new(models.APIKeyIDRequest))),
 		},
 	}
 
@@ -115,5 +429,8 @@ func RegisterConfigRoutes() http.Handler {
 	// No other changes are needed here.
 	handlers.RegisterRoutes(mux, apis)
 
-	return mux
+	// The gRPC API shares the service instances and authorizer with the routes
+	grpcServer := grpcapi.NewServer(configService, authorizer)
+
+	return mux, grpcServer
 }
@@ -0,0 +1,402 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"makatom-api-config/internal/auth"
+	"makatom-api-config/internal/models"
+	"makatom/common/pkg/database/mongodb"
+	"makatom/common/pkg/handlers"
+	"makatom/common/pkg/types"
+)
+
+const (
+	// apiKeyPrefix marks keys issued by this service
+	apiKeyPrefix = "mk_"
+	// apiKeySubjectPrefix marks the subjects of API key principals
+	apiKeySubjectPrefix = "apikey:"
+	// lastUsedResolution throttles last-used writes for busy keys
+	lastUsedResolution = time.Minute
+)
+
+var (
+	ErrInvalidAPIKey = errors.New("invalid API key")
+	ErrAPIKeyExpired = errors.New("API key expired")
+	ErrAPIKeyRevoked = errors.New("API key revoked")
+	// ErrAPIKeyCreatorAccess is returned when the key's creator lost all the access it grants
+	ErrAPIKeyCreatorAccess = errors.New("API key creator no longer holds its access")
+)
+
+// APIKeyService handles business logic for service-account API keys. A key never
+// grants more than its creator's stored role bindings currently do.
+type APIKeyService struct {
+	repo     *mongodb.MongoRepository[models.APIKey]
+	bindings auth.BindingStore
+}
+
+// NewAPIKeyService creates a new APIKeyService instance
+func NewAPIKeyService(apiKeyCollection *mongo.Collection, bindings auth.BindingStore) *APIKeyService {
+	return &APIKeyService{
+		repo:     mongodb.NewMongoRepository[models.APIKey](apiKeyCollection),
+		bindings: bindings,
+	}
+}
+
+// AuthenticateKey resolves a plaintext API key to a principal, implementing auth.KeyStore
+func (s *APIKeyService) AuthenticateKey(ctx context.Context, key string) (*auth.Principal, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	apiKey, err := s.repo.FindOne(ctx, bson.M{"key_hash": hashAPIKey(key)})
+	if err != nil {
+		if err.Error() == "not found" {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if apiKey.Revoked {
+		return nil, ErrAPIKeyRevoked
+	}
+	if apiKey.ExpiresAt != nil && now.After(*apiKey.ExpiresAt) {
+		return nil, ErrAPIKeyExpired
+	}
+
+	bindings, err := s.currentBindings(ctx, apiKey)
+	if err != nil {
+		return nil, err
+	}
+
+	// Record usage, at most once per lastUsedResolution; usage is informational only
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= lastUsedResolution {
+		if _, err := s.repo.UpdateByID(ctx, apiKey.ID, bson.M{"$set": bson.M{"last_used_at": now}}); err != nil {
+			log.Printf("apikeys: failed to record usage of key %s: %v", apiKey.ID.Hex(), err)
+		}
+	}
+
+	return &auth.Principal{
+		Subject:        apiKeySubjectPrefix + apiKey.ID.Hex(),
+		TenantID:       apiKey.TenantID,
+		Bindings:       bindings,
+		ServiceAccount: true,
+	}, nil
+}
+
+// KeyBindings returns the current bindings of the API key with the given ID,
+// failing when the key no longer exists or is revoked or expired
+func (s *APIKeyService) KeyBindings(ctx context.Context, tenantID, id string) ([]auth.Binding, error) {
+	keyID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	apiKey, err := s.repo.FindOne(ctx, bson.M{"_id": keyID, "tenant_id": tenantID})
+	if err != nil {
+		if err.Error() == "not found" {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+
+	if apiKey.Revoked {
+		return nil, ErrAPIKeyRevoked
+	}
+	if apiKey.ExpiresAt != nil && time.Now().After(*apiKey.ExpiresAt) {
+		return nil, ErrAPIKeyExpired
+	}
+	return s.currentBindings(ctx, apiKey)
+}
+
+// currentBindings returns the bindings of the key that its creator can still
+// grant with their current role bindings. Creators that lost access narrow
+// their keys; a key left with nothing is refused.
+func (s *APIKeyService) currentBindings(ctx context.Context, apiKey models.APIKey) ([]auth.Binding, error) {
+	creator, err := s.creatorPrincipal(ctx, apiKey.TenantID, apiKey.CreatedBy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the key creator's role bindings: %v", err)
+	}
+
+	var bindings []auth.Binding
+	for _, binding := range apiKeyBindings(apiKey) {
+		if creator.CanGrant(binding) {
+			bindings = append(bindings, binding)
+		}
+	}
+	if len(bindings) == 0 {
+		return nil, ErrAPIKeyCreatorAccess
+	}
+	return bindings, nil
+}
+
+// creatorPrincipal returns the stored access of a key's creator. Roles carried by
+// the creator's token cannot be re-checked when the key is used and do not count.
+func (s *APIKeyService) creatorPrincipal(ctx context.Context, tenantID, subject string) (*auth.Principal, error) {
+	bindings, err := s.bindings.LoadBindings(ctx, tenantID, subject)
+	if err != nil {
+		return nil, err
+	}
+	return &auth.Principal{Subject: subject, TenantID: tenantID, Bindings: bindings}, nil
+}
+
+// CreateAPIKey issues a new API key for the caller's tenant
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, req models.CreateAPIKeyRequest) handlers.ServiceResponse {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+
+	if req.Scope != models.APIKeyScopeReadOnly && req.Scope != models.APIKeyScopeReadWrite {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      fmt.Sprintf("scope must be %q or %q", models.APIKeyScopeReadOnly, models.APIKeyScopeReadWrite),
+		}
+	}
+
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      "expires_at must be in the future",
+		}
+	}
+
+	for _, allowed := range req.AllowedTypes {
+		if allowed.Type == "" {
+			return handlers.ServiceResponse{
+				StatusCode: http.StatusBadRequest,
+				Error:      "allowed_types entries require a type",
+			}
+		}
+	}
+
+	key, prefix, err := generateAPIKey()
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to generate API key: %v", err),
+		}
+	}
+
+	apiKey := models.APIKey{
+		Base:         &types.Base{},
+		TenantID:     principal.TenantID,
+		Name:         req.Name,
+		Prefix:       prefix,
+		KeyHash:      hashAPIKey(key),
+		Scope:        req.Scope,
+		AllowedTypes: req.AllowedTypes,
+		AllowSecrets: req.AllowSecrets,
+		ExpiresAt:    req.ExpiresAt,
+		CreatedBy:    principal.Subject,
+	}
+
+	// Keys are limited to the caller's own access and apikey:manage scope
+	if !canManageKey(principal, apiKey) {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusForbidden,
+			Error:      "permission denied: the key would exceed the caller's access",
+		}
+	}
+
+	// Keys are re-checked against the creator's stored access whenever they are used
+	creator, err := s.creatorPrincipal(ctx, principal.TenantID, principal.Subject)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to load role bindings: %v", err),
+		}
+	}
+	for _, binding := range apiKeyBindings(apiKey) {
+		if !creator.CanGrant(binding) {
+			return handlers.ServiceResponse{
+				StatusCode: http.StatusForbidden,
+				Error:      "permission denied: API keys need the access they grant from role bindings, not only from the token",
+			}
+		}
+	}
+
+	created, err := s.repo.InsertOne(ctx, apiKey)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to create API key: %v", err),
+		}
+	}
+
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusCreated,
+		Data: models.CreatedAPIKeyResponse{
+			APIKeyResponse: created.ToResponse(),
+			Key:            key,
+		},
+	}
+}
+
+// GetAPIKeys lists the API keys of the caller's tenant
+func (s *APIKeyService) GetAPIKeys(ctx context.Context, query models.APIKeyQuery) handlers.ServiceResponse {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+
+	filter := bson.M{"tenant_id": principal.TenantID}
+	if !query.IncludeRevoked {
+		filter["revoked"] = false
+	}
+
+	keys, err := s.repo.Find(ctx, filter, 0, 0)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to get API keys: %v", err),
+		}
+	}
+
+	responses := make([]models.APIKeyResponse, len(keys))
+	for i, key := range keys {
+		responses[i] = key.ToResponse()
+	}
+
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusOK,
+		Data: map[string]interface{}{
+			"api_keys": responses,
+			"total":    len(responses),
+		},
+	}
+}
+
+// RevokeAPIKey revokes an API key; revoked keys are kept for auditing
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, req models.APIKeyIDRequest) handlers.ServiceResponse {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+
+	id, err := primitive.ObjectIDFromHex(req.ID)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      "Invalid API key ID",
+		}
+	}
+
+	existing, err := s.repo.FindOne(ctx, bson.M{"_id": id, "tenant_id": principal.TenantID})
+	if err != nil {
+		if err.Error() == "not found" {
+			return handlers.ServiceResponse{
+				StatusCode: http.StatusNotFound,
+				Error:      "API key not found",
+			}
+		}
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to get API key: %v", err),
+		}
+	}
+
+	for _, binding := range apiKeyBindings(existing) {
+		if !principal.CanScope(auth.PermAPIKeyManage, binding.Scope) {
+			return forbiddenResponse(auth.PermAPIKeyManage)
+		}
+	}
+
+	if existing.Revoked {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusOK,
+			Data:       existing.ToResponse(),
+		}
+	}
+
+	revoked, err := s.repo.UpdateByID(ctx, id, bson.M{"$set": bson.M{
+		"revoked":    true,
+		"revoked_at": time.Now(),
+	}})
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to revoke API key: %v", err),
+		}
+	}
+
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusOK,
+		Data:       revoked.ToResponse(),
+	}
+}
+
+// canManageKey reports whether the principal may issue the key: it must hold
+// apikey:manage and every permission the key grants over the key's allow-list
+func canManageKey(principal *auth.Principal, key models.APIKey) bool {
+	for _, binding := range apiKeyBindings(key) {
+		if !principal.CanScope(auth.PermAPIKeyManage, binding.Scope) || !principal.CanGrant(binding) {
+			return false
+		}
+	}
+	return true
+}
+
+// apiKeyBindings translates the key scope and type allow-list into role bindings.
+// Secrets are only readable within the allow-list when the key allows them.
+func apiKeyBindings(key models.APIKey) []auth.Binding {
+	roles := []auth.Role{auth.RoleViewer}
+	if key.AllowSecrets {
+		roles = append(roles, auth.RoleSecretReader)
+	}
+	if key.Scope == models.APIKeyScopeReadWrite {
+		roles = append(roles, auth.RoleEditor)
+	}
+
+	scopes := []auth.Scope{{}}
+	if len(key.AllowedTypes) > 0 {
+		scopes = make([]auth.Scope, len(key.AllowedTypes))
+		for i, allowed := range key.AllowedTypes {
+			scopes[i] = auth.Scope{Type: allowed.Type, Subtype: allowed.Subtype}
+		}
+	}
+
+	var bindings []auth.Binding
+	for _, role := range roles {
+		for _, scope := range scopes {
+			bindings = append(bindings, auth.Binding{Role: role, Scope: scope})
+		}
+	}
+	return bindings
+}
+
+// generateAPIKey returns a new plaintext key and its public prefix
+func generateAPIKey() (string, string, error) {
+	prefixBytes := make([]byte, 4)
+	secretBytes := make([]byte, 32)
+	if _, err := rand.Read(prefixBytes); err != nil {
+		return "", "", err
+	}
+	if _, err := rand.Read(secretBytes); err != nil {
+		return "", "", err
+	}
+
+	prefix := apiKeyPrefix + hex.EncodeToString(prefixBytes)
+	return prefix + "_" + base64.RawURLEncoding.EncodeToString(secretBytes), prefix, nil
+}
+
+// hashAPIKey returns the hex encoded SHA-256 of the key. Keys carry 256 bits of
+// entropy, so a fast hash is sufficient.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"makatom-api-config/internal/auth"
+	"makatom-api-config/internal/models"
+	"makatom-api-config/internal/references"
+	"makatom/common/pkg/handlers"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Batched lookups let callers resolving many configs at once, such as the
+// GraphQL API, load related data with one query instead of one per config.
+
+// ArchivesByConfig returns the archives of several configs, newest first and
+// keyed by config ID. As with GetConfigArchives, the caller must be able to
+// read a config to see its archives; other configs get none.
+func (s *ConfigService) ArchivesByConfig(ctx context.Context, ids []primitive.ObjectID) (map[primitive.ObjectID][]models.ConfigArchiveResponse, *handlers.ServiceResponse) {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		resp := unauthorizedResponse()
+		return nil, &resp
+	}
+
+	filter, allowed := configFilter(principal, models.ConfigQuery{})
+	if !allowed {
+		resp := forbiddenResponse(auth.PermConfigRead)
+		return nil, &resp
+	}
+	filter["_id"] = bson.M{"$in": ids}
+	readable, err := s.repo.Find(ctx, filter, 0, 0)
+	if err != nil {
+		return nil, &handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to get configs: %v", err),
+		}
+	}
+
+	result := make(map[primitive.ObjectID][]models.ConfigArchiveResponse, len(readable))
+	readableIDs := make([]primitive.ObjectID, len(readable))
+	for i, config := range readable {
+		readableIDs[i] = config.ID
+		result[config.ID] = []models.ConfigArchiveResponse{}
+	}
+	if len(readableIDs) == 0 {
+		return result, nil
+	}
+
+	archives, err := s.archiveRepo.Find(ctx, bson.M{
+		"config_id": bson.M{"$in": readableIDs},
+		"tenant_id": principal.TenantID,
+	}, 0, 0)
+	if err != nil {
+		return nil, &handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to get config archives: %v", err),
+		}
+	}
+	sort.Slice(archives, func(i, j int) bool {
+		return archives[i].Version > archives[j].Version
+	})
+	for _, archive := range archives {
+		result[archive.ConfigID] = append(result[archive.ConfigID], archive.ToArchiveResponse())
+	}
+	return result, nil
+}
+
+// ConfigsByKey returns the configs with the given keys that the caller may
+// read, decrypted for them. Keys without a readable config are left out.
+func (s *ConfigService) ConfigsByKey(ctx context.Context, keys []models.ConfigKey) (map[models.ConfigKey]models.ConfigResponse, *handlers.ServiceResponse) {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		resp := unauthorizedResponse()
+		return nil, &resp
+	}
+
+	result := make(map[models.ConfigKey]models.ConfigResponse, len(keys))
+	clauses := make([]bson.M, 0, len(keys))
+	for _, key := range keys {
+		parts := strings.SplitN(key.Key, "/", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		clauses = append(clauses, bson.M{
+			"namespace": namespaceFilter(key.Namespace),
+			"type":      parts[0],
+			"subtype":   parts[1],
+			"name":      parts[2],
+		})
+	}
+	if len(clauses) == 0 {
+		return result, nil
+	}
+
+	filter, allowed := configFilter(principal, models.ConfigQuery{})
+	if !allowed {
+		return result, nil
+	}
+	filter["$and"] = []bson.M{{"$or": clauses}}
+	configs, err := s.repo.Find(ctx, filter, 0, 0)
+	if err != nil {
+		return nil, &handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to get configs: %v", err),
+		}
+	}
+
+	for _, config := range configs {
+		config, err = s.decryptForReader(ctx, principal, config)
+		if err != nil {
+			return nil, &handlers.ServiceResponse{
+				StatusCode: http.StatusInternalServerError,
+				Error:      fmt.Sprintf("config %s: %v", config.ID.Hex(), err),
+			}
+		}
+		key := models.ConfigKey{
+			Namespace: config.Namespace,
+			Key:       references.Key(config.Type, config.Subtype, config.Name),
+		}
+		result[key] = config.ToResponse()
+	}
+	return result, nil
+}
@@ -0,0 +1,291 @@
+package services
+
+import (
+	"container/list"
+	"context"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"makatom-api-config/internal/auth"
+	"makatom-api-config/internal/models"
+	"makatom-api-config/internal/references"
+	"makatom/common/pkg/handlers"
+	"makatom/common/pkg/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// cacheRetryInterval is how long the cache stays off after its change stream fails
+const cacheRetryInterval = 5 * time.Second
+
+// configCache is an LRU cache of stored configs and their decrypted form for
+// reads by ID or natural key. It only serves while a change stream of the
+// configs collection invalidates the entries that other replicas change, and
+// is emptied whenever that stream stops. Cached configs are shared between
+// readers and must not be modified.
+type configCache struct {
+	mu       sync.Mutex
+	capacity int
+	enabled  bool
+	order    *list.List
+	entries  map[primitive.ObjectID]*list.Element
+	keys     map[configCacheKey]primitive.ObjectID
+	// generation moves on every invalidation, so reads that started before
+	// one do not store what they read
+	generation uint64
+	stats      models.CacheStats
+}
+
+// configCacheKey is the natural key of a config within its tenant and namespace
+type configCacheKey struct {
+	tenantID  string
+	namespace string
+	key       string
+}
+
+// configCacheEntry is a cached config. decrypted is filled by the first reader
+// allowed to see the secrets of the config.
+type configCacheEntry struct {
+	config    models.Config
+	key       configCacheKey
+	mu        sync.Mutex
+	decrypted *models.Config
+}
+
+func newConfigCache() *configCache {
+	return &configCache{
+		order:   list.New(),
+		entries: make(map[primitive.ObjectID]*list.Element),
+		keys:    make(map[configCacheKey]primitive.ObjectID),
+	}
+}
+
+func cacheKeyOf(config models.Config) configCacheKey {
+	return configCacheKey{
+		tenantID:  config.TenantID,
+		namespace: config.Namespace,
+		key:       references.Key(config.Type, config.Subtype, config.Name),
+	}
+}
+
+// get returns the entry of a config of the tenant; the generation is to be passed
+// to put when the config has to be read from the database instead
+func (c *configCache) get(tenantID string, id primitive.ObjectID) (*configCacheEntry, uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lookup(tenantID, id), c.generation
+}
+
+// getByKey returns the entry of a config by natural key, like get
+func (c *configCache) getByKey(key configCacheKey) (*configCacheEntry, uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lookup(key.tenantID, c.keys[key]), c.generation
+}
+
+func (c *configCache) lookup(tenantID string, id primitive.ObjectID) *configCacheEntry {
+	if element, found := c.entries[id]; found && c.enabled {
+		entry := element.Value.(*configCacheEntry)
+		if entry.config.TenantID == tenantID {
+			c.order.MoveToFront(element)
+			c.stats.Hits++
+			return entry
+		}
+	}
+	c.stats.Misses++
+	return nil
+}
+
+// put caches a config read from the database, unless the cache is off or a
+// config was invalidated since the read began
+func (c *configCache) put(config models.Config, generation uint64) *configCacheEntry {
+	entry := &configCacheEntry{config: config, key: cacheKeyOf(config)}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.enabled || c.capacity <= 0 || generation != c.generation {
+		return entry
+	}
+	if element, found := c.entries[config.ID]; found {
+		c.remove(element)
+	}
+	c.entries[config.ID] = c.order.PushFront(entry)
+	c.keys[entry.key] = config.ID
+
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+		c.stats.Evictions++
+	}
+	return entry
+}
+
+// invalidate drops a config from the cache
+func (c *configCache) invalidate(id primitive.ObjectID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	if element, found := c.entries[id]; found {
+		c.remove(element)
+		c.stats.Invalidations++
+	}
+}
+
+// setEnabled turns the cache on or off; turning it off empties it. Reads that
+// started before either may have missed a change and are not stored.
+func (c *configCache) setEnabled(enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.enabled = enabled
+	c.generation++
+	if !enabled {
+		c.order.Init()
+		clear(c.entries)
+		clear(c.keys)
+	}
+}
+
+func (c *configCache) remove(element *list.Element) {
+	entry := c.order.Remove(element).(*configCacheEntry)
+	delete(c.entries, entry.config.ID)
+	if c.keys[entry.key] == entry.config.ID {
+		delete(c.keys, entry.key)
+	}
+}
+
+// snapshot returns the current statistics
+func (c *configCache) snapshot() models.CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Enabled = c.enabled
+	stats.Capacity = c.capacity
+	stats.Size = c.order.Len()
+	if lookups := stats.Hits + stats.Misses; lookups > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(lookups)
+	}
+	return stats
+}
+
+// loadConfig returns the config with the ID if it belongs to the tenant, from
+// the cache when possible. A config of another tenant is returned uncached, for
+// the caller to reject.
+func (s *ConfigService) loadConfig(ctx context.Context, tenantID string, id primitive.ObjectID) (*configCacheEntry, error) {
+	entry, generation := s.cache.get(tenantID, id)
+	if entry != nil {
+		return entry, nil
+	}
+
+	config, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if config.TenantID != tenantID {
+		return &configCacheEntry{config: config}, nil
+	}
+	return s.cache.put(config, generation), nil
+}
+
+// loadConfigByKey returns the config with a natural key in a namespace from the
+// cache when possible, or nil when there is none
+func (s *ConfigService) loadConfigByKey(ctx context.Context, tenantID, namespace, key string) (*configCacheEntry, error) {
+	cacheKey := configCacheKey{tenantID: tenantID, namespace: namespace, key: key}
+	entry, generation := s.cache.getByKey(cacheKey)
+	if entry != nil {
+		return entry, nil
+	}
+
+	config, err := s.findByKey(ctx, tenantID, namespace, key)
+	if err != nil || config == nil {
+		return nil, err
+	}
+	return s.cache.put(*config, generation), nil
+}
+
+// decryptEntry returns the config of an entry decrypted as far as the principal
+// may see it, decrypting at most once per cached config
+func (s *ConfigService) decryptEntry(ctx context.Context, principal *auth.Principal, entry *configCacheEntry) (models.Config, error) {
+	if entry.config.Metadata == nil || !principal.Can(auth.PermSecretRead, entry.config.Resource()) {
+		return entry.config, nil
+	}
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+	if entry.decrypted == nil {
+		decrypted, err := s.decryptForReader(ctx, principal, entry.config)
+		if err != nil {
+			return entry.config, err
+		}
+		entry.decrypted = &decrypted
+	}
+	return *entry.decrypted, nil
+}
+
+// GetCacheStats returns the hit, miss, eviction and invalidation counts of the
+// config cache of this replica. The cache is shared by all tenants, so ops:read
+// must be held tenant-wide.
+func (s *ConfigService) GetCacheStats(ctx context.Context, req types.EmptyRequest) handlers.ServiceResponse {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+	if !principal.CanScope(auth.PermOpsRead, auth.Scope{}) {
+		return forbiddenResponse(auth.PermOpsRead)
+	}
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusOK,
+		Data:       s.cache.snapshot(),
+	}
+}
+
+// RunCache enables the config cache with room for capacity configs and keeps
+// it invalidated through a change stream until ctx is done. The cache is off
+// while the stream is down, e.g. on a standalone MongoDB without change streams.
+func (s *ConfigService) RunCache(ctx context.Context, capacity int) {
+	s.cache.mu.Lock()
+	s.cache.capacity = capacity
+	s.cache.mu.Unlock()
+	if capacity <= 0 {
+		return
+	}
+
+	for {
+		err := s.watchInvalidations(ctx)
+		s.cache.setEnabled(false)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("config cache: change stream stopped, cache off for %s: %v", cacheRetryInterval, err)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(cacheRetryInterval):
+		}
+	}
+}
+
+// watchInvalidations serves the cache while a change stream invalidates the
+// configs changed through any replica
+func (s *ConfigService) watchInvalidations(ctx context.Context) error {
+	pipeline := mongo.Pipeline{{{Key: "$project", Value: bson.M{"documentKey": 1, "operationType": 1}}}}
+	stream, err := s.configs.Watch(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+	defer stream.Close(context.Background())
+
+	// Changes from now on are seen, and the cache was emptied when it went off
+	s.cache.setEnabled(true)
+	for stream.Next(ctx) {
+		var change configChange
+		if err := stream.Decode(&change); err != nil {
+			return err
+		}
+		s.cache.invalidate(change.DocumentKey.ID)
+	}
+	return stream.Err()
+}
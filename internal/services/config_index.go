@@ -0,0 +1,119 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// changeIndexPollInterval is how often a blocked read re-reads the change index
+// to see changes made through other replicas
+const changeIndexPollInterval = time.Second
+
+// changeIndexDocument holds the change index of a tenant
+type changeIndexDocument struct {
+	TenantID  string    `bson:"_id"`
+	Index     int64     `bson:"index"`
+	ChangedAt time.Time `bson:"changed_at"`
+}
+
+// changeNotifier wakes the blocked reads of this replica when the change
+// index of their tenant moves
+type changeNotifier struct {
+	mu      sync.Mutex
+	waiters map[string]chan struct{}
+}
+
+func newChangeNotifier() *changeNotifier {
+	return &changeNotifier{waiters: make(map[string]chan struct{})}
+}
+
+// wait returns a channel closed on the next change of the tenant
+func (n *changeNotifier) wait(tenantID string) <-chan struct{} {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	ch, found := n.waiters[tenantID]
+	if !found {
+		ch = make(chan struct{})
+		n.waiters[tenantID] = ch
+	}
+	return ch
+}
+
+// notify wakes every read waiting on the tenant
+func (n *changeNotifier) notify(tenantID string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if ch, found := n.waiters[tenantID]; found {
+		close(ch)
+		delete(n.waiters, tenantID)
+	}
+}
+
+// ChangeIndex returns the change index of a tenant. It increases on every
+// change to one of its configs and is 0 before the first one.
+func (s *ConfigService) ChangeIndex(ctx context.Context, tenantID string) (int64, error) {
+	document, err := s.changeState(ctx, tenantID)
+	return document.Index, err
+}
+
+// changeState returns the change index of a tenant with the time of its last
+// change, which is zero before the first one
+func (s *ConfigService) changeState(ctx context.Context, tenantID string) (changeIndexDocument, error) {
+	var document changeIndexDocument
+	err := s.indexes.FindOne(ctx, bson.M{"_id": tenantID}).Decode(&document)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return changeIndexDocument{TenantID: tenantID}, nil
+	}
+	return document, err
+}
+
+// WaitForChange blocks until the change index of a tenant exceeds after, wait
+// elapses or ctx is done, and returns the index then current
+func (s *ConfigService) WaitForChange(ctx context.Context, tenantID string, after int64, wait time.Duration) (int64, error) {
+	timeout := time.NewTimer(wait)
+	defer timeout.Stop()
+	ticker := time.NewTicker(changeIndexPollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Register before reading so a change in between still wakes us
+		changed := s.notifier.wait(tenantID)
+		index, err := s.ChangeIndex(ctx, tenantID)
+		if err != nil || index > after {
+			return index, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return index, nil
+		case <-timeout.C:
+			return index, nil
+		case <-changed:
+		case <-ticker.C:
+		}
+	}
+}
+
+// recordChange bumps the change index of a tenant after a committed change of
+// a config and drops the config from the cache of this replica. The change
+// itself stands when the bump fails; readers then see it with the next one.
+func (s *ConfigService) recordChange(ctx context.Context, tenantID string, configID primitive.ObjectID) {
+	s.cache.invalidate(configID)
+	_, err := s.indexes.UpdateOne(ctx,
+		bson.M{"_id": tenantID},
+		bson.M{"$inc": bson.M{"index": 1}, "$set": bson.M{"changed_at": time.Now()}},
+		options.Update().SetUpsert(true),
+	)
+	if err != nil {
+		log.Printf("failed to bump change index of tenant %s: %v", tenantID, err)
+	}
+	s.notifier.notify(tenantID)
+}
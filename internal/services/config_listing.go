@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"makatom-api-config/internal/auth"
+	"makatom-api-config/internal/models"
+	"makatom-api-config/internal/registry"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// listDecryptWorkers bounds how many configs of a page are decrypted at once
+	listDecryptWorkers = 8
+	// maxListLimit caps the page size of a listing
+	maxListLimit = 1000
+)
+
+// configPage is the single document a listing aggregation returns
+type configPage struct {
+	Configs []models.Config `bson:"configs"`
+	Total   []struct {
+		Count int64 `bson:"count"`
+	} `bson:"total"`
+}
+
+// findPage returns one page of the configs matching filter, oldest first, and
+// the number of matching configs, in a single round trip. The page is returned
+// in one document, so it must stay under the 16MB BSON limit.
+func (s *ConfigService) findPage(ctx context.Context, filter bson.M, skip, limit int64) ([]models.Config, int64, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$sort", Value: bson.M{"_id": 1}}},
+		{{Key: "$facet", Value: bson.M{
+			"configs": bson.A{bson.M{"$skip": skip}, bson.M{"$limit": limit}},
+			"total":   bson.A{bson.M{"$count": "count"}},
+		}}},
+	}
+	cursor, err := s.configs.Aggregate(ctx, pipeline, options.Aggregate().SetAllowDiskUse(true))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer cursor.Close(ctx)
+
+	var pages []configPage
+	if err := cursor.All(ctx, &pages); err != nil {
+		return nil, 0, err
+	}
+	if len(pages) == 0 {
+		return nil, 0, nil
+	}
+	var total int64
+	if len(pages[0].Total) > 0 {
+		total = pages[0].Total[0].Count
+	}
+	return pages[0].Configs, total, nil
+}
+
+// decrypterSource resolves the metadata decrypter of a type/subtype, as
+// *registry.Registry does
+type decrypterSource interface {
+	MetadataDecrypter(ctx context.Context, tenantID, typeName, subtypeName string) (registry.Decrypter, error)
+}
+
+// schemaKey identifies the type/subtype whose decrypter configs of a page share
+type schemaKey struct {
+	typeName string
+	subtype  string
+}
+
+// decryptPage converts a page of configs into responses decrypted for the
+// principal, decrypting up to listDecryptWorkers configs in parallel. Each
+// type/subtype's schema is resolved once per page and a failure to resolve it
+// fails the page; a config that fails to decrypt keeps its stored ciphertext
+// and carries the error.
+func decryptPage(ctx context.Context, source decrypterSource, principal *auth.Principal, configs []models.Config) ([]models.ConfigResponse, error) {
+	decrypters := make(map[schemaKey]registry.Decrypter)
+	decrypts := make([]registry.Decrypter, len(configs))
+	for i, config := range configs {
+		if config.Metadata == nil || !principal.Can(auth.PermSecretRead, config.Resource()) {
+			continue
+		}
+		key := schemaKey{typeName: config.Type, subtype: config.Subtype}
+		decrypt, resolved := decrypters[key]
+		if !resolved {
+			var err error
+			decrypt, err = source.MetadataDecrypter(ctx, config.TenantID, config.Type, config.Subtype)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load schema of %s/%s: %v", config.Type, config.Subtype, err)
+			}
+			decrypters[key] = decrypt
+		}
+		decrypts[i] = decrypt
+	}
+
+	responses := make([]models.ConfigResponse, len(configs))
+	slots := make(chan struct{}, listDecryptWorkers)
+	var wg sync.WaitGroup
+	for i, config := range configs {
+		if decrypts[i] == nil {
+			responses[i] = config.ToResponse()
+			continue
+		}
+		slots <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			decrypted, err := decryptConfig(config, decrypts[i])
+			responses[i] = decrypted.ToResponse()
+			if err != nil {
+				responses[i].DecryptionError = err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+	return responses, nil
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"makatom-api-config/internal/auth"
+	"makatom-api-config/internal/models"
+	"makatom-api-config/internal/registry"
+	"makatom/common/pkg/types"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// benchListingConfigs is the size of the listing the benchmarks page through
+const benchListingConfigs = 10000
+
+// stubDecrypters resolves every type/subtype to a decrypter of the password
+// field, counting the lookups a page makes
+type stubDecrypters struct {
+	cipher  *registry.FieldCipher
+	lookups int
+}
+
+func (s *stubDecrypters) MetadataDecrypter(ctx context.Context, tenantID, typeName, subtypeName string) (registry.Decrypter, error) {
+	s.lookups++
+	return func(metadata map[string]interface{}) (map[string]interface{}, error) {
+		decrypted := make(map[string]interface{}, len(metadata))
+		for key, value := range metadata {
+			if key != "password" || !registry.IsEncrypted(value) {
+				decrypted[key] = value
+				continue
+			}
+			plaintext, err := s.cipher.Decrypt(value.(string), registry.FieldLocation(tenantID, typeName, subtypeName, key))
+			if err != nil {
+				return nil, err
+			}
+			decrypted[key] = plaintext
+		}
+		return decrypted, nil
+	}, nil
+}
+
+// benchConfigs returns n configs of one subtype with an encrypted password
+func benchConfigs(b *testing.B, cipher *registry.FieldCipher, n int) []models.Config {
+	b.Helper()
+	configs := make([]models.Config, n)
+	for i := range configs {
+		password, err := cipher.Encrypt(fmt.Sprintf("secret-%d", i), registry.FieldLocation("bench", "benchlisting", "secret", "password"))
+		if err != nil {
+			b.Fatalf("failed to encrypt: %v", err)
+		}
+		configs[i] = models.Config{
+			Base:     &types.Base{ID: primitive.NewObjectID()},
+			Name:     fmt.Sprintf("bench-%d", i),
+			Type:     "benchlisting",
+			Subtype:  "secret",
+			Tags:     []string{"bench"},
+			TenantID: "bench",
+			Metadata: map[string]interface{}{
+				"url":       fmt.Sprintf("postgres://db-%d.internal:5432/app", i),
+				"password":  password,
+				"pool_size": 10,
+			},
+		}
+	}
+	return configs
+}
+
+func benchPrincipal() *auth.Principal {
+	return &auth.Principal{
+		Subject:  "bench",
+		TenantID: "bench",
+		Bindings: []auth.Binding{{Role: auth.RoleAdmin}},
+	}
+}
+
+func BenchmarkDecryptPage(b *testing.B) {
+	cipher, err := registry.NewFieldCipher("bench")
+	if err != nil {
+		b.Fatalf("failed to create cipher: %v", err)
+	}
+	configs := benchConfigs(b, cipher, benchListingConfigs)
+	principal := benchPrincipal()
+	ctx := context.Background()
+
+	for _, size := range []int{10, 100, 1000, benchListingConfigs} {
+		b.Run(fmt.Sprintf("configs=%d", size), func(b *testing.B) {
+			source := &stubDecrypters{cipher: cipher}
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				responses, err := decryptPage(ctx, source, principal, configs[:size])
+				if err != nil {
+					b.Fatalf("decryptPage failed: %v", err)
+				}
+				if responses[0].DecryptionError != "" {
+					b.Fatalf("decryption failed: %s", responses[0].DecryptionError)
+				}
+			}
+			b.StopTimer()
+			if source.lookups != b.N {
+				b.Fatalf("expected one schema lookup per page, got %d for %d pages", source.lookups, b.N)
+			}
+		})
+	}
+}
+
+// BenchmarkFindPage pages through a seeded collection. It needs a MongoDB
+// server at MONGO_URI and drops the throwaway database it seeds afterwards.
+func BenchmarkFindPage(b *testing.B) {
+	uri := os.Getenv("MONGO_URI")
+	if uri == "" {
+		b.Skip("MONGO_URI is not set")
+	}
+
+	ctx := context.Background()
+	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		b.Fatalf("failed to connect: %v", err)
+	}
+	defer client.Disconnect(ctx)
+
+	database := client.Database(fmt.Sprintf("bench_find_page_%d", time.Now().UnixNano()))
+	defer database.Drop(ctx)
+
+	cipher, err := registry.NewFieldCipher("bench")
+	if err != nil {
+		b.Fatalf("failed to create cipher: %v", err)
+	}
+	documents := make([]interface{}, benchListingConfigs)
+	for i, config := range benchConfigs(b, cipher, benchListingConfigs) {
+		documents[i] = config
+	}
+	collection := database.Collection("configs")
+	if _, err := collection.InsertMany(ctx, documents); err != nil {
+		b.Fatalf("failed to seed configs: %v", err)
+	}
+
+	s := &ConfigService{configs: collection}
+	filter, _ := configFilter(benchPrincipal(), models.ConfigQuery{})
+	pages := []struct {
+		limit int64
+		skip  int64
+	}{
+		{10, 0},
+		{100, 0},
+		{100, benchListingConfigs / 2},
+		{100, benchListingConfigs - 100},
+		{1000, 0},
+	}
+	for _, page := range pages {
+		b.Run(fmt.Sprintf("limit=%d/skip=%d", page.limit, page.skip), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				configs, total, err := s.findPage(ctx, filter, page.skip, page.limit)
+				if err != nil {
+					b.Fatalf("findPage failed: %v", err)
+				}
+				if total != benchListingConfigs || int64(len(configs)) != page.limit {
+					b.Fatalf("expected %d of %d configs, got %d of %d", page.limit, benchListingConfigs, len(configs), total)
+				}
+			}
+		})
+	}
+}
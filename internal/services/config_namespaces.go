@@ -0,0 +1,180 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+
+	"makatom-api-config/internal/auth"
+	"makatom-api-config/internal/models"
+	"makatom/common/pkg/handlers"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// namespaceSegment is one level of a path-style namespace such as acme/payments/prod
+var namespaceSegment = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// validateNamespace checks a path-style namespace; the empty namespace is the root
+func validateNamespace(namespace string) error {
+	if namespace == "" {
+		return nil
+	}
+	for _, segment := range strings.Split(namespace, "/") {
+		if !namespaceSegment.MatchString(segment) {
+			return fmt.Errorf("invalid namespace %q: segments must match %s", namespace, namespaceSegment)
+		}
+	}
+	return nil
+}
+
+// namespaceFilter matches a namespace; configs created before namespaces have none
+func namespaceFilter(namespace string) interface{} {
+	if namespace == "" {
+		return bson.M{"$in": []interface{}{"", nil}}
+	}
+	return namespace
+}
+
+// namespacePrefixFilter matches a namespace and every namespace below it
+func namespacePrefixFilter(prefix string) bson.M {
+	return bson.M{"$regex": "^" + regexp.QuoteMeta(strings.TrimSuffix(prefix, "/")) + "(/|$)"}
+}
+
+// ancestorNamespaces returns the namespace and its ancestors, outermost first:
+// acme/payments/prod -> [acme, acme/payments, acme/payments/prod]
+func ancestorNamespaces(namespace string) []string {
+	segments := strings.Split(namespace, "/")
+	namespaces := make([]string, len(segments))
+	for i := range segments {
+		namespaces[i] = strings.Join(segments[:i+1], "/")
+	}
+	return namespaces
+}
+
+// GetInheritedConfig deep-merges the metadata of the configs with the same type,
+// subtype and name from the outermost ancestor namespace down to the requested one.
+// Inner levels win; the provenance map records which level supplied each key.
+func (s *ConfigService) GetInheritedConfig(ctx context.Context, req models.InheritedConfigRequest) handlers.ServiceResponse {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+	tenantID := principal.TenantID
+
+	if err := validateNamespace(req.Namespace); err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      err.Error(),
+		}
+	}
+
+	namespaces := ancestorNamespaces(req.Namespace)
+	configs, err := s.repo.Find(ctx, bson.M{
+		"tenant_id": tenantID,
+		"namespace": bson.M{"$in": namespaces},
+		"type":      req.Type,
+		"subtype":   req.Subtype,
+		"name":      req.Name,
+	}, 0, 0)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to get configs: %v", err),
+		}
+	}
+	if len(configs) == 0 {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusNotFound,
+			Error:      "Config not found in the namespace or its ancestors",
+		}
+	}
+
+	depth := make(map[string]int, len(namespaces))
+	for i, namespace := range namespaces {
+		depth[namespace] = i
+	}
+	sort.Slice(configs, func(i, j int) bool {
+		return depth[configs[i].Namespace] < depth[configs[j].Namespace]
+	})
+
+	response := models.InheritedConfigResponse{
+		Namespace:  req.Namespace,
+		Name:       req.Name,
+		Type:       req.Type,
+		Subtype:    req.Subtype,
+		Metadata:   map[string]interface{}{},
+		Provenance: map[string]string{},
+		Sources:    make([]models.InheritedSource, 0, len(configs)),
+	}
+	for _, config := range configs {
+		// Every level must be readable, otherwise the merged result would be misleading
+		if !principal.Can(auth.PermConfigRead, config.Resource()) {
+			return forbiddenResponse(auth.PermConfigRead)
+		}
+
+		metadata := config.Metadata
+		if metadata != nil && principal.Can(auth.PermSecretRead, config.Resource()) {
+			metadata, err = s.registry.DecryptMetadata(ctx, tenantID, config.Type, config.Subtype, metadata)
+			if err != nil {
+				return handlers.ServiceResponse{
+					StatusCode: http.StatusInternalServerError,
+					Error:      fmt.Sprintf("failed to decrypt metadata of %s: %v", config.Namespace, err),
+				}
+			}
+		}
+
+		deepMerge(response.Metadata, metadata, config.Namespace, "", response.Provenance)
+		response.Sources = append(response.Sources, models.InheritedSource{
+			Namespace:     config.Namespace,
+			ConfigID:      config.ID.Hex(),
+			SchemaVersion: config.SchemaVersion,
+			UpdatedAt:     config.UpdatedAt,
+		})
+	}
+
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusOK,
+		Data:       response,
+	}
+}
+
+// deepMerge merges src into dst. Objects are merged key by key; any other value
+// replaces what dst held. Provenance records the namespace of every leaf key.
+func deepMerge(dst, src map[string]interface{}, namespace, prefix string, provenance map[string]string) {
+	for key, value := range src {
+		path := prefix + key
+		value = models.PlainValue(value)
+
+		if nested, ok := value.(map[string]interface{}); ok {
+			existing, isObject := dst[key].(map[string]interface{})
+			if !isObject {
+				dropProvenance(provenance, path)
+				existing = map[string]interface{}{}
+				dst[key] = existing
+			}
+			deepMerge(existing, nested, namespace, path+".", provenance)
+			if len(nested) == 0 {
+				provenance[path] = namespace
+			}
+			continue
+		}
+
+		dropProvenance(provenance, path)
+		dst[key] = value
+		provenance[path] = namespace
+	}
+}
+
+// dropProvenance removes the provenance of a key and of everything nested below it
+func dropProvenance(provenance map[string]string, path string) {
+	delete(provenance, path)
+	for key := range provenance {
+		if strings.HasPrefix(key, path+".") {
+			delete(provenance, key)
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sort"
+
+	"makatom-api-config/internal/auth"
+	"makatom-api-config/internal/models"
+	"makatom-api-config/internal/registry"
+	"makatom/common/pkg/handlers"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// baseEnvironment names the base metadata in environment views
+const baseEnvironment = "base"
+
+// mergeOverlay returns the base metadata deep-merged with an environment overlay
+func mergeOverlay(base, overlay map[string]interface{}) map[string]interface{} {
+	merged := map[string]interface{}{}
+	discard := map[string]string{}
+	deepMerge(merged, base, "", "", discard)
+	deepMerge(merged, overlay, "", "", discard)
+	return merged
+}
+
+// checkOverlays validates the name of every environment and its merged view against
+// the subtype schema, and checks the references of the merged view. It returns the
+// failures by environment and the config keys the environments reference.
+func (s *ConfigService) checkOverlays(ctx context.Context, owner models.Config, base map[string]interface{}, overlays models.EnvOverlays) (map[string]interface{}, []string, error) {
+	failed := map[string]interface{}{}
+	for env, overlay := range overlays {
+		if env == baseEnvironment || !namespaceSegment.MatchString(env) {
+			failed[env] = registry.ValidationResult{
+				Valid:  false,
+				Errors: []string{fmt.Sprintf("invalid environment name, must match %s and not be %q", namespaceSegment, baseEnvironment)},
+			}
+			continue
+		}
+
+		valid, result, err := s.registry.ValidateMetadata(ctx, owner.TenantID, owner.Type, owner.Subtype, mergeOverlay(base, overlay))
+		if err != nil {
+			return nil, nil, err
+		}
+		if !valid {
+			failed[env] = result
+		}
+	}
+
+	keys, problems, err := s.overlayReferences(ctx, owner, base, overlays)
+	if err != nil {
+		return nil, nil, err
+	}
+	for env, envProblems := range problems {
+		if _, invalid := failed[env]; !invalid {
+			failed[env] = registry.ValidationResult{Valid: false, Errors: envProblems}
+		}
+	}
+	return failed, keys, nil
+}
+
+// overlayReferences checks the references made by the merged view of every
+// environment. It returns the referenced config keys and the problems by environment.
+func (s *ConfigService) overlayReferences(ctx context.Context, owner models.Config, base map[string]interface{}, overlays models.EnvOverlays) ([]string, map[string][]string, error) {
+	var keys []string
+	problems := make(map[string][]string)
+	for env, overlay := range overlays {
+		envKeys, envProblems, err := s.checkReferences(ctx, owner, mergeOverlay(base, overlay))
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(envProblems) > 0 {
+			problems[env] = envProblems
+		}
+		keys = unionKeys(keys, envKeys)
+	}
+	return keys, problems, nil
+}
+
+// unionKeys returns the sorted, distinct keys of a and b
+func unionKeys(a, b []string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	keys := make([]string, 0, len(a)+len(b))
+	for _, key := range append(append([]string{}, a...), b...) {
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// encryptOverlays encrypts the fields marked with encryption=true in every overlay
+func (s *ConfigService) encryptOverlays(ctx context.Context, tenantID, configType, subtype string, overlays models.EnvOverlays) (models.EnvOverlays, error) {
+	encrypted := make(models.EnvOverlays, len(overlays))
+	for env, overlay := range overlays {
+		sealed, err := s.registry.EncryptMetadata(ctx, tenantID, configType, subtype, overlay)
+		if err != nil {
+			return nil, fmt.Errorf("environment %s: %v", env, err)
+		}
+		encrypted[env] = sealed
+	}
+	return encrypted, nil
+}
+
+// decryptOverlays decrypts the encrypted fields of every overlay
+func (s *ConfigService) decryptOverlays(ctx context.Context, tenantID, configType, subtype string, overlays models.EnvOverlays) (models.EnvOverlays, error) {
+	decrypted := make(models.EnvOverlays, len(overlays))
+	for env, overlay := range overlays {
+		plain, err := s.registry.DecryptMetadata(ctx, tenantID, configType, subtype, overlay)
+		if err != nil {
+			return nil, fmt.Errorf("environment %s: %v", env, err)
+		}
+		decrypted[env] = plain
+	}
+	return decrypted, nil
+}
+
+// GetEnvironmentDiff lists the metadata keys whose value differs between the base
+// metadata and the merged view of each environment. Encrypted fields are compared
+// only for principals allowed to read secrets; otherwise they are listed as redacted.
+func (s *ConfigService) GetEnvironmentDiff(ctx context.Context, req models.ConfigIDRequest) handlers.ServiceResponse {
+	id, err := primitive.ObjectIDFromHex(req.ID)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      "Invalid config ID",
+		}
+	}
+
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+	tenantID := principal.TenantID
+
+	config, err := s.repo.FindOne(ctx, bson.M{"_id": id, "tenant_id": tenantID})
+	if err != nil {
+		if err.Error() == "not found" {
+			return handlers.ServiceResponse{
+				StatusCode: http.StatusNotFound,
+				Error:      "Config not found",
+			}
+		}
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to get config: %v", err),
+		}
+	}
+
+	if !principal.Can(auth.PermConfigRead, config.Resource()) {
+		return forbiddenResponse(auth.PermConfigRead)
+	}
+
+	base, overlays := config.Metadata, config.Overlays
+	redacted := make(map[string]bool)
+	if principal.Can(auth.PermSecretRead, config.Resource()) {
+		if base, err = s.registry.DecryptMetadata(ctx, tenantID, config.Type, config.Subtype, base); err == nil {
+			overlays, err = s.decryptOverlays(ctx, tenantID, config.Type, config.Subtype, overlays)
+		}
+		if err != nil {
+			return handlers.ServiceResponse{
+				StatusCode: http.StatusInternalServerError,
+				Error:      fmt.Sprintf("failed to decrypt metadata: %v", err),
+			}
+		}
+	} else {
+		for _, field := range config.EncryptedFields {
+			redacted[field] = true
+		}
+	}
+
+	environments := []string{baseEnvironment}
+	views := map[string]map[string]interface{}{baseEnvironment: flattenMetadata(base)}
+	for env := range overlays {
+		environments = append(environments, env)
+	}
+	sort.Strings(environments[1:])
+	for _, env := range environments[1:] {
+		views[env] = flattenMetadata(mergeOverlay(base, overlays[env]))
+	}
+
+	keys := make(map[string]bool)
+	for _, view := range views {
+		for key := range view {
+			keys[key] = true
+		}
+	}
+
+	differing := map[string]map[string]interface{}{}
+	identical := make([]string, 0)
+	redactedKeys := make([]string, 0)
+	for key := range keys {
+		if redacted[topLevelKey(key)] {
+			redactedKeys = append(redactedKeys, key)
+			continue
+		}
+
+		values := make(map[string]interface{}, len(environments))
+		same := true
+		for _, env := range environments {
+			value, present := views[env][key]
+			if !present {
+				value = nil
+			}
+			values[env] = value
+			if !reflect.DeepEqual(value, values[baseEnvironment]) {
+				same = false
+			}
+		}
+		if same {
+			identical = append(identical, key)
+		} else {
+			differing[key] = values
+		}
+	}
+	sort.Strings(identical)
+	sort.Strings(redactedKeys)
+
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusOK,
+		Data: map[string]interface{}{
+			"config_id":    config.ID.Hex(),
+			"environments": environments,
+			"differing":    differing,
+			"identical":    identical,
+			"redacted":     redactedKeys,
+		},
+	}
+}
+
+// flattenMetadata maps every leaf of metadata to its dotted key path
+func flattenMetadata(metadata map[string]interface{}) map[string]interface{} {
+	flat := map[string]interface{}{}
+	var walk func(prefix string, m map[string]interface{})
+	walk = func(prefix string, m map[string]interface{}) {
+		for key, value := range m {
+			if nested, ok := value.(map[string]interface{}); ok && len(nested) > 0 {
+				walk(prefix+key+".", nested)
+				continue
+			}
+			flat[prefix+key] = value
+		}
+	}
+	walk("", models.PlainValue(metadata).(map[string]interface{}))
+	return flat
+}
+
+// topLevelKey returns the first segment of a dotted key path
+func topLevelKey(path string) string {
+	for i := 0; i < len(path); i++ {
+		if path[i] == '.' {
+			return path[:i]
+		}
+	}
+	return path
+}
@@ -0,0 +1,244 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"makatom-api-config/internal/auth"
+	"makatom-api-config/internal/models"
+	"makatom-api-config/internal/references"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// errReferenceForbidden is returned when resolving a reference the principal may not read
+var errReferenceForbidden = errors.New("permission denied")
+
+// checkReferences validates the references made by the metadata of a config: every
+// target must exist in the tenant and namespace of the owner, have the referenced
+// field, and not lead back to the owner. It returns the referenced config keys and
+// the validation problems.
+func (s *ConfigService) checkReferences(ctx context.Context, owner models.Config, metadata map[string]interface{}) ([]string, []string, error) {
+	tenantID, namespace := owner.TenantID, owner.Namespace
+	schemaInfo, err := s.registry.Schema(ctx, tenantID, owner.Type, owner.Subtype)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	refs, problems := references.Collect(schemaInfo.Schema, metadata)
+	if len(refs) == 0 {
+		return []string{}, problems, nil
+	}
+
+	self := references.Key(owner.Type, owner.Subtype, owner.Name)
+	targets := make(map[string]*models.Config)
+	for _, ref := range refs {
+		if ref.Key() == self {
+			problems = append(problems, fmt.Sprintf("reference %s points at the config itself", ref))
+			continue
+		}
+
+		target, found := targets[ref.Key()]
+		if !found {
+			target, err = s.findByKey(ctx, tenantID, namespace, ref.Key())
+			if err != nil {
+				return nil, nil, err
+			}
+			targets[ref.Key()] = target
+		}
+		if target == nil {
+			problems = append(problems, fmt.Sprintf("reference %s: config does not exist", ref))
+			continue
+		}
+		if ref.Field != "" {
+			if _, present := target.Metadata[ref.Field]; !present {
+				problems = append(problems, fmt.Sprintf("reference %s: field %q does not exist", ref, ref.Field))
+			}
+		}
+	}
+
+	keys := references.Keys(refs)
+	cycle, err := s.findCycle(ctx, tenantID, namespace, self, keys)
+	if err != nil {
+		return nil, nil, err
+	}
+	if cycle != nil {
+		problems = append(problems, fmt.Sprintf("reference cycle: %s", strings.Join(cycle, " -> ")))
+	}
+
+	return keys, problems, nil
+}
+
+// findCycle follows the stored references from keys and returns the path back to
+// self, or nil when the references do not lead back to it
+func (s *ConfigService) findCycle(ctx context.Context, tenantID, namespace, self string, keys []string) ([]string, error) {
+	visited := make(map[string]bool)
+
+	var visit func(key string, path []string) ([]string, error)
+	visit = func(key string, path []string) ([]string, error) {
+		path = append(path, key)
+		if key == self {
+			return path, nil
+		}
+		if visited[key] {
+			return nil, nil
+		}
+		visited[key] = true
+
+		config, err := s.findByKey(ctx, tenantID, namespace, key)
+		if err != nil || config == nil {
+			return nil, err
+		}
+		for _, next := range config.References {
+			cycle, err := visit(next, path)
+			if cycle != nil || err != nil {
+				return cycle, err
+			}
+		}
+		return nil, nil
+	}
+
+	for _, key := range keys {
+		cycle, err := visit(key, []string{self})
+		if cycle != nil || err != nil {
+			return cycle, err
+		}
+	}
+	return nil, nil
+}
+
+// findByKey returns the config with the given natural key in a namespace, or nil when there is none
+func (s *ConfigService) findByKey(ctx context.Context, tenantID, namespace, key string) (*models.Config, error) {
+	parts := strings.SplitN(key, "/", 3)
+	if len(parts) != 3 {
+		return nil, nil
+	}
+
+	config, err := s.repo.FindOne(ctx, bson.M{
+		"tenant_id": tenantID,
+		"namespace": namespaceFilter(namespace),
+		"type":      parts[0],
+		"subtype":   parts[1],
+		"name":      parts[2],
+	})
+	if err != nil {
+		if err.Error() == "not found" {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &config, nil
+}
+
+// referrers returns the names of the configs referencing the given config
+func (s *ConfigService) referrers(ctx context.Context, config models.Config) ([]string, error) {
+	referencing, err := s.repo.Find(ctx, bson.M{
+		"tenant_id":  config.TenantID,
+		"namespace":  namespaceFilter(config.Namespace),
+		"references": references.Key(config.Type, config.Subtype, config.Name),
+	}, 0, 10)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(referencing))
+	for _, referrer := range referencing {
+		names = append(names, references.Key(referrer.Type, referrer.Subtype, referrer.Name))
+	}
+	return names, nil
+}
+
+// referenceResolver resolves references on behalf of a principal, following
+// references of referenced configs up to references.MaxDepth
+type referenceResolver struct {
+	service   *ConfigService
+	principal *auth.Principal
+	resolved  map[string]map[string]interface{}
+	resolving map[string]bool
+}
+
+func (s *ConfigService) newReferenceResolver(principal *auth.Principal) *referenceResolver {
+	return &referenceResolver{
+		service:   s,
+		principal: principal,
+		resolved:  make(map[string]map[string]interface{}),
+		resolving: make(map[string]bool),
+	}
+}
+
+// resolve returns the metadata of config with its references replaced by their
+// values. The metadata must already be decrypted as far as the principal may see it.
+func (r *referenceResolver) resolve(ctx context.Context, config models.Config, metadata map[string]interface{}) (map[string]interface{}, error) {
+	key := references.Key(config.Type, config.Subtype, config.Name)
+	if cached, ok := r.resolved[key]; ok {
+		return cached, nil
+	}
+	if r.resolving[key] {
+		return nil, fmt.Errorf("reference cycle through %s", key)
+	}
+	if len(r.resolving) >= references.MaxDepth {
+		return nil, fmt.Errorf("references nested deeper than %d configs", references.MaxDepth)
+	}
+
+	r.resolving[key] = true
+	defer delete(r.resolving, key)
+
+	schemaInfo, err := r.service.registry.Schema(ctx, config.TenantID, config.Type, config.Subtype)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved, err := references.Resolve(schemaInfo.Schema, metadata, func(ref references.Ref) (interface{}, error) {
+		return r.lookup(ctx, config.TenantID, config.Namespace, ref)
+	})
+	if err != nil {
+		return nil, err
+	}
+	r.resolved[key] = resolved
+	return resolved, nil
+}
+
+// lookup loads a referenced config and returns the referenced value
+func (r *referenceResolver) lookup(ctx context.Context, tenantID, namespace string, ref references.Ref) (interface{}, error) {
+	entry, err := r.service.loadConfigByKey(ctx, tenantID, namespace, ref.Key())
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, fmt.Errorf("referenced config %s does not exist", ref.Key())
+	}
+	target := &entry.config
+	if !r.principal.Can(auth.PermConfigRead, target.Resource()) {
+		return nil, fmt.Errorf("%w: %s on %s", errReferenceForbidden, auth.PermConfigRead, ref.Key())
+	}
+
+	// Encrypted values are only resolved for principals who may read them
+	canReadSecrets := r.principal.Can(auth.PermSecretRead, target.Resource())
+	decrypted, err := r.service.decryptEntry(ctx, r.principal, entry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt %s: %v", ref.Key(), err)
+	}
+	metadata := decrypted.Metadata
+	if !canReadSecrets && ref.Field != "" {
+		for _, field := range target.EncryptedFields {
+			if field == ref.Field {
+				return nil, fmt.Errorf("%w: %s on %s", errReferenceForbidden, auth.PermSecretRead, ref)
+			}
+		}
+	}
+
+	resolved, err := r.resolve(ctx, *target, metadata)
+	if err != nil {
+		return nil, err
+	}
+	if ref.Field == "" {
+		return resolved, nil
+	}
+	value, present := resolved[ref.Field]
+	if !present {
+		return nil, fmt.Errorf("referenced field %s does not exist", ref)
+	}
+	return value, nil
+}
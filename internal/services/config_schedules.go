@@ -0,0 +1,319 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"makatom-api-config/internal/auth"
+	"makatom-api-config/internal/models"
+	"makatom/common/pkg/handlers"
+	"makatom/common/pkg/types"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// scheduleLease is how long a claimed change may stay applying before the
+// scheduler of any replica picks it up again, e.g. after a crash mid-apply
+const scheduleLease = 5 * time.Minute
+
+// scheduleUpdate stores a validated update to be applied at req.ApplyAt
+func (s *ConfigService) scheduleUpdate(ctx context.Context, principal *auth.Principal, existing models.Config, req models.UpdateConfigWithIDRequest) handlers.ServiceResponse {
+	if !req.ApplyAt.After(time.Now()) {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      "apply_at must be in the future",
+		}
+	}
+
+	change := models.ScheduledChange{
+		Base:       &types.Base{},
+		ConfigID:   existing.ID,
+		TenantID:   existing.TenantID,
+		Name:       existing.Name,
+		Namespace:  existing.Namespace,
+		Type:       existing.Type,
+		Subtype:    existing.Subtype,
+		ConfigTags: existing.Tags,
+		Tags:       req.Tags,
+		ApplyAt:    *req.ApplyAt,
+		Status:     models.ScheduleStatusPending,
+		CreatedBy:  principal.Subject,
+	}
+
+	// The change is applied with the author's stored access, so it must grant the write now
+	author, err := s.authorPrincipal(ctx, principal.TenantID, principal.Subject)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to load role bindings: %v", err),
+		}
+	}
+	if !author.Can(auth.PermConfigWrite, existing.Resource()) || !canWriteTags(author, existing, req.Tags) {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusForbidden,
+			Error:      "permission denied: scheduled updates need config:write from a role binding or API key, not only from the token",
+		}
+	}
+
+	if req.Metadata != nil {
+		change.Metadata, err = s.registry.EncryptMetadata(ctx, existing.TenantID, existing.Type, existing.Subtype, req.Metadata)
+	}
+	if err == nil && req.Overlays != nil {
+		change.Overlays, err = s.encryptOverlays(ctx, existing.TenantID, existing.Type, existing.Subtype, req.Overlays)
+	}
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to encrypt metadata: %v", err),
+		}
+	}
+
+	scheduled, err := s.scheduleRepo.InsertOne(ctx, change)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to schedule update: %v", err),
+		}
+	}
+
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusAccepted,
+		Data:       scheduled,
+	}
+}
+
+// GetScheduledChanges lists the scheduled changes of the configs the caller may read
+func (s *ConfigService) GetScheduledChanges(ctx context.Context, query models.ScheduleQuery) handlers.ServiceResponse {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+
+	scopeFilter, allowed := principal.ScopeFilter(auth.PermConfigRead)
+	if !allowed {
+		return forbiddenResponse(auth.PermConfigRead)
+	}
+	filter := bson.M{"tenant_id": principal.TenantID}
+	for key, value := range scopeFilter {
+		filter[key] = value
+	}
+	if query.ConfigID != "" {
+		configID, err := primitive.ObjectIDFromHex(query.ConfigID)
+		if err != nil {
+			return handlers.ServiceResponse{
+				StatusCode: http.StatusBadRequest,
+				Error:      "Invalid config ID",
+			}
+		}
+		filter["config_id"] = configID
+	}
+	if query.Status != "" {
+		filter["status"] = query.Status
+	}
+
+	total, err := s.scheduleRepo.Count(ctx, filter)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to count scheduled changes: %v", err),
+		}
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+	changes, err := s.scheduleRepo.Find(ctx, filter, query.Skip, limit)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to get scheduled changes: %v", err),
+		}
+	}
+
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusOK,
+		Data: map[string]interface{}{
+			"schedules": changes,
+			"total":     total,
+			"limit":     query.Limit,
+			"skip":      query.Skip,
+		},
+	}
+}
+
+// CancelScheduledChange cancels a change that has not been applied yet
+func (s *ConfigService) CancelScheduledChange(ctx context.Context, req models.ScheduleIDRequest) handlers.ServiceResponse {
+	id, err := primitive.ObjectIDFromHex(req.ID)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      "Invalid schedule ID",
+		}
+	}
+
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+
+	change, err := s.scheduleRepo.FindOne(ctx, bson.M{"_id": id, "tenant_id": principal.TenantID})
+	if err != nil {
+		if err.Error() == "not found" {
+			return handlers.ServiceResponse{
+				StatusCode: http.StatusNotFound,
+				Error:      "Scheduled change not found",
+			}
+		}
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to get scheduled change: %v", err),
+		}
+	}
+
+	if !principal.Can(auth.PermConfigWrite, change.Resource()) {
+		return forbiddenResponse(auth.PermConfigWrite)
+	}
+
+	// Only pending changes can be cancelled; the scheduler may be claiming this one right now
+	result, err := s.schedules.UpdateOne(ctx,
+		bson.M{"_id": id, "status": models.ScheduleStatusPending},
+		bson.M{"$set": bson.M{
+			"status":       models.ScheduleStatusCancelled,
+			"cancelled_by": principal.Subject,
+			"updated_at":   time.Now(),
+		}},
+	)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to cancel scheduled change: %v", err),
+		}
+	}
+	if result.ModifiedCount == 0 {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusConflict,
+			Error:      "only pending changes can be cancelled",
+		}
+	}
+
+	change.Status = models.ScheduleStatusCancelled
+	change.CancelledBy = principal.Subject
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusOK,
+		Data:       change,
+	}
+}
+
+// RunScheduler applies due scheduled changes every interval until ctx is done.
+// Changes are claimed atomically, so several replicas may run the scheduler.
+func (s *ConfigService) RunScheduler(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		s.applyDueChanges(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// applyDueChanges claims and applies due changes, oldest first, until none is left
+func (s *ConfigService) applyDueChanges(ctx context.Context) {
+	for ctx.Err() == nil {
+		now := time.Now()
+		var change models.ScheduledChange
+		err := s.schedules.FindOneAndUpdate(ctx,
+			bson.M{
+				"apply_at": bson.M{"$lte": now},
+				"$or": []bson.M{
+					{"status": models.ScheduleStatusPending},
+					{"status": models.ScheduleStatusApplying, "claimed_at": bson.M{"$lt": now.Add(-scheduleLease)}},
+				},
+			},
+			bson.M{"$set": bson.M{"status": models.ScheduleStatusApplying, "claimed_at": now}},
+			options.FindOneAndUpdate().SetSort(bson.M{"apply_at": 1}).SetReturnDocument(options.After),
+		).Decode(&change)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return
+		}
+		if err != nil {
+			log.Printf("scheduler: failed to claim scheduled change: %v", err)
+			return
+		}
+
+		s.applyScheduledChange(ctx, change)
+	}
+}
+
+// applyScheduledChange applies a claimed change through UpdateConfig on behalf of
+// its author and records the outcome
+func (s *ConfigService) applyScheduledChange(ctx context.Context, change models.ScheduledChange) {
+	req := models.UpdateConfigWithIDRequest{
+		ID:   change.ConfigID.Hex(),
+		Tags: change.Tags,
+	}
+
+	var err error
+	if change.Metadata != nil {
+		req.Metadata, err = s.registry.DecryptMetadata(ctx, change.TenantID, change.Type, change.Subtype, change.Metadata)
+	}
+	if err == nil && change.Overlays != nil {
+		req.Overlays, err = s.decryptOverlays(ctx, change.TenantID, change.Type, change.Subtype, change.Overlays)
+	}
+
+	status, message := models.ScheduleStatusApplied, ""
+	if err != nil {
+		status, message = models.ScheduleStatusFailed, fmt.Sprintf("failed to decrypt scheduled metadata: %v", err)
+	} else if author, err := s.authorPrincipal(ctx, change.TenantID, change.CreatedBy); err != nil {
+		status, message = models.ScheduleStatusFailed, fmt.Sprintf("failed to load the author's access: %v", err)
+	} else {
+		// UpdateConfig fails the change if the author no longer holds config:write
+		resp := s.UpdateConfig(auth.WithPrincipal(ctx, author), req)
+		if resp.StatusCode != http.StatusOK {
+			status, message = models.ScheduleStatusFailed, resp.Error
+		}
+	}
+
+	outcome := bson.M{"status": status, "error": message}
+	if status == models.ScheduleStatusApplied {
+		outcome["applied_at"] = time.Now()
+	}
+	if _, err := s.scheduleRepo.UpdateByID(ctx, change.ID, bson.M{"$set": outcome}); err != nil {
+		log.Printf("scheduler: failed to record outcome of scheduled change %s: %v", change.ID.Hex(), err)
+	}
+}
+
+// authorPrincipal re-loads the current access of a change's author: the
+// bindings of its API key, or its stored role bindings. Roles carried by a
+// user's token cannot be re-checked outside a request and are not included.
+func (s *ConfigService) authorPrincipal(ctx context.Context, tenantID, subject string) (*auth.Principal, error) {
+	var bindings []auth.Binding
+	var err error
+	keyID, isKey := strings.CutPrefix(subject, apiKeySubjectPrefix)
+	if isKey {
+		bindings, err = s.apiKeys.KeyBindings(ctx, tenantID, keyID)
+	} else {
+		bindings, err = s.bindings.LoadBindings(ctx, tenantID, subject)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &auth.Principal{
+		Subject:        subject,
+		TenantID:       tenantID,
+		Bindings:       bindings,
+		ServiceAccount: isKey,
+	}, nil
+}
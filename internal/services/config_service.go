@@ -2,14 +2,21 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"sort"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 
+	"makatom-api-config/internal/auth"
+	"makatom-api-config/internal/flags"
 	"makatom-api-config/internal/models"
+	"makatom-api-config/internal/registry"
 	"makatom/common/pkg/database/mongodb"
 	"makatom/common/pkg/handlers"
 	"makatom/common/pkg/types"
@@ -21,26 +28,69 @@ const (
 
 // ConfigService handles business logic for config operations
 type ConfigService struct {
-	repo        *mongodb.MongoRepository[models.Config]
-	archiveRepo *mongodb.MongoRepository[models.ConfigArchive]
+	repo         *mongodb.MongoRepository[models.Config]
+	configs      *mongo.Collection
+	archiveRepo  *mongodb.MongoRepository[models.ConfigArchive]
+	archives     *mongo.Collection
+	scheduleRepo *mongodb.MongoRepository[models.ScheduledChange]
+	schedules    *mongo.Collection
+	indexes      *mongo.Collection
+	notifier     *changeNotifier
+	cache        *configCache
+	registry     *registry.Registry
+	reviewPolicy ReviewPolicy
+	bindings     auth.BindingStore
+	apiKeys      *APIKeyService
 }
 
 // NewConfigService creates a new ConfigService instance
-func NewConfigService(configCollection, archiveCollection *mongo.Collection) *ConfigService {
+func NewConfigService(configCollection, archiveCollection, scheduleCollection, indexCollection *mongo.Collection, typeRegistry *registry.Registry, reviewPolicy ReviewPolicy, bindings auth.BindingStore, apiKeys *APIKeyService) *ConfigService {
 	return &ConfigService{
-		repo:        mongodb.NewMongoRepository[models.Config](configCollection),
-		archiveRepo: mongodb.NewMongoRepository[models.ConfigArchive](archiveCollection),
+		repo:         mongodb.NewMongoRepository[models.Config](configCollection),
+		configs:      configCollection,
+		archiveRepo:  mongodb.NewMongoRepository[models.ConfigArchive](archiveCollection),
+		archives:     archiveCollection,
+		scheduleRepo: mongodb.NewMongoRepository[models.ScheduledChange](scheduleCollection),
+		schedules:    scheduleCollection,
+		indexes:      indexCollection,
+		notifier:     newChangeNotifier(),
+		cache:        newConfigCache(),
+		registry:     typeRegistry,
+		reviewPolicy: reviewPolicy,
+		bindings:     bindings,
+		apiKeys:      apiKeys,
 	}
 }
 
 // CreateConfig creates a new config
 func (s *ConfigService) CreateConfig(ctx context.Context, req models.CreateConfigRequest) handlers.ServiceResponse {
-	// For now, use dummy values - in a real app, these would come from JWT token
-	userID := "dummy-user-id"
-	tenantID := "dummy-tenant-id"
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+	userID := principal.Subject
+	tenantID := principal.TenantID
+
+	// Ensure the caller may write configs of this type/subtype and tags
+	if !principal.Can(auth.PermConfigWrite, auth.Resource{Type: req.Type, Subtype: req.Subtype, Tags: req.Tags}) {
+		return forbiddenResponse(auth.PermConfigWrite)
+	}
+
+	if err := validateNamespace(req.Namespace); err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      err.Error(),
+		}
+	}
 
-	// Validate that type exists
-	_, typeExists := types.GlobalConfigTypeRegistry.GetType(req.Type)
+	// Validate that type exists in the merged built-in and tenant registry
+	_, typeExists, err := s.registry.GetType(ctx, tenantID, req.Type)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to get config type: %v", err),
+		}
+	}
 	if !typeExists {
 		return handlers.ServiceResponse{
 			StatusCode: http.StatusBadRequest,
@@ -50,7 +100,13 @@ func (s *ConfigService) CreateConfig(ctx context.Context, req models.CreateConfi
 
 	// Validate that subtype exists for the type
 	if req.Subtype != "" {
-		_, subtypeExists := types.GlobalConfigTypeRegistry.GetSubtype(req.Type, req.Subtype)
+		_, subtypeExists, err := s.registry.GetSubtype(ctx, tenantID, req.Type, req.Subtype)
+		if err != nil {
+			return handlers.ServiceResponse{
+				StatusCode: http.StatusInternalServerError,
+				Error:      fmt.Sprintf("failed to get config subtype: %v", err),
+			}
+		}
 		if !subtypeExists {
 			return handlers.ServiceResponse{
 				StatusCode: http.StatusBadRequest,
@@ -61,8 +117,14 @@ func (s *ConfigService) CreateConfig(ctx context.Context, req models.CreateConfi
 
 	// Validate metadata against subtype schema if metadata is provided
 	if req.Metadata != nil {
-		validationResult := types.GlobalConfigTypeRegistry.ValidateMetadata(req.Type, req.Subtype, req.Metadata)
-		if !validationResult.Valid {
+		valid, validationResult, err := s.registry.ValidateMetadata(ctx, tenantID, req.Type, req.Subtype, req.Metadata)
+		if err != nil {
+			return handlers.ServiceResponse{
+				StatusCode: http.StatusInternalServerError,
+				Error:      fmt.Sprintf("failed to validate metadata: %v", err),
+			}
+		}
+		if !valid {
 			return handlers.ServiceResponse{
 				StatusCode: http.StatusBadRequest,
 				Error:      "metadata validation failed",
@@ -71,11 +133,56 @@ func (s *ConfigService) CreateConfig(ctx context.Context, req models.CreateConfi
 		}
 	}
 
+	// References must point at existing configs of the tenant without forming a cycle
+	owner := models.Config{TenantID: tenantID, Namespace: req.Namespace, Type: req.Type, Subtype: req.Subtype, Name: req.Name}
+	referenceKeys, problems, err := s.checkReferences(ctx, owner, req.Metadata)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to check references: %v", err),
+		}
+	}
+	if len(problems) > 0 {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      "metadata validation failed",
+			Data:       registry.ValidationResult{Valid: false, Errors: problems},
+		}
+	}
+
+	// Every environment overlay merged over the base must be valid on its own
+	var encryptedOverlays models.EnvOverlays
+	if len(req.Overlays) > 0 {
+		failed, overlayKeys, err := s.checkOverlays(ctx, owner, req.Metadata, req.Overlays)
+		if err != nil {
+			return handlers.ServiceResponse{
+				StatusCode: http.StatusInternalServerError,
+				Error:      fmt.Sprintf("failed to validate overlays: %v", err),
+			}
+		}
+		if len(failed) > 0 {
+			return handlers.ServiceResponse{
+				StatusCode: http.StatusBadRequest,
+				Error:      "overlay validation failed",
+				Data:       failed,
+			}
+		}
+		referenceKeys = unionKeys(referenceKeys, overlayKeys)
+
+		encryptedOverlays, err = s.encryptOverlays(ctx, tenantID, req.Type, req.Subtype, req.Overlays)
+		if err != nil {
+			return handlers.ServiceResponse{
+				StatusCode: http.StatusInternalServerError,
+				Error:      fmt.Sprintf("failed to encrypt overlays: %v", err),
+			}
+		}
+	}
+
 	// Encrypt metadata fields marked with encryption=true
 	var encryptedMetadata map[string]interface{}
 	if req.Metadata != nil {
 		var err error
-		encryptedMetadata, err = types.GlobalConfigTypeRegistry.EncryptMetadata(req.Type, req.Subtype, req.Metadata)
+		encryptedMetadata, err = s.registry.EncryptMetadata(ctx, tenantID, req.Type, req.Subtype, req.Metadata)
 		if err != nil {
 			return handlers.ServiceResponse{
 				StatusCode: http.StatusInternalServerError,
@@ -84,19 +191,31 @@ func (s *ConfigService) CreateConfig(ctx context.Context, req models.CreateConfi
 		}
 	}
 
-	// Check if config with same name already exists for this tenant
-	existing, err := s.repo.FindOne(ctx, bson.M{
+	// Check if config with same name already exists for this tenant and namespace
+	duplicateFilter := bson.M{
 		"name":      req.Name,
 		"tenant_id": tenantID,
+		"namespace": namespaceFilter(req.Namespace),
 		"type":      req.Type,
 		"subtype":   req.Subtype,
-	})
+	}
+	// Flags are evaluated by key alone, so a key is unique across flag subtypes
+	if req.Type == flags.TypeName {
+		delete(duplicateFilter, "subtype")
+	}
+	existing, err := s.repo.FindOne(ctx, duplicateFilter)
 
 	// If we found an existing config, return duplicate error
 	if err == nil && existing.ID != primitive.NilObjectID {
+		if req.Type == flags.TypeName {
+			return handlers.ServiceResponse{
+				StatusCode: http.StatusBadRequest,
+				Error:      fmt.Sprintf("a flag with key %q already exists in this namespace", req.Name),
+			}
+		}
 		return handlers.ServiceResponse{
 			StatusCode: http.StatusBadRequest,
-			Error:      "config with this name already exists for this tenant and type",
+			Error:      "config with this name already exists for this tenant, namespace and type",
 		}
 	}
 
@@ -109,17 +228,31 @@ func (s *ConfigService) CreateConfig(ctx context.Context, req models.CreateConfi
 		}
 	}
 
+	// Record the schema the metadata is written against
+	schemaInfo, err := s.registry.Schema(ctx, tenantID, req.Type, req.Subtype)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to get config schema: %v", err),
+		}
+	}
+
 	// Create new config with encrypted metadata
 	config := models.Config{
-		Base:          &types.Base{},
-		Name:          req.Name,
-		Type:          req.Type,
-		Subtype:       req.Subtype,
-		Tags:          req.Tags,
-		TenantID:      tenantID,
-		CreatedBy:     userID,
-		LastUpdatedBy: userID,
-		Metadata:      encryptedMetadata,
+		Base:            &types.Base{},
+		Name:            req.Name,
+		Namespace:       req.Namespace,
+		Type:            req.Type,
+		Subtype:         req.Subtype,
+		Tags:            req.Tags,
+		TenantID:        tenantID,
+		CreatedBy:       userID,
+		LastUpdatedBy:   userID,
+		Metadata:        encryptedMetadata,
+		SchemaVersion:   schemaInfo.Version,
+		EncryptedFields: schemaInfo.EncryptedFields,
+		References:      referenceKeys,
+		Overlays:        encryptedOverlays,
 	}
 
 	createdConfig, err := s.repo.InsertOne(ctx, config)
@@ -129,6 +262,7 @@ func (s *ConfigService) CreateConfig(ctx context.Context, req models.CreateConfi
 			Error:      fmt.Sprintf("failed to create config: %v", err),
 		}
 	}
+	s.recordChange(ctx, tenantID, createdConfig.ID)
 
 	return handlers.ServiceResponse{
 		StatusCode: http.StatusCreated,
@@ -137,7 +271,7 @@ func (s *ConfigService) CreateConfig(ctx context.Context, req models.CreateConfi
 }
 
 // GetConfigByID retrieves a config by its ID
-func (s *ConfigService) GetConfigByID(ctx context.Context, req models.ConfigIDRequest) handlers.ServiceResponse {
+func (s *ConfigService) GetConfigByID(ctx context.Context, req models.GetConfigRequest) handlers.ServiceResponse {
 	// Parse ObjectID
 	id, err := primitive.ObjectIDFromHex(req.ID)
 	if err != nil {
@@ -147,10 +281,13 @@ func (s *ConfigService) GetConfigByID(ctx context.Context, req models.ConfigIDRe
 		}
 	}
 
-	// For now, use dummy tenant ID - in a real app, this would come from JWT token
-	tenantID := "dummy-tenant-id"
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+	tenantID := principal.TenantID
 
-	config, err := s.repo.FindByID(ctx, id)
+	entry, err := s.loadConfig(ctx, tenantID, id)
 	if err != nil {
 		if err.Error() == "not found" {
 			return handlers.ServiceResponse{
@@ -165,58 +302,73 @@ func (s *ConfigService) GetConfigByID(ctx context.Context, req models.ConfigIDRe
 	}
 
 	// Ensure the config belongs to the requesting tenant
-	if config.TenantID != tenantID {
+	if entry.config.TenantID != tenantID {
 		return handlers.ServiceResponse{
 			StatusCode: http.StatusNotFound,
 			Error:      "Config not found",
 		}
 	}
 
-	// Decrypt metadata fields marked with encryption=true
-	if config.Metadata != nil {
-		decryptedMetadata, err := types.GlobalConfigTypeRegistry.DecryptMetadata(config.Type, config.Subtype, config.Metadata)
+	if !principal.Can(auth.PermConfigRead, entry.config.Resource()) {
+		return forbiddenResponse(auth.PermConfigRead)
+	}
+
+	// Decrypt metadata fields marked with encryption=true, only for secret readers
+	config, err := s.decryptEntry(ctx, principal, entry)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      err.Error(),
+		}
+	}
+
+	// Serve the base metadata merged with the requested environment overlay
+	if req.Env != "" {
+		overlay, defined := config.Overlays[req.Env]
+		if !defined && req.Env != baseEnvironment {
+			return handlers.ServiceResponse{
+				StatusCode: http.StatusNotFound,
+				Error:      fmt.Sprintf("environment %q is not defined for the config", req.Env),
+			}
+		}
+		config.Metadata = mergeOverlay(config.Metadata, overlay)
+		config.Overlays = nil
+	}
+
+	// Replace references by the values they point at
+	if req.Resolve && len(config.References) > 0 {
+		resolved, err := s.newReferenceResolver(principal).resolve(ctx, config, config.Metadata)
 		if err != nil {
+			status := http.StatusUnprocessableEntity
+			if errors.Is(err, errReferenceForbidden) {
+				status = http.StatusForbidden
+			}
 			return handlers.ServiceResponse{
-				StatusCode: http.StatusInternalServerError,
-				Error:      fmt.Sprintf("failed to decrypt metadata: %v", err),
+				StatusCode: status,
+				Error:      fmt.Sprintf("failed to resolve references: %v", err),
 			}
 		}
-		config.Metadata = decryptedMetadata
+		config.Metadata = resolved
 	}
 
+	response := config.ToResponse()
+	response.Env = req.Env
 	return handlers.ServiceResponse{
 		StatusCode: http.StatusOK,
-		Data:       config.ToResponse(),
+		Data:       response,
 	}
 }
 
 // GetConfigs retrieves configs with filtering and pagination
 func (s *ConfigService) GetConfigs(ctx context.Context, query models.ConfigQuery) handlers.ServiceResponse {
-	// For now, use dummy tenant ID - in a real app, this would come from JWT token
-	tenantID := "dummy-tenant-id"
-
-	// Build filter
-	filter := bson.M{"tenant_id": tenantID}
-
-	if query.Type != "" {
-		filter["type"] = query.Type
-	}
-
-	if query.Subtype != "" {
-		filter["subtype"] = query.Subtype
-	}
-
-	if query.Tag != "" {
-		filter["tags"] = bson.M{"$in": []string{query.Tag}}
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
 	}
 
-	// Get total count
-	total, err := s.repo.Count(ctx, filter)
-	if err != nil {
-		return handlers.ServiceResponse{
-			StatusCode: http.StatusInternalServerError,
-			Error:      fmt.Sprintf("failed to count configs: %v", err),
-		}
+	filter, allowed := configFilter(principal, query)
+	if !allowed {
+		return forbiddenResponse(auth.PermConfigRead)
 	}
 
 	// Set default limit if not provided
@@ -224,9 +376,10 @@ func (s *ConfigService) GetConfigs(ctx context.Context, query models.ConfigQuery
 	if limit <= 0 {
 		limit = 10
 	}
+	limit = min(limit, maxListLimit)
 
-	// Get configs
-	configs, err := s.repo.Find(ctx, filter, query.Skip, limit)
+	skip := max(query.Skip, 0)
+	configs, total, err := s.findPage(ctx, filter, skip, limit)
 	if err != nil {
 		return handlers.ServiceResponse{
 			StatusCode: http.StatusInternalServerError,
@@ -234,21 +387,13 @@ func (s *ConfigService) GetConfigs(ctx context.Context, query models.ConfigQuery
 		}
 	}
 
-	// Convert to responses with decryption
-	responses := make([]models.ConfigResponse, len(configs))
-	for i, config := range configs {
-		// Decrypt metadata fields marked with encryption=true
-		if config.Metadata != nil {
-			decryptedMetadata, err := types.GlobalConfigTypeRegistry.DecryptMetadata(config.Type, config.Subtype, config.Metadata)
-			if err != nil {
-				return handlers.ServiceResponse{
-					StatusCode: http.StatusInternalServerError,
-					Error:      fmt.Sprintf("failed to decrypt metadata for config %s: %v", config.ID.Hex(), err),
-				}
-			}
-			config.Metadata = decryptedMetadata
+	// A config that fails to decrypt is marked rather than failing the page
+	responses, err := decryptPage(ctx, s.registry, principal, configs)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to decrypt configs: %v", err),
 		}
-		responses[i] = config.ToResponse()
 	}
 
 	return handlers.ServiceResponse{
@@ -256,12 +401,82 @@ func (s *ConfigService) GetConfigs(ctx context.Context, query models.ConfigQuery
 		Data: map[string]interface{}{
 			"configs": responses,
 			"total":   total,
-			"limit":   query.Limit,
-			"skip":    query.Skip,
+			"limit":   limit,
+			"skip":    skip,
 		},
 	}
 }
 
+// decryptForReader decrypts the metadata and overlays of a config when the
+// principal may read its secrets; other readers get the stored ciphertext
+func (s *ConfigService) decryptForReader(ctx context.Context, principal *auth.Principal, config models.Config) (models.Config, error) {
+	if config.Metadata == nil || !principal.Can(auth.PermSecretRead, config.Resource()) {
+		return config, nil
+	}
+
+	decrypt, err := s.registry.MetadataDecrypter(ctx, config.TenantID, config.Type, config.Subtype)
+	if err != nil {
+		return config, fmt.Errorf("failed to decrypt metadata: %v", err)
+	}
+	return decryptConfig(config, decrypt)
+}
+
+// decryptConfig decrypts the metadata and overlays of a config
+func decryptConfig(config models.Config, decrypt registry.Decrypter) (models.Config, error) {
+	metadata, err := decrypt(config.Metadata)
+	if err != nil {
+		return config, fmt.Errorf("failed to decrypt metadata: %v", err)
+	}
+	overlays := make(models.EnvOverlays, len(config.Overlays))
+	for env, overlay := range config.Overlays {
+		plain, err := decrypt(overlay)
+		if err != nil {
+			return config, fmt.Errorf("failed to decrypt overlays: environment %s: %v", env, err)
+		}
+		overlays[env] = plain
+	}
+	config.Metadata = metadata
+	config.Overlays = overlays
+	return config, nil
+}
+
+// configFilter builds the filter of a config query, restricted to the scopes the
+// principal may read. It reports false when the principal may read no config.
+func configFilter(principal *auth.Principal, query models.ConfigQuery) (bson.M, bool) {
+	scopeFilter, allowed := principal.ScopeFilter(auth.PermConfigRead)
+	if !allowed {
+		return nil, false
+	}
+	filter := bson.M{"tenant_id": principal.TenantID}
+	for key, value := range scopeFilter {
+		filter[key] = value
+	}
+
+	if query.Name != "" {
+		filter["name"] = query.Name
+	}
+
+	if query.Namespace != "" {
+		filter["namespace"] = query.Namespace
+	} else if query.NamespacePrefix != "" {
+		filter["namespace"] = namespacePrefixFilter(query.NamespacePrefix)
+	}
+
+	if query.Type != "" {
+		filter["type"] = query.Type
+	}
+
+	if query.Subtype != "" {
+		filter["subtype"] = query.Subtype
+	}
+
+	if query.Tag != "" {
+		filter["tags"] = bson.M{"$in": []string{query.Tag}}
+	}
+
+	return filter, true
+}
+
 // DecryptConfigField decrypts a specific encrypted field value
 func (s *ConfigService) DecryptConfigField(ctx context.Context, req models.DecryptFieldRequest) handlers.ServiceResponse {
 	// Parse ObjectID
@@ -273,11 +488,14 @@ func (s *ConfigService) DecryptConfigField(ctx context.Context, req models.Decry
 		}
 	}
 
-	// For now, use dummy tenant ID - in a real app, this would come from JWT token
-	tenantID := "dummy-tenant-id"
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+	tenantID := principal.TenantID
 
 	// Get the config to verify it exists and belongs to tenant
-	config, err := s.repo.FindByID(ctx, id)
+	entry, err := s.loadConfig(ctx, tenantID, id)
 	if err != nil {
 		if err.Error() == "not found" {
 			return handlers.ServiceResponse{
@@ -290,6 +508,7 @@ func (s *ConfigService) DecryptConfigField(ctx context.Context, req models.Decry
 			Error:      fmt.Sprintf("failed to get config: %v", err),
 		}
 	}
+	config := entry.config
 
 	// Ensure the config belongs to the requesting tenant
 	if config.TenantID != tenantID {
@@ -299,8 +518,18 @@ func (s *ConfigService) DecryptConfigField(ctx context.Context, req models.Decry
 		}
 	}
 
+	if !principal.Can(auth.PermSecretRead, config.Resource()) {
+		return forbiddenResponse(auth.PermSecretRead)
+	}
+
 	// Verify the field is marked for encryption in the schema
-	subtype, exists := types.GlobalConfigTypeRegistry.GetSubtype(config.Type, config.Subtype)
+	subtype, exists, err := s.registry.GetSubtype(ctx, tenantID, config.Type, config.Subtype)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to get config subtype: %v", err),
+		}
+	}
 	if !exists {
 		return handlers.ServiceResponse{
 			StatusCode: http.StatusBadRequest,
@@ -349,7 +578,7 @@ func (s *ConfigService) DecryptConfigField(ctx context.Context, req models.Decry
 	}
 
 	// Decrypt the value
-	decryptedValue, err := types.GlobalConfigTypeRegistry.DecryptMetadata(config.Type, config.Subtype, map[string]interface{}{
+	decryptedValue, err := s.registry.DecryptMetadata(ctx, tenantID, config.Type, config.Subtype, map[string]interface{}{
 		req.FieldName: encryptedStr,
 	})
 	if err != nil {
@@ -380,9 +609,12 @@ func (s *ConfigService) UpdateConfig(ctx context.Context, req models.UpdateConfi
 		}
 	}
 
-	// For now, use dummy values - in a real app, these would come from JWT token
-	tenantID := "dummy-tenant-id"
-	userID := "dummy-user-id"
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+	tenantID := principal.TenantID
+	userID := principal.Subject
 
 	// Do not allow changing name, type, subtype, or tenantID (before DB lookup)
 	if req.Name != "" {
@@ -420,11 +652,66 @@ func (s *ConfigService) UpdateConfig(ctx context.Context, req models.UpdateConfi
 		}
 	}
 
+	if !principal.Can(auth.PermConfigWrite, existing.Resource()) || !canWriteTags(principal, existing, req.Tags) {
+		return forbiddenResponse(auth.PermConfigWrite)
+	}
+
+	// Changes to protected configs go through reviewed drafts
+	if s.reviewPolicy.Protects(existing.Tags) {
+		return protectedResponse()
+	}
+
+	updates, errResp := s.buildUpdate(ctx, existing, req)
+	if errResp != nil {
+		return *errResp
+	}
+
+	// Scheduled updates are validated now and again when the scheduler applies them
+	if req.ApplyAt != nil {
+		return s.scheduleUpdate(ctx, principal, existing, req)
+	}
+
+	updatedConfig, err := s.applyUpdate(ctx, existing, updates, userID)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("transaction failed: %v", err),
+		}
+	}
+
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusOK,
+		Data:       updatedConfig.ToResponse(),
+	}
+}
+
+// canWriteTags reports whether the principal may write the config once it
+// carries the given tags, so scoped editors cannot retag a config out of their
+// scope or into another. Nil tags leave the tags unchanged.
+func canWriteTags(principal *auth.Principal, config models.Config, tags []string) bool {
+	if tags == nil {
+		return true
+	}
+	return principal.Can(auth.PermConfigWrite, auth.Resource{Type: config.Type, Subtype: config.Subtype, Tags: tags})
+}
+
+// buildUpdate validates the tags, metadata and overlays of an update against the
+// existing config and returns the update document, or the error response
+func (s *ConfigService) buildUpdate(ctx context.Context, existing models.Config, req models.UpdateConfigWithIDRequest) (bson.M, *handlers.ServiceResponse) {
+	tenantID := existing.TenantID
+	var err error
+
 	// Validate metadata against subtype schema if metadata is being updated
 	if req.Metadata != nil {
-		validationResult := types.GlobalConfigTypeRegistry.ValidateMetadata(existing.Type, existing.Subtype, req.Metadata)
-		if !validationResult.Valid {
-			return handlers.ServiceResponse{
+		valid, validationResult, err := s.registry.ValidateMetadata(ctx, tenantID, existing.Type, existing.Subtype, req.Metadata)
+		if err != nil {
+			return nil, &handlers.ServiceResponse{
+				StatusCode: http.StatusInternalServerError,
+				Error:      fmt.Sprintf("failed to validate metadata: %v", err),
+			}
+		}
+		if !valid {
+			return nil, &handlers.ServiceResponse{
 				StatusCode: http.StatusBadRequest,
 				Error:      "metadata validation failed",
 				Data:       validationResult,
@@ -432,20 +719,100 @@ func (s *ConfigService) UpdateConfig(ctx context.Context, req models.UpdateConfi
 		}
 	}
 
-	// Build update document (only allow tags and metadata)
+	// Build update document (only allow tags, metadata and overlays)
 	updates := bson.M{}
+	if req.Metadata != nil || req.Overlays != nil {
+		// Whichever of the base and the overlays is not replaced keeps its stored value
+		base, overlays := req.Metadata, req.Overlays
+		if base == nil && existing.Metadata != nil {
+			base, err = s.registry.DecryptMetadata(ctx, tenantID, existing.Type, existing.Subtype, existing.Metadata)
+		}
+		if err == nil && overlays == nil {
+			overlays, err = s.decryptOverlays(ctx, tenantID, existing.Type, existing.Subtype, existing.Overlays)
+		}
+		if err != nil {
+			return nil, &handlers.ServiceResponse{
+				StatusCode: http.StatusInternalServerError,
+				Error:      fmt.Sprintf("failed to decrypt metadata: %v", err),
+			}
+		}
+
+		referenceKeys, problems, err := s.checkReferences(ctx, existing, base)
+		if err != nil {
+			return nil, &handlers.ServiceResponse{
+				StatusCode: http.StatusInternalServerError,
+				Error:      fmt.Sprintf("failed to check references: %v", err),
+			}
+		}
+		if len(problems) > 0 {
+			return nil, &handlers.ServiceResponse{
+				StatusCode: http.StatusBadRequest,
+				Error:      "metadata validation failed",
+				Data:       registry.ValidationResult{Valid: false, Errors: problems},
+			}
+		}
+
+		failed, overlayKeys, err := s.checkOverlays(ctx, existing, base, overlays)
+		if err != nil {
+			return nil, &handlers.ServiceResponse{
+				StatusCode: http.StatusInternalServerError,
+				Error:      fmt.Sprintf("failed to validate overlays: %v", err),
+			}
+		}
+		if len(failed) > 0 {
+			return nil, &handlers.ServiceResponse{
+				StatusCode: http.StatusBadRequest,
+				Error:      "overlay validation failed",
+				Data:       failed,
+			}
+		}
+		updates["references"] = unionKeys(referenceKeys, overlayKeys)
+	}
+	if req.Overlays != nil {
+		encryptedOverlays, err := s.encryptOverlays(ctx, tenantID, existing.Type, existing.Subtype, req.Overlays)
+		if err != nil {
+			return nil, &handlers.ServiceResponse{
+				StatusCode: http.StatusInternalServerError,
+				Error:      fmt.Sprintf("failed to encrypt overlays: %v", err),
+			}
+		}
+		updates["overlays"] = encryptedOverlays
+	}
 	if req.Tags != nil {
 		updates["tags"] = req.Tags
 	}
 	if req.Metadata != nil {
-		updates["metadata"] = req.Metadata
+		// Encrypt metadata fields marked with encryption=true and record the schema
+		encryptedMetadata, err := s.registry.EncryptMetadata(ctx, tenantID, existing.Type, existing.Subtype, req.Metadata)
+		if err != nil {
+			return nil, &handlers.ServiceResponse{
+				StatusCode: http.StatusInternalServerError,
+				Error:      fmt.Sprintf("failed to encrypt metadata: %v", err),
+			}
+		}
+		schemaInfo, err := s.registry.Schema(ctx, tenantID, existing.Type, existing.Subtype)
+		if err != nil {
+			return nil, &handlers.ServiceResponse{
+				StatusCode: http.StatusInternalServerError,
+				Error:      fmt.Sprintf("failed to get config schema: %v", err),
+			}
+		}
+		updates["metadata"] = encryptedMetadata
+		updates["schema_version"] = schemaInfo.Version
+		updates["encrypted_fields"] = schemaInfo.EncryptedFields
 	}
+	return updates, nil
+}
+
+// applyUpdate archives the current version of a config and applies the update
+// document within one transaction
+func (s *ConfigService) applyUpdate(ctx context.Context, existing models.Config, updates bson.M, userID string) (models.Config, error) {
 	updates["last_updated_by"] = userID
 
 	var updatedConfig models.Config
 
 	// Use transaction to ensure both archive creation and config update happen atomically
-	err = s.repo.WithTransaction(ctx, func(sessCtx mongo.SessionContext) error {
+	err := s.repo.WithTransaction(ctx, func(sessCtx mongo.SessionContext) error {
 		// Archive the current version before updating
 		err := s.archiveConfigVersionWithSession(sessCtx, existing, userID)
 		if err != nil {
@@ -453,25 +820,53 @@ func (s *ConfigService) UpdateConfig(ctx context.Context, req models.UpdateConfi
 		}
 
 		// Update the config
-		updated, err := s.repo.UpdateByID(sessCtx, id, bson.M{"$set": updates})
+		updated, err := s.repo.UpdateByID(sessCtx, existing.ID, bson.M{"$set": updates})
 		if err != nil {
 			return fmt.Errorf("failed to update config: %v", err)
 		}
 		updatedConfig = updated
 		return nil
 	})
+	if err == nil {
+		s.recordChange(ctx, existing.TenantID, existing.ID)
+	}
+	return updatedConfig, err
+}
 
-	if err != nil {
-		return handlers.ServiceResponse{
-			StatusCode: http.StatusInternalServerError,
-			Error:      fmt.Sprintf("transaction failed: %v", err),
+// errConfigChanged reports that a guarded update found the config changed since it was read
+var errConfigChanged = errors.New("config changed since it was read")
+
+// applyUpdateUnchanged archives and updates a config like applyUpdate, but only
+// while it was last updated at updatedAt. Otherwise nothing is written and
+// errConfigChanged is returned.
+func (s *ConfigService) applyUpdateUnchanged(ctx context.Context, existing models.Config, updates bson.M, userID string, updatedAt time.Time) (models.Config, error) {
+	updates["last_updated_by"] = userID
+	updates["updated_at"] = time.Now()
+
+	var updatedConfig models.Config
+	err := s.repo.WithTransaction(ctx, func(sessCtx mongo.SessionContext) error {
+		if err := s.archiveConfigVersionWithSession(sessCtx, existing, userID); err != nil {
+			return fmt.Errorf("failed to archive config version: %v", err)
 		}
-	}
 
-	return handlers.ServiceResponse{
-		StatusCode: http.StatusOK,
-		Data:       updatedConfig.ToResponse(),
+		// The archive is rolled back with the transaction when the guard fails
+		err := s.configs.FindOneAndUpdate(sessCtx,
+			bson.M{"_id": existing.ID, "updated_at": updatedAt},
+			bson.M{"$set": updates},
+			options.FindOneAndUpdate().SetReturnDocument(options.After),
+		).Decode(&updatedConfig)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return errConfigChanged
+		}
+		if err != nil {
+			return fmt.Errorf("failed to update config: %v", err)
+		}
+		return nil
+	})
+	if err == nil {
+		s.recordChange(ctx, existing.TenantID, existing.ID)
 	}
+	return updatedConfig, err
 }
 
 // DeleteConfig deletes a config by its ID and all its archives with transaction support
@@ -485,8 +880,11 @@ func (s *ConfigService) DeleteConfig(ctx context.Context, req models.ConfigIDReq
 		}
 	}
 
-	// For now, use dummy tenant ID - in a real app, this would come from JWT token
-	tenantID := "dummy-tenant-id"
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+	tenantID := principal.TenantID
 
 	// Check if config exists and belongs to tenant
 	existing, err := s.repo.FindByID(ctx, id)
@@ -510,6 +908,31 @@ func (s *ConfigService) DeleteConfig(ctx context.Context, req models.ConfigIDReq
 		}
 	}
 
+	if !principal.Can(auth.PermConfigDelete, existing.Resource()) {
+		return forbiddenResponse(auth.PermConfigDelete)
+	}
+
+	// Protected configs only lose their protected tags through a reviewed draft
+	if s.reviewPolicy.Protects(existing.Tags) {
+		return protectedOperationResponse("remove its protected tags with a reviewed draft before deleting it")
+	}
+
+	// Configs still referenced by other configs cannot be deleted
+	referencedBy, err := s.referrers(ctx, existing)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to check references: %v", err),
+		}
+	}
+	if len(referencedBy) > 0 {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusConflict,
+			Error:      "config is referenced by other configs",
+			Data:       map[string]interface{}{"referenced_by": referencedBy},
+		}
+	}
+
 	// Use transaction to ensure both archive deletion and config deletion happen atomically
 	err = s.repo.WithTransaction(ctx, func(sessCtx mongo.SessionContext) error {
 		// Delete all archives for this config first
@@ -535,6 +958,7 @@ func (s *ConfigService) DeleteConfig(ctx context.Context, req models.ConfigIDReq
 			Error:      fmt.Sprintf("transaction failed: %v", err),
 		}
 	}
+	s.recordChange(ctx, tenantID, id)
 
 	return handlers.ServiceResponse{
 		StatusCode: http.StatusOK,
@@ -553,8 +977,11 @@ func (s *ConfigService) GetConfigArchives(ctx context.Context, req models.Config
 		}
 	}
 
-	// For now, use dummy tenant ID - in a real app, this would come from JWT token
-	tenantID := "dummy-tenant-id"
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+	tenantID := principal.TenantID
 
 	// Check if config exists and belongs to tenant
 	existing, err := s.repo.FindByID(ctx, id)
@@ -578,6 +1005,10 @@ func (s *ConfigService) GetConfigArchives(ctx context.Context, req models.Config
 		}
 	}
 
+	if !principal.Can(auth.PermConfigRead, existing.Resource()) {
+		return forbiddenResponse(auth.PermConfigRead)
+	}
+
 	// Get archives for this config, ordered by version descending
 	archives, err := s.archiveRepo.Find(ctx, bson.M{
 		"config_id": id,
@@ -605,6 +1036,168 @@ func (s *ConfigService) GetConfigArchives(ctx context.Context, req models.Config
 	}
 }
 
+// RestoreConfig restores the tags and metadata of a config from one of its archives.
+// The current version is archived first, so a restore can itself be undone.
+func (s *ConfigService) RestoreConfig(ctx context.Context, req models.RestoreConfigRequest) handlers.ServiceResponse {
+	// Parse ObjectIDs
+	id, err := primitive.ObjectIDFromHex(req.ID)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      "Invalid config ID",
+		}
+	}
+	archiveID, err := primitive.ObjectIDFromHex(req.ArchiveID)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      "Invalid archive ID",
+		}
+	}
+
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+	tenantID := principal.TenantID
+	userID := principal.Subject
+
+	existing, err := s.repo.FindOne(ctx, bson.M{"_id": id, "tenant_id": tenantID})
+	if err != nil {
+		if err.Error() == "not found" {
+			return handlers.ServiceResponse{
+				StatusCode: http.StatusNotFound,
+				Error:      "Config not found",
+			}
+		}
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to get config: %v", err),
+		}
+	}
+
+	if !principal.Can(auth.PermConfigWrite, existing.Resource()) {
+		return forbiddenResponse(auth.PermConfigWrite)
+	}
+
+	// Rolling a protected config back is a change like any other
+	if s.reviewPolicy.Protects(existing.Tags) {
+		return protectedOperationResponse("propose the archived version as a draft with POST /config/drafts")
+	}
+
+	archive, err := s.archiveRepo.FindOne(ctx, bson.M{
+		"_id":       archiveID,
+		"config_id": id,
+		"tenant_id": tenantID,
+	})
+	if err != nil {
+		if err.Error() == "not found" {
+			return handlers.ServiceResponse{
+				StatusCode: http.StatusNotFound,
+				Error:      "Config archive not found",
+			}
+		}
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to get config archive: %v", err),
+		}
+	}
+
+	// The restored tags are written like any other tag update
+	if !canWriteTags(principal, existing, archive.Tags) {
+		return forbiddenResponse(auth.PermConfigWrite)
+	}
+	if s.reviewPolicy.Protects(archive.Tags) {
+		return protectedOperationResponse("propose the archived version as a draft with POST /config/drafts")
+	}
+
+	// The restored references must still resolve; encrypted fields may hold references too
+	plainMetadata := archive.Metadata
+	if plainMetadata != nil {
+		plainMetadata, err = s.registry.DecryptMetadata(ctx, tenantID, archive.Type, archive.Subtype, archive.Metadata)
+		if err != nil {
+			return handlers.ServiceResponse{
+				StatusCode: http.StatusInternalServerError,
+				Error:      fmt.Sprintf("failed to decrypt archived metadata: %v", err),
+			}
+		}
+	}
+	plainOverlays, err := s.decryptOverlays(ctx, tenantID, archive.Type, archive.Subtype, archive.Overlays)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to decrypt archived overlays: %v", err),
+		}
+	}
+	referenceKeys, problems, err := s.checkReferences(ctx, existing, plainMetadata)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to check references: %v", err),
+		}
+	}
+	overlayKeys, overlayProblems, err := s.overlayReferences(ctx, existing, plainMetadata, plainOverlays)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to check references: %v", err),
+		}
+	}
+	referenceKeys = unionKeys(referenceKeys, overlayKeys)
+	for env, envProblems := range overlayProblems {
+		for _, problem := range envProblems {
+			problems = append(problems, fmt.Sprintf("environment %s: %s", env, problem))
+		}
+	}
+	if len(problems) > 0 {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusConflict,
+			Error:      "archived version has invalid references",
+			Data:       registry.ValidationResult{Valid: false, Errors: problems},
+		}
+	}
+
+	// Archived metadata is stored encrypted already, so it is copied as is
+	updates := bson.M{
+		"tags":             archive.Tags,
+		"metadata":         archive.Metadata,
+		"overlays":         archive.Overlays,
+		"schema_version":   archive.SchemaVersion,
+		"encrypted_fields": archive.EncryptedFields,
+		"references":       referenceKeys,
+		"last_updated_by":  userID,
+	}
+
+	var restoredConfig models.Config
+
+	err = s.repo.WithTransaction(ctx, func(sessCtx mongo.SessionContext) error {
+		err := s.archiveConfigVersionWithSession(sessCtx, existing, userID)
+		if err != nil {
+			return fmt.Errorf("failed to archive config version: %v", err)
+		}
+
+		restored, err := s.repo.UpdateByID(sessCtx, id, bson.M{"$set": updates})
+		if err != nil {
+			return fmt.Errorf("failed to restore config: %v", err)
+		}
+		restoredConfig = restored
+		return nil
+	})
+
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("transaction failed: %v", err),
+		}
+	}
+	s.recordChange(ctx, existing.TenantID, existing.ID)
+
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusOK,
+		Data:       restoredConfig.ToResponse(),
+	}
+}
+
 // archiveConfigVersion archives the current version of a config
 // func (s *ConfigService) archiveConfigVersion(ctx context.Context, config models.Config, archivedBy string) error {
 // 	// Get current version number
@@ -650,32 +1243,29 @@ func (s *ConfigService) GetConfigArchives(ctx context.Context, req models.Config
 
 // archiveConfigVersionWithSession archives the current version of a config within a session
 func (s *ConfigService) archiveConfigVersionWithSession(sessCtx mongo.SessionContext, config models.Config, archivedBy string) error {
-	// Get current version number
-	currentVersion, err := s.archiveRepo.Count(sessCtx, bson.M{"config_id": config.ID})
-	if err != nil {
+	// Versions keep counting after old archives are pruned: the next one follows the latest
+	var latest models.ConfigArchive
+	err := s.archives.FindOne(sessCtx, bson.M{"config_id": config.ID},
+		options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}})).Decode(&latest)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
 		return err
 	}
 
 	// Create archive entry
-	archive := config.ToArchive(int(currentVersion)+1, archivedBy)
+	archive := config.ToArchive(latest.Version+1, archivedBy)
 	_, err = s.archiveRepo.InsertOne(sessCtx, archive)
 	if err != nil {
 		return err
 	}
 
-	// Check if we need to remove oldest archive (keep only MaxArchiveHistory)
-	// Since we just added one archive, if currentVersion + 1 > MaxArchiveHistory, we need to remove the oldest
-	if currentVersion+1 > MaxArchiveHistory {
-		// Find the oldest archive (lowest version)
-		oldestArchive, err := s.archiveRepo.FindOne(sessCtx, bson.M{
-			"config_id": config.ID,
-		})
-		if err != nil {
-			return err
-		}
-
-		// Delete the oldest archive
-		_, err = s.archiveRepo.DeleteOne(sessCtx, bson.M{"_id": oldestArchive.ID})
+	// Keep only MaxArchiveHistory archives, removing the lowest versions first
+	count, err := s.archiveRepo.Count(sessCtx, bson.M{"config_id": config.ID})
+	if err != nil {
+		return err
+	}
+	for ; count > MaxArchiveHistory; count-- {
+		err := s.archives.FindOneAndDelete(sessCtx, bson.M{"config_id": config.ID},
+			options.FindOneAndDelete().SetSort(bson.D{{Key: "version", Value: 1}})).Err()
 		if err != nil {
 			return err
 		}
@@ -684,6 +1274,24 @@ func (s *ConfigService) archiveConfigVersionWithSession(sessCtx mongo.SessionCon
 	return nil
 }
 
+// configVersions returns the archived versions of a config sorted by version and
+// the number of its current version, the one after the last archived version
+func (s *ConfigService) configVersions(ctx context.Context, config models.Config) ([]models.ConfigArchive, int, error) {
+	archives, err := s.archiveRepo.Find(ctx, bson.M{"config_id": config.ID}, 0, 0)
+	if err != nil {
+		return nil, 0, err
+	}
+	sort.Slice(archives, func(i, j int) bool {
+		return archives[i].Version < archives[j].Version
+	})
+
+	current := 1
+	if len(archives) > 0 {
+		current = archives[len(archives)-1].Version + 1
+	}
+	return archives, current, nil
+}
+
 // deleteAllArchivesByConfigIDWithSession deletes all archives for a specific config ID within a session
 func (s *ConfigService) deleteAllArchivesByConfigIDWithSession(sessCtx mongo.SessionContext, configID primitive.ObjectID) error {
 	// Delete all archives for this config
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"makatom-api-config/internal/auth"
+	"makatom-api-config/internal/models"
+	"makatom/common/pkg/handlers"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// configChange is a change stream event of the configs collection
+type configChange struct {
+	OperationType string `bson:"operationType"`
+	DocumentKey   struct {
+		ID primitive.ObjectID `bson:"_id"`
+	} `bson:"documentKey"`
+	FullDocument             *models.Config `bson:"fullDocument"`
+	FullDocumentBeforeChange *models.Config `bson:"fullDocumentBeforeChange"`
+	WallTime                 time.Time      `bson:"wallTime"`
+}
+
+// WatchConfigs passes the changes of the configs matching the query that the
+// caller may read to emit, from the time of the call until ctx is done or emit
+// fails. Deletions are only seen when the collection records pre-images.
+func (s *ConfigService) WatchConfigs(ctx context.Context, query models.ConfigQuery, emit func(models.ConfigEvent) error) *handlers.ServiceResponse {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		resp := unauthorizedResponse()
+		return &resp
+	}
+	if _, allowed := principal.ScopeFilter(auth.PermConfigRead); !allowed {
+		resp := forbiddenResponse(auth.PermConfigRead)
+		return &resp
+	}
+	if err := validateNamespace(query.Namespace); err != nil {
+		return &handlers.ServiceResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      err.Error(),
+		}
+	}
+
+	pipeline := mongo.Pipeline{{{Key: "$match", Value: bson.M{
+		"operationType": bson.M{"$in": []string{"insert", "update", "replace", "delete"}},
+		"$or": []bson.M{
+			{"fullDocument.tenant_id": principal.TenantID},
+			{"fullDocumentBeforeChange.tenant_id": principal.TenantID},
+		},
+	}}}}
+	opts := options.ChangeStream().
+		SetFullDocument(options.UpdateLookup).
+		SetFullDocumentBeforeChange(options.WhenAvailable)
+
+	stream, err := s.configs.Watch(ctx, pipeline, opts)
+	if err != nil {
+		return &handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to watch configs: %v", err),
+		}
+	}
+	defer stream.Close(context.Background())
+
+	for stream.Next(ctx) {
+		var change configChange
+		if err := stream.Decode(&change); err != nil {
+			return &handlers.ServiceResponse{
+				StatusCode: http.StatusInternalServerError,
+				Error:      fmt.Sprintf("failed to decode config change: %v", err),
+			}
+		}
+
+		kind, config := models.ConfigEventUpdated, change.FullDocument
+		switch change.OperationType {
+		case "insert":
+			kind = models.ConfigEventCreated
+		case "delete":
+			kind, config = models.ConfigEventDeleted, change.FullDocumentBeforeChange
+		}
+		// The config was deleted before an update could be looked up, or its
+		// pre-image was not recorded
+		if config == nil || config.TenantID != principal.TenantID || !watchMatches(*config, query) {
+			continue
+		}
+		if !principal.Can(auth.PermConfigRead, config.Resource()) {
+			continue
+		}
+
+		decrypted, err := s.decryptForReader(ctx, principal, *config)
+		if err != nil {
+			return &handlers.ServiceResponse{
+				StatusCode: http.StatusInternalServerError,
+				Error:      fmt.Sprintf("config %s: %v", config.ID.Hex(), err),
+			}
+		}
+		occurredAt := change.WallTime
+		if occurredAt.IsZero() {
+			occurredAt = time.Now()
+		}
+
+		if err := emit(models.ConfigEvent{
+			Kind:       kind,
+			ConfigID:   change.DocumentKey.ID.Hex(),
+			Config:     decrypted.ToResponse(),
+			OccurredAt: occurredAt,
+		}); err != nil {
+			return nil
+		}
+	}
+
+	if err := stream.Err(); err != nil && ctx.Err() == nil {
+		return &handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("config change stream failed: %v", err),
+		}
+	}
+	return nil
+}
+
+// watchMatches reports whether a config matches the fields of a watch query. The
+// namespace is matched like namespaceFilter: an empty one selects the root namespace.
+func watchMatches(config models.Config, query models.ConfigQuery) bool {
+	return (query.Name == "" || config.Name == query.Name) &&
+		config.Namespace == query.Namespace &&
+		(query.Type == "" || config.Type == query.Type) &&
+		(query.Subtype == "" || config.Subtype == query.Subtype) &&
+		(query.Tag == "" || slices.Contains(config.Tags, query.Tag))
+}
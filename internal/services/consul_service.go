@@ -0,0 +1,276 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"makatom-api-config/internal/auth"
+	"makatom-api-config/internal/models"
+	"makatom/common/pkg/handlers"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	// consulDefaultWait and ConsulMaxWait bound blocking queries like Consul does
+	consulDefaultWait = 5 * time.Minute
+	ConsulMaxWait     = 10 * time.Minute
+)
+
+// consulKeyFields are the config fields addressed by the leading key segments
+var consulKeyFields = []string{"type", "subtype", "name"}
+
+// ConsulService serves configs through a read-only Consul KV compatible API.
+// Keys are type/subtype/name for a whole config, whose value is its metadata
+// as JSON, followed by a field path for a single metadata value. Configs
+// without a subtype have no key. Indexes derive from the config timestamps.
+type ConsulService struct {
+	configService *ConfigService
+}
+
+// NewConsulService creates a new ConsulService instance
+func NewConsulService(configService *ConfigService) *ConsulService {
+	return &ConsulService{configService: configService}
+}
+
+// consulRead is the outcome of reading a key or prefix
+type consulRead struct {
+	pairs []models.ConsulKVPair
+	index uint64
+}
+
+// GetKV serves GET /v1/kv/{key...}. With index set, it blocks until the result
+// changes past that index or the wait time elapses, then answers anyway.
+func (s *ConsulService) GetKV(ctx context.Context, req models.ConsulKVRequest) handlers.ServiceResponse {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+
+	if err := validateNamespace(req.Namespace); err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      err.Error(),
+		}
+	}
+	wait, err := consulWait(req.Wait)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      err.Error(),
+		}
+	}
+
+	// Read the tenant change state first, so a change during the read still wakes us
+	state, err := s.configService.changeState(ctx, principal.TenantID)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to get change index: %v", err),
+		}
+	}
+
+	result, errResp := s.read(ctx, principal, req, state)
+	if errResp != nil {
+		return *errResp
+	}
+
+	// Re-read only when the tenant changed; most changes leave this key as it was
+	deadline := time.Now().Add(wait)
+	for req.Index > 0 && result.index <= uint64(req.Index) {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			break
+		}
+		next, err := s.configService.WaitForChange(ctx, principal.TenantID, state.Index, remaining)
+		if err != nil {
+			return handlers.ServiceResponse{
+				StatusCode: http.StatusInternalServerError,
+				Error:      fmt.Sprintf("failed to wait for changes: %v", err),
+			}
+		}
+		if next <= state.Index {
+			break
+		}
+
+		if state, err = s.configService.changeState(ctx, principal.TenantID); err != nil {
+			return handlers.ServiceResponse{
+				StatusCode: http.StatusInternalServerError,
+				Error:      fmt.Sprintf("failed to get change index: %v", err),
+			}
+		}
+		if result, errResp = s.read(ctx, principal, req, state); errResp != nil {
+			return *errResp
+		}
+	}
+
+	return consulResponse(req, result)
+}
+
+// read loads the pairs of a key, or of every key under a prefix with recurse or keys.
+// Deleted keys leave no timestamp behind, so the index of a prefix or of a missing
+// key is at least the time of the tenant's last change, read before the configs.
+func (s *ConsulService) read(ctx context.Context, principal *auth.Principal, req models.ConsulKVRequest, state changeIndexDocument) (consulRead, *handlers.ServiceResponse) {
+	filter, allowed := configFilter(principal, models.ConfigQuery{})
+	if !allowed {
+		resp := forbiddenResponse(auth.PermConfigRead)
+		return consulRead{}, &resp
+	}
+	filter["namespace"] = namespaceFilter(req.Namespace)
+
+	prefix := req.Recurse || req.Keys
+	segments := strings.Split(req.Key, "/")
+	for i, segment := range segments {
+		if i == len(consulKeyFields) {
+			break
+		}
+		// The last segment of a prefix may be partial
+		if prefix && i == len(segments)-1 {
+			if segment != "" {
+				filter[consulKeyFields[i]] = bson.M{"$regex": "^" + regexp.QuoteMeta(segment)}
+			}
+			break
+		}
+		filter[consulKeyFields[i]] = segment
+	}
+	if !prefix && len(segments) < len(consulKeyFields) {
+		return consulRead{index: 1}, nil
+	}
+
+	configs, err := s.configService.repo.Find(ctx, filter, 0, 0)
+	if err != nil {
+		return consulRead{}, &handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to get configs: %v", err),
+		}
+	}
+
+	result := consulRead{index: 1}
+	for _, config := range configs {
+		if config.Subtype == "" {
+			continue
+		}
+		config, err = s.configService.decryptForReader(ctx, principal, config)
+		if err != nil {
+			return consulRead{}, &handlers.ServiceResponse{
+				StatusCode: http.StatusInternalServerError,
+				Error:      fmt.Sprintf("config %s: %v", config.ID.Hex(), err),
+			}
+		}
+
+		for _, pair := range consulPairs(config) {
+			if (prefix && strings.HasPrefix(pair.Key, req.Key)) || pair.Key == req.Key {
+				result.pairs = append(result.pairs, pair)
+				result.index = max(result.index, pair.ModifyIndex)
+			}
+		}
+	}
+	sort.Slice(result.pairs, func(i, j int) bool {
+		return result.pairs[i].Key < result.pairs[j].Key
+	})
+
+	if (prefix || len(result.pairs) == 0) && !state.ChangedAt.IsZero() {
+		result.index = max(result.index, uint64(state.ChangedAt.UnixMilli()))
+	}
+	return result, nil
+}
+
+// consulPairs returns the pairs of a config: the whole metadata under the
+// config key and every leaf value under its field path
+func consulPairs(config models.Config) []models.ConsulKVPair {
+	key := config.Type + "/" + config.Subtype + "/" + config.Name
+	createIndex := uint64(config.CreatedAt.UnixMilli())
+	modifyIndex := uint64(config.UpdatedAt.UnixMilli())
+
+	pair := func(key string, value interface{}) models.ConsulKVPair {
+		return models.ConsulKVPair{
+			Key:         key,
+			Value:       consulValue(value),
+			CreateIndex: createIndex,
+			ModifyIndex: modifyIndex,
+		}
+	}
+
+	pairs := []models.ConsulKVPair{pair(key, config.Metadata)}
+	var walk func(path string, value interface{})
+	walk = func(path string, value interface{}) {
+		if nested, ok := value.(map[string]interface{}); ok && len(nested) > 0 {
+			for field, item := range nested {
+				walk(path+"/"+field, item)
+			}
+			return
+		}
+		pairs = append(pairs, pair(path, value))
+	}
+	for field, value := range config.Metadata {
+		walk(key+"/"+field, models.PlainValue(value))
+	}
+	return pairs
+}
+
+// consulValue encodes a value: strings as their bytes, anything else as JSON
+func consulValue(value interface{}) []byte {
+	if text, ok := value.(string); ok {
+		return []byte(text)
+	}
+	encoded, err := json.Marshal(models.PlainValue(value))
+	if err != nil {
+		return nil
+	}
+	return encoded
+}
+
+// consulWait parses the wait parameter of a blocking query
+func consulWait(raw string) (time.Duration, error) {
+	if raw == "" {
+		return consulDefaultWait, nil
+	}
+	wait, err := time.ParseDuration(raw)
+	if err != nil || wait <= 0 {
+		return 0, fmt.Errorf("invalid wait %q", raw)
+	}
+	return min(wait, ConsulMaxWait), nil
+}
+
+// consulResponse answers in Consul's format: 404 without a body when nothing
+// matches, the raw value with raw, key names with keys and pairs otherwise
+func consulResponse(req models.ConsulKVRequest, result consulRead) handlers.ServiceResponse {
+	raw := models.RawResponse{
+		ContentType: "application/json",
+		Headers: map[string]string{
+			"X-Consul-Index":       strconv.FormatUint(result.index, 10),
+			"X-Consul-KnownLeader": "true",
+			"X-Consul-LastContact": "0",
+		},
+	}
+
+	status := http.StatusOK
+	switch {
+	case len(result.pairs) == 0:
+		status = http.StatusNotFound
+		raw.ContentType = ""
+	case req.Keys:
+		keys := make([]string, len(result.pairs))
+		for i, pair := range result.pairs {
+			keys[i] = pair.Key
+		}
+		raw.Body, _ = json.Marshal(keys)
+	case req.Raw && !req.Recurse:
+		raw.ContentType = "text/plain; charset=utf-8"
+		raw.Body = result.pairs[0].Value
+	default:
+		raw.Body, _ = json.Marshal(result.pairs)
+	}
+
+	return handlers.ServiceResponse{
+		StatusCode: status,
+		Data:       raw,
+	}
+}
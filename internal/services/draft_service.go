@@ -0,0 +1,818 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"makatom-api-config/internal/auth"
+	"makatom-api-config/internal/models"
+	"makatom-api-config/internal/registry"
+	"makatom/common/pkg/database/mongodb"
+	"makatom/common/pkg/handlers"
+	"makatom/common/pkg/types"
+)
+
+// draftPublishLease is how long a publish may hold its claim on a draft before
+// the draft can be published again, e.g. after a crash mid-publish
+const draftPublishLease = 5 * time.Minute
+
+// ReviewPolicy decides which configs can only change through approved drafts and
+// how many approvals a draft needs
+type ReviewPolicy struct {
+	// ProtectedTags marks configs that can only be changed by publishing a draft
+	ProtectedTags []string
+	// Approvals is the number of approvals a draft needs
+	Approvals int
+	// ProtectedApprovals applies to drafts of protected configs and drafts changing encrypted fields
+	ProtectedApprovals int
+}
+
+// ReviewPolicyFromEnv reads the review policy from CONFIG_PROTECTED_TAGS (comma
+// separated), CONFIG_DRAFT_APPROVALS (default 1) and CONFIG_PROTECTED_APPROVALS (default 2)
+func ReviewPolicyFromEnv() (ReviewPolicy, error) {
+	policy := ReviewPolicy{Approvals: 1, ProtectedApprovals: 2}
+	for _, tag := range strings.Split(os.Getenv("CONFIG_PROTECTED_TAGS"), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			policy.ProtectedTags = append(policy.ProtectedTags, tag)
+		}
+	}
+
+	for name, target := range map[string]*int{
+		"CONFIG_DRAFT_APPROVALS":     &policy.Approvals,
+		"CONFIG_PROTECTED_APPROVALS": &policy.ProtectedApprovals,
+	} {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return ReviewPolicy{}, fmt.Errorf("%s must be a positive number, got %q", name, value)
+		}
+		*target = n
+	}
+	return policy, nil
+}
+
+// Protects reports whether a config with the given tags can only change through drafts
+func (p ReviewPolicy) Protects(tags []string) bool {
+	for _, tag := range tags {
+		if slices.Contains(p.ProtectedTags, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// requiredApprovals returns the number of approvals a draft needs
+func (p ReviewPolicy) requiredApprovals(protected, secretChange bool) int {
+	if protected || secretChange {
+		return max(p.Approvals, p.ProtectedApprovals)
+	}
+	return p.Approvals
+}
+
+// protectedResponse is returned when a protected config is changed directly
+func protectedResponse() handlers.ServiceResponse {
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusConflict,
+		Error:      "config is protected: propose the change as a draft with POST /config/drafts",
+	}
+}
+
+// protectedOperationResponse is returned when a protected config would be
+// deleted or restored directly; hint says how to go through review instead
+func protectedOperationResponse(hint string) handlers.ServiceResponse {
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusConflict,
+		Error:      "config is protected: " + hint,
+	}
+}
+
+// DraftService handles proposed config changes and their review
+type DraftService struct {
+	configService *ConfigService
+	repo          *mongodb.MongoRepository[models.ConfigDraft]
+	drafts        *mongo.Collection
+	registry      *registry.Registry
+}
+
+// NewDraftService creates a new DraftService instance
+func NewDraftService(configService *ConfigService, draftCollection *mongo.Collection, typeRegistry *registry.Registry) *DraftService {
+	return &DraftService{
+		configService: configService,
+		repo:          mongodb.NewMongoRepository[models.ConfigDraft](draftCollection),
+		drafts:        draftCollection,
+		registry:      typeRegistry,
+	}
+}
+
+// CreateDraft stores a proposed change to a config. The change is validated like
+// an update but only applied when an approved draft is published.
+func (s *DraftService) CreateDraft(ctx context.Context, req models.CreateDraftRequest) handlers.ServiceResponse {
+	id, err := primitive.ObjectIDFromHex(req.ID)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      "Invalid config ID",
+		}
+	}
+
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+	tenantID := principal.TenantID
+
+	config, errResp := s.findConfig(ctx, id, tenantID)
+	if errResp != nil {
+		return *errResp
+	}
+	if !principal.Can(auth.PermConfigWrite, config.Resource()) || !canWriteTags(principal, config, req.Tags) {
+		return forbiddenResponse(auth.PermConfigWrite)
+	}
+	if req.Tags == nil && req.Metadata == nil && req.Overlays == nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      "draft must change tags, metadata or overlays",
+		}
+	}
+
+	// Validate now so reviewers only see changes that could be published
+	if _, errResp := s.configService.buildUpdate(ctx, config, models.UpdateConfigWithIDRequest{
+		Tags:     req.Tags,
+		Metadata: req.Metadata,
+		Overlays: req.Overlays,
+	}); errResp != nil {
+		return *errResp
+	}
+
+	draft := models.ConfigDraft{
+		Base:          &types.Base{},
+		ConfigID:      config.ID,
+		TenantID:      tenantID,
+		Name:          config.Name,
+		Namespace:     config.Namespace,
+		Type:          config.Type,
+		Subtype:       config.Subtype,
+		ConfigTags:    config.Tags,
+		Tags:          req.Tags,
+		Metadata:      req.Metadata,
+		Overlays:      req.Overlays,
+		Comment:       req.Comment,
+		BaseUpdatedAt: config.UpdatedAt,
+		Status:        models.DraftStatusPending,
+		Reviews:       []models.DraftReview{},
+		CreatedBy:     principal.Subject,
+	}
+
+	diff, err := s.diff(ctx, config, draft)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to compare draft: %v", err),
+		}
+	}
+	if len(diff) == 0 {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      "draft does not change the config",
+		}
+	}
+
+	encryptedFields, err := s.encryptedFields(ctx, config)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to get config schema: %v", err),
+		}
+	}
+	for _, change := range diff {
+		if touchesEncryptedField(change.Path, encryptedFields) {
+			draft.SecretChange = true
+			break
+		}
+	}
+	policy := s.configService.reviewPolicy
+	draft.RequiredApprovals = policy.requiredApprovals(policy.Protects(config.Tags) || policy.Protects(req.Tags), draft.SecretChange)
+
+	// Proposed metadata is stored encrypted like the config itself
+	if draft.Metadata != nil {
+		draft.Metadata, err = s.registry.EncryptMetadata(ctx, tenantID, config.Type, config.Subtype, draft.Metadata)
+	}
+	if err == nil && draft.Overlays != nil {
+		draft.Overlays, err = s.configService.encryptOverlays(ctx, tenantID, config.Type, config.Subtype, draft.Overlays)
+	}
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to encrypt metadata: %v", err),
+		}
+	}
+
+	created, err := s.repo.InsertOne(ctx, draft)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to create draft: %v", err),
+		}
+	}
+
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusCreated,
+		Data:       draftResponse(created, config, redactDiff(principal, config, diff, encryptedFields)),
+	}
+}
+
+// GetDrafts lists the drafts of the configs the caller may read
+func (s *DraftService) GetDrafts(ctx context.Context, query models.DraftQuery) handlers.ServiceResponse {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+
+	scopeFilter, allowed := principal.ScopeFilter(auth.PermConfigRead)
+	if !allowed {
+		return forbiddenResponse(auth.PermConfigRead)
+	}
+	filter := bson.M{"tenant_id": principal.TenantID}
+	for key, value := range scopeFilter {
+		filter[key] = value
+	}
+	if query.ConfigID != "" {
+		configID, err := primitive.ObjectIDFromHex(query.ConfigID)
+		if err != nil {
+			return handlers.ServiceResponse{
+				StatusCode: http.StatusBadRequest,
+				Error:      "Invalid config ID",
+			}
+		}
+		filter["config_id"] = configID
+	}
+	if query.Status != "" {
+		filter["status"] = query.Status
+	}
+
+	total, err := s.repo.Count(ctx, filter)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to count drafts: %v", err),
+		}
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = 10
+	}
+	drafts, err := s.repo.Find(ctx, filter, query.Skip, limit)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to get drafts: %v", err),
+		}
+	}
+
+	responses := make([]models.DraftResponse, len(drafts))
+	for i, draft := range drafts {
+		responses[i] = models.DraftResponse{ConfigDraft: draft, Approvals: draft.Approvals()}
+	}
+
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusOK,
+		Data: map[string]interface{}{
+			"drafts": responses,
+			"total":  total,
+			"limit":  query.Limit,
+			"skip":   query.Skip,
+		},
+	}
+}
+
+// GetDraft returns a draft with its diff against the current config
+func (s *DraftService) GetDraft(ctx context.Context, req models.DraftIDRequest) handlers.ServiceResponse {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+
+	draft, errResp := s.findDraft(ctx, req.ID, principal.TenantID)
+	if errResp != nil {
+		return *errResp
+	}
+	if !principal.Can(auth.PermConfigRead, draft.Resource()) {
+		return forbiddenResponse(auth.PermConfigRead)
+	}
+
+	config, errResp := s.findConfig(ctx, draft.ConfigID, principal.TenantID)
+	if errResp != nil {
+		return *errResp
+	}
+
+	plain, err := s.plainDraft(ctx, draft)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to decrypt draft: %v", err),
+		}
+	}
+	diff, err := s.diff(ctx, config, plain)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to compare draft: %v", err),
+		}
+	}
+	encryptedFields, err := s.encryptedFields(ctx, config)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to get config schema: %v", err),
+		}
+	}
+
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusOK,
+		Data:       draftResponse(draft, config, redactDiff(principal, config, diff, encryptedFields)),
+	}
+}
+
+// ApproveDraft records an approval; the draft is approved once it has the required number
+func (s *DraftService) ApproveDraft(ctx context.Context, req models.ReviewDraftRequest) handlers.ServiceResponse {
+	return s.review(ctx, req, models.DraftDecisionApprove)
+}
+
+// RejectDraft rejects a draft; rejected drafts cannot be published
+func (s *DraftService) RejectDraft(ctx context.Context, req models.ReviewDraftRequest) handlers.ServiceResponse {
+	return s.review(ctx, req, models.DraftDecisionReject)
+}
+
+func (s *DraftService) review(ctx context.Context, req models.ReviewDraftRequest, decision string) handlers.ServiceResponse {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+
+	draft, errResp := s.findDraft(ctx, req.ID, principal.TenantID)
+	if errResp != nil {
+		return *errResp
+	}
+	if !principal.Can(auth.PermConfigReview, draft.Resource()) {
+		return forbiddenResponse(auth.PermConfigReview)
+	}
+	// Reviewers of encrypted-field changes must be able to see the values
+	if draft.SecretChange && !principal.Can(auth.PermSecretRead, draft.Resource()) {
+		return forbiddenResponse(auth.PermSecretRead)
+	}
+	if draft.CreatedBy == principal.Subject {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusForbidden,
+			Error:      "authors cannot review their own drafts",
+		}
+	}
+	if draft.Status != models.DraftStatusPending {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusConflict,
+			Error:      fmt.Sprintf("draft is %s", draft.Status),
+		}
+	}
+	for _, review := range draft.Reviews {
+		if review.Reviewer == principal.Subject {
+			return handlers.ServiceResponse{
+				StatusCode: http.StatusConflict,
+				Error:      "draft already reviewed by this reviewer",
+			}
+		}
+	}
+
+	// Reviews are added atomically and only to a pending draft, so concurrent
+	// reviews cannot overwrite each other or approve a rejected draft
+	update := bson.M{"$push": bson.M{"reviews": models.DraftReview{
+		Reviewer:   principal.Subject,
+		Decision:   decision,
+		Comment:    req.Comment,
+		ReviewedAt: time.Now(),
+	}}}
+	if decision == models.DraftDecisionReject {
+		update["$set"] = bson.M{"status": models.DraftStatusRejected}
+	}
+	updated, err := s.updateDraft(ctx, bson.M{
+		"_id":              draft.ID,
+		"status":           models.DraftStatusPending,
+		"reviews.reviewer": bson.M{"$ne": principal.Subject},
+	}, update)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusConflict,
+			Error:      "draft is no longer pending or was already reviewed by this reviewer",
+		}
+	}
+	if err == nil && updated.Status == models.DraftStatusPending && updated.Approvals() >= updated.RequiredApprovals {
+		// A reject recorded in between keeps the draft rejected
+		updated, err = s.updateDraft(ctx, bson.M{
+			"_id":    draft.ID,
+			"status": models.DraftStatusPending,
+		}, bson.M{"$set": bson.M{"status": models.DraftStatusApproved}})
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			updated, err = s.repo.FindByID(ctx, draft.ID)
+		}
+	}
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to review draft: %v", err),
+		}
+	}
+
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusOK,
+		Data:       models.DraftResponse{ConfigDraft: updated, Approvals: updated.Approvals()},
+	}
+}
+
+// updateDraft applies update to the draft matching filter and returns the
+// updated draft, or mongo.ErrNoDocuments when none matches
+func (s *DraftService) updateDraft(ctx context.Context, filter, update bson.M) (models.ConfigDraft, error) {
+	var updated models.ConfigDraft
+	err := s.drafts.FindOneAndUpdate(ctx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&updated)
+	return updated, err
+}
+
+// PublishDraft applies an approved draft through the archive-then-update transaction
+// of UpdateConfig. Drafts proposed before the config last changed must be re-proposed.
+// The draft is claimed first and the config only updated while unchanged since the
+// draft was proposed, so concurrent publishes and updates cannot both apply.
+func (s *DraftService) PublishDraft(ctx context.Context, req models.DraftIDRequest) handlers.ServiceResponse {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+	tenantID := principal.TenantID
+
+	draft, errResp := s.findDraft(ctx, req.ID, tenantID)
+	if errResp != nil {
+		return *errResp
+	}
+	if !principal.Can(auth.PermConfigWrite, draft.Resource()) {
+		return forbiddenResponse(auth.PermConfigWrite)
+	}
+	if draft.Status != models.DraftStatusApproved {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusConflict,
+			Error:      fmt.Sprintf("only approved drafts can be published, draft is %s", draft.Status),
+		}
+	}
+
+	// Claim the draft; a claim left behind by a crashed publish expires
+	now := time.Now()
+	draft, err := s.updateDraft(ctx,
+		bson.M{
+			"_id": draft.ID,
+			"$or": []bson.M{
+				{"status": models.DraftStatusApproved},
+				{"status": models.DraftStatusPublishing, "claimed_at": bson.M{"$lt": now.Add(-draftPublishLease)}},
+			},
+		},
+		bson.M{"$set": bson.M{"status": models.DraftStatusPublishing, "claimed_at": now}},
+	)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusConflict,
+			Error:      "draft is already being published or is no longer approved",
+		}
+	}
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to claim draft: %v", err),
+		}
+	}
+
+	published, errResp := s.publishClaimed(ctx, principal, draft)
+	if errResp != nil {
+		// Hand the draft back so it can be published once the problem is fixed
+		if _, err := s.updateDraft(ctx,
+			bson.M{"_id": draft.ID, "status": models.DraftStatusPublishing, "claimed_at": now},
+			bson.M{"$set": bson.M{"status": models.DraftStatusApproved}, "$unset": bson.M{"claimed_at": ""}},
+		); err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			log.Printf("drafts: failed to release draft %s: %v", draft.ID.Hex(), err)
+		}
+		return *errResp
+	}
+
+	if _, err := s.updateDraft(ctx,
+		bson.M{"_id": draft.ID, "status": models.DraftStatusPublishing},
+		bson.M{"$set": bson.M{
+			"status":       models.DraftStatusPublished,
+			"published_by": principal.Subject,
+			"published_at": time.Now(),
+		}},
+	); err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("config updated but failed to mark draft published: %v", err),
+		}
+	}
+
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusOK,
+		Data:       published.ToResponse(),
+	}
+}
+
+// publishClaimed validates a claimed draft against the current config and applies
+// it, provided the config is unchanged since the draft was proposed
+func (s *DraftService) publishClaimed(ctx context.Context, principal *auth.Principal, draft models.ConfigDraft) (models.Config, *handlers.ServiceResponse) {
+	changed := &handlers.ServiceResponse{
+		StatusCode: http.StatusConflict,
+		Error:      "config changed since the draft was proposed",
+	}
+
+	config, errResp := s.findConfig(ctx, draft.ConfigID, draft.TenantID)
+	if errResp != nil {
+		return models.Config{}, errResp
+	}
+	if !config.UpdatedAt.Equal(draft.BaseUpdatedAt) {
+		return models.Config{}, changed
+	}
+	if !canWriteTags(principal, config, draft.Tags) {
+		resp := forbiddenResponse(auth.PermConfigWrite)
+		return models.Config{}, &resp
+	}
+
+	plain, err := s.plainDraft(ctx, draft)
+	if err != nil {
+		return models.Config{}, &handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to decrypt draft: %v", err),
+		}
+	}
+
+	// Validate again, referenced configs may have changed since the draft was proposed
+	updates, errResp := s.configService.buildUpdate(ctx, config, models.UpdateConfigWithIDRequest{
+		Tags:     plain.Tags,
+		Metadata: plain.Metadata,
+		Overlays: plain.Overlays,
+	})
+	if errResp != nil {
+		return models.Config{}, errResp
+	}
+
+	published, err := s.configService.applyUpdateUnchanged(ctx, config, updates, principal.Subject, draft.BaseUpdatedAt)
+	if errors.Is(err, errConfigChanged) {
+		return models.Config{}, changed
+	}
+	if err != nil {
+		return models.Config{}, &handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("transaction failed: %v", err),
+		}
+	}
+	return published, nil
+}
+
+// WithdrawDraft lets the author abandon a draft that has not been published
+func (s *DraftService) WithdrawDraft(ctx context.Context, req models.DraftIDRequest) handlers.ServiceResponse {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+
+	draft, errResp := s.findDraft(ctx, req.ID, principal.TenantID)
+	if errResp != nil {
+		return *errResp
+	}
+	if draft.CreatedBy != principal.Subject {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusForbidden,
+			Error:      "only the author can withdraw a draft",
+		}
+	}
+	if draft.Status != models.DraftStatusPending && draft.Status != models.DraftStatusApproved {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusConflict,
+			Error:      fmt.Sprintf("draft is %s", draft.Status),
+		}
+	}
+
+	// A publish may claim the draft in the meantime
+	updated, err := s.updateDraft(ctx,
+		bson.M{"_id": draft.ID, "status": bson.M{"$in": []string{models.DraftStatusPending, models.DraftStatusApproved}}},
+		bson.M{"$set": bson.M{"status": models.DraftStatusWithdrawn}},
+	)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusConflict,
+			Error:      "draft is no longer pending or approved",
+		}
+	}
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to withdraw draft: %v", err),
+		}
+	}
+
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusOK,
+		Data:       models.DraftResponse{ConfigDraft: updated, Approvals: updated.Approvals()},
+	}
+}
+
+// findDraft loads a draft of the tenant or returns the error response
+func (s *DraftService) findDraft(ctx context.Context, hexID, tenantID string) (models.ConfigDraft, *handlers.ServiceResponse) {
+	id, err := primitive.ObjectIDFromHex(hexID)
+	if err != nil {
+		return models.ConfigDraft{}, &handlers.ServiceResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      "Invalid draft ID",
+		}
+	}
+
+	draft, err := s.repo.FindOne(ctx, bson.M{"_id": id, "tenant_id": tenantID})
+	if err != nil {
+		if err.Error() == "not found" {
+			return models.ConfigDraft{}, &handlers.ServiceResponse{
+				StatusCode: http.StatusNotFound,
+				Error:      "Draft not found",
+			}
+		}
+		return models.ConfigDraft{}, &handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to get draft: %v", err),
+		}
+	}
+	return draft, nil
+}
+
+// findConfig loads a config of the tenant or returns the error response
+func (s *DraftService) findConfig(ctx context.Context, id primitive.ObjectID, tenantID string) (models.Config, *handlers.ServiceResponse) {
+	config, err := s.configService.repo.FindOne(ctx, bson.M{"_id": id, "tenant_id": tenantID})
+	if err != nil {
+		if err.Error() == "not found" {
+			return models.Config{}, &handlers.ServiceResponse{
+				StatusCode: http.StatusNotFound,
+				Error:      "Config not found",
+			}
+		}
+		return models.Config{}, &handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to get config: %v", err),
+		}
+	}
+	return config, nil
+}
+
+// plainDraft returns the draft with its proposed metadata and overlays decrypted
+func (s *DraftService) plainDraft(ctx context.Context, draft models.ConfigDraft) (models.ConfigDraft, error) {
+	var err error
+	if draft.Metadata != nil {
+		draft.Metadata, err = s.registry.DecryptMetadata(ctx, draft.TenantID, draft.Type, draft.Subtype, draft.Metadata)
+		if err != nil {
+			return draft, err
+		}
+	}
+	if draft.Overlays != nil {
+		draft.Overlays, err = s.configService.decryptOverlays(ctx, draft.TenantID, draft.Type, draft.Subtype, draft.Overlays)
+	}
+	return draft, err
+}
+
+// encryptedFields returns the fields of the config's subtype marked for encryption
+func (s *DraftService) encryptedFields(ctx context.Context, config models.Config) ([]string, error) {
+	schemaInfo, err := s.registry.Schema(ctx, config.TenantID, config.Type, config.Subtype)
+	if err != nil {
+		return nil, err
+	}
+	return schemaInfo.EncryptedFields, nil
+}
+
+// diff compares the decrypted config with the decrypted proposal of a draft.
+// Only the parts the draft replaces are compared.
+func (s *DraftService) diff(ctx context.Context, config models.Config, draft models.ConfigDraft) ([]models.DraftChange, error) {
+	before := map[string]interface{}{}
+	after := map[string]interface{}{}
+
+	if draft.Tags != nil {
+		before["tags"] = config.Tags
+		after["tags"] = draft.Tags
+	}
+	if draft.Metadata != nil {
+		current, err := s.registry.DecryptMetadata(ctx, config.TenantID, config.Type, config.Subtype, config.Metadata)
+		if err != nil {
+			return nil, err
+		}
+		for path, value := range flattenMetadata(current) {
+			before["metadata."+path] = value
+		}
+		for path, value := range flattenMetadata(draft.Metadata) {
+			after["metadata."+path] = value
+		}
+	}
+	if draft.Overlays != nil {
+		current, err := s.configService.decryptOverlays(ctx, config.TenantID, config.Type, config.Subtype, config.Overlays)
+		if err != nil {
+			return nil, err
+		}
+		for env, overlay := range current {
+			for path, value := range flattenMetadata(overlay) {
+				before["overlays."+env+"."+path] = value
+			}
+		}
+		for env, overlay := range draft.Overlays {
+			for path, value := range flattenMetadata(overlay) {
+				after["overlays."+env+"."+path] = value
+			}
+		}
+	}
+
+	paths := make(map[string]bool, len(before)+len(after))
+	for path := range before {
+		paths[path] = true
+	}
+	for path := range after {
+		paths[path] = true
+	}
+
+	changes := make([]models.DraftChange, 0)
+	for path := range paths {
+		oldValue, hadValue := before[path]
+		newValue, hasValue := after[path]
+		change := models.DraftChange{Path: path, Before: oldValue, After: newValue}
+		switch {
+		case !hadValue:
+			change.Change = models.DraftChangeAdded
+		case !hasValue:
+			change.Change = models.DraftChangeRemoved
+		case !sameValue(oldValue, newValue):
+			change.Change = models.DraftChangeChanged
+		default:
+			continue
+		}
+		changes = append(changes, change)
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+// draftResponse builds the response of a draft with its diff against the config
+func draftResponse(draft models.ConfigDraft, config models.Config, diff []models.DraftChange) models.DraftResponse {
+	return models.DraftResponse{
+		ConfigDraft: draft,
+		Approvals:   draft.Approvals(),
+		Stale:       !config.UpdatedAt.Equal(draft.BaseUpdatedAt),
+		Diff:        diff,
+	}
+}
+
+// redactDiff hides the values of encrypted fields from callers without secret:read
+func redactDiff(principal *auth.Principal, config models.Config, diff []models.DraftChange, encryptedFields []string) []models.DraftChange {
+	if principal.Can(auth.PermSecretRead, config.Resource()) {
+		return diff
+	}
+	for i, change := range diff {
+		if touchesEncryptedField(change.Path, encryptedFields) {
+			diff[i] = models.DraftChange{Path: change.Path, Change: change.Change, Redacted: true}
+		}
+	}
+	return diff
+}
+
+// touchesEncryptedField reports whether a diff path is inside an encrypted field
+func touchesEncryptedField(path string, encryptedFields []string) bool {
+	var field string
+	switch {
+	case strings.HasPrefix(path, "metadata."):
+		field = topLevelKey(strings.TrimPrefix(path, "metadata."))
+	case strings.HasPrefix(path, "overlays."):
+		rest := strings.TrimPrefix(path, "overlays.")
+		if dot := strings.Index(rest, "."); dot >= 0 {
+			field = topLevelKey(rest[dot+1:])
+		}
+	default:
+		return false
+	}
+	return slices.Contains(encryptedFields, field)
+}
+
+// sameValue compares values by their JSON form, so BSON and JSON decoded values compare equal
+func sameValue(a, b interface{}) bool {
+	left, errLeft := json.Marshal(a)
+	right, errRight := json.Marshal(b)
+	return errLeft == nil && errRight == nil && string(left) == string(right)
+}
@@ -0,0 +1,242 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"makatom-api-config/internal/auth"
+	"makatom-api-config/internal/flags"
+	"makatom-api-config/internal/models"
+	"makatom/common/pkg/handlers"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// OFREP error codes, see https://github.com/open-feature/protocol
+const (
+	ofrepFlagNotFound        = "FLAG_NOT_FOUND"
+	ofrepParseError          = "PARSE_ERROR"
+	ofrepTargetingKeyMissing = "TARGETING_KEY_MISSING"
+	ofrepGeneral             = "GENERAL"
+)
+
+// ofrepTargetingKey is the OpenFeature name of the targeting key context attribute
+const ofrepTargetingKey = "targetingKey"
+
+// OFREPEvaluateFlag evaluates a single flag following the OpenFeature Remote
+// Evaluation Protocol. Evaluation failures are OFREP error documents rather
+// than the response envelope.
+func (s *FlagService) OFREPEvaluateFlag(ctx context.Context, req models.OFREPEvaluateRequest) handlers.ServiceResponse {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+
+	config, errResp := s.findFlag(ctx, principal, req.Key, req.Namespace)
+	if errResp != nil {
+		return ofrepErrorResponse(req.Key, *errResp)
+	}
+	if !principal.Can(auth.PermConfigRead, config.Resource()) {
+		return ofrepForbiddenResponse(req.Key)
+	}
+
+	evaluation := ofrepEvaluate(config, req.Env, ofrepContext(req.Context))
+	status := http.StatusOK
+	if evaluation.ErrorCode != "" {
+		status = http.StatusBadRequest
+	}
+	return ofrepResponse(status, evaluation)
+}
+
+// OFREPEvaluateFlags evaluates every flag the caller may read in the namespace.
+// The response carries an ETag of its content; a request whose If-None-Match
+// matches it is answered with 304 Not Modified.
+func (s *FlagService) OFREPEvaluateFlags(ctx context.Context, req models.OFREPBulkEvaluateRequest) handlers.ServiceResponse {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+
+	if err := validateNamespace(req.Namespace); err != nil {
+		return ofrepResponse(http.StatusBadRequest, map[string]string{
+			"errorCode":    ofrepGeneral,
+			"errorDetails": err.Error(),
+		})
+	}
+
+	filter, allowed := configFilter(principal, models.ConfigQuery{Type: flags.TypeName})
+	if !allowed {
+		return ofrepResponse(http.StatusForbidden, map[string]string{
+			"errorCode":    ofrepGeneral,
+			"errorDetails": fmt.Sprintf("permission denied: %s", auth.PermConfigRead),
+		})
+	}
+	filter["namespace"] = namespaceFilter(req.Namespace)
+	filter["subtype"] = bson.M{"$in": []string{flags.SubtypeBoolean, flags.SubtypeMultivariate, flags.SubtypeJSON}}
+
+	configs, err := s.configService.repo.Find(ctx, filter, 0, 0)
+	if err != nil {
+		return ofrepResponse(http.StatusInternalServerError, map[string]string{
+			"errorDetails": fmt.Sprintf("failed to get flags: %v", err),
+		})
+	}
+
+	evalContext := ofrepContext(req.Context)
+	evaluations := make([]models.OFREPEvaluation, 0, len(configs))
+	for _, config := range configs {
+		evaluations = append(evaluations, ofrepEvaluate(config, req.Env, evalContext))
+	}
+	// A stable order keeps the ETag stable
+	sort.Slice(evaluations, func(i, j int) bool {
+		return evaluations[i].Key < evaluations[j].Key
+	})
+
+	body, err := json.Marshal(map[string]interface{}{"flags": evaluations})
+	if err != nil {
+		return ofrepResponse(http.StatusInternalServerError, map[string]string{
+			"errorDetails": fmt.Sprintf("failed to encode evaluations: %v", err),
+		})
+	}
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	if etagMatches(req.IfNoneMatch, etag) {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusNotModified,
+			Data:       models.RawResponse{Headers: map[string]string{"ETag": etag}},
+		}
+	}
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusOK,
+		Data: models.RawResponse{
+			ContentType: "application/json",
+			Headers:     map[string]string{"ETag": etag},
+			Body:        body,
+		},
+	}
+}
+
+// ofrepEvaluate evaluates a flag config into an OFREP evaluation. OFREP clients
+// pass one environment for all flags, so flags without an overlay for it are
+// evaluated with their base metadata.
+func ofrepEvaluate(config models.Config, env string, evalContext map[string]interface{}) models.OFREPEvaluation {
+	if _, defined := config.Overlays[env]; !defined {
+		env = ""
+	}
+
+	evaluation, errResp := evaluateFlag(config, env, evalContext)
+	if errResp != nil {
+		details := errResp.Error
+		if errs, ok := errResp.Data.([]string); ok {
+			details = fmt.Sprintf("%s: %s", details, strings.Join(errs, "; "))
+		}
+		return models.OFREPEvaluation{
+			Key:          config.Name,
+			ErrorCode:    ofrepParseError,
+			ErrorDetails: details,
+		}
+	}
+
+	if evaluation.ErrorCode == flags.ErrorTargetingKeyMissing {
+		return models.OFREPEvaluation{
+			Key:          config.Name,
+			ErrorCode:    ofrepTargetingKeyMissing,
+			ErrorDetails: "the flag rolls out by an attribute missing from the context",
+		}
+	}
+
+	metadata := map[string]interface{}{"config_id": evaluation.ConfigID}
+	if evaluation.Rule != "" {
+		metadata["rule"] = evaluation.Rule
+	}
+	return models.OFREPEvaluation{
+		Key:      evaluation.Key,
+		Value:    evaluation.Value,
+		Reason:   evaluation.Reason,
+		Variant:  evaluation.Variant,
+		Metadata: metadata,
+	}
+}
+
+// ofrepContext maps the OpenFeature targetingKey onto the attribute rollouts
+// bucket by. An explicit targeting_key attribute takes precedence.
+func ofrepContext(evalContext map[string]interface{}) map[string]interface{} {
+	targetingKey, ok := evalContext[ofrepTargetingKey]
+	if !ok {
+		return evalContext
+	}
+	if _, set := evalContext[flags.TargetingKey]; set {
+		return evalContext
+	}
+
+	mapped := make(map[string]interface{}, len(evalContext)+1)
+	for key, value := range evalContext {
+		mapped[key] = value
+	}
+	mapped[flags.TargetingKey] = targetingKey
+	return mapped
+}
+
+// ofrepForbiddenResponse is the OFREP error document for a caller without
+// config:read on the flag
+func ofrepForbiddenResponse(key string) handlers.ServiceResponse {
+	return ofrepResponse(http.StatusForbidden, models.OFREPEvaluation{
+		Key:          key,
+		ErrorCode:    ofrepGeneral,
+		ErrorDetails: fmt.Sprintf("permission denied: %s", auth.PermConfigRead),
+	})
+}
+
+// ofrepErrorResponse converts a failed flag lookup into the OFREP error document
+func ofrepErrorResponse(key string, resp handlers.ServiceResponse) handlers.ServiceResponse {
+	switch resp.StatusCode {
+	case http.StatusNotFound:
+		return ofrepResponse(http.StatusNotFound, models.OFREPEvaluation{
+			Key:          key,
+			ErrorCode:    ofrepFlagNotFound,
+			ErrorDetails: fmt.Sprintf("flag %q was not found", key),
+		})
+	case http.StatusBadRequest:
+		return ofrepResponse(http.StatusBadRequest, models.OFREPEvaluation{
+			Key:          key,
+			ErrorCode:    ofrepGeneral,
+			ErrorDetails: resp.Error,
+		})
+	}
+	return ofrepResponse(resp.StatusCode, map[string]string{"errorDetails": resp.Error})
+}
+
+// ofrepResponse encodes an OFREP document as the raw response body
+func ofrepResponse(status int, document interface{}) handlers.ServiceResponse {
+	body, err := json.Marshal(document)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to encode response: %v", err),
+		}
+	}
+	return handlers.ServiceResponse{
+		StatusCode: status,
+		Data: models.RawResponse{
+			ContentType: "application/json",
+			Body:        body,
+		},
+	}
+}
+
+// etagMatches reports whether an If-None-Match header matches the ETag
+func etagMatches(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag || candidate == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"makatom-api-config/internal/auth"
+	"makatom-api-config/internal/flags"
+	"makatom-api-config/internal/models"
+	"makatom/common/pkg/handlers"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// FlagService evaluates feature_flag configs. Flags are stored, versioned and
+// archived like any other config; this service only reads them.
+type FlagService struct {
+	configService *ConfigService
+}
+
+// NewFlagService creates a new FlagService instance
+func NewFlagService(configService *ConfigService) *FlagService {
+	return &FlagService{configService: configService}
+}
+
+// EvaluateFlag returns the variant a flag serves for the evaluation context
+func (s *FlagService) EvaluateFlag(ctx context.Context, req models.EvaluateFlagRequest) handlers.ServiceResponse {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+
+	config, errResp := s.findFlag(ctx, principal, req.Key, req.Namespace)
+	if errResp != nil {
+		return *errResp
+	}
+	if !principal.Can(auth.PermConfigRead, config.Resource()) {
+		return forbiddenResponse(auth.PermConfigRead)
+	}
+
+	evaluation, errResp := evaluateFlag(config, req.Env, req.Context)
+	if errResp != nil {
+		return *errResp
+	}
+
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusOK,
+		Data:       evaluation,
+	}
+}
+
+// findFlag loads the flag with the given key or returns the error response
+func (s *FlagService) findFlag(ctx context.Context, principal *auth.Principal, key, namespace string) (models.Config, *handlers.ServiceResponse) {
+	if err := validateNamespace(namespace); err != nil {
+		return models.Config{}, &handlers.ServiceResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      err.Error(),
+		}
+	}
+
+	config, err := s.configService.repo.FindOne(ctx, bson.M{
+		"tenant_id": principal.TenantID,
+		"type":      flags.TypeName,
+		"name":      key,
+		"namespace": namespaceFilter(namespace),
+	})
+	if err != nil {
+		if err.Error() == "not found" {
+			return models.Config{}, &handlers.ServiceResponse{
+				StatusCode: http.StatusNotFound,
+				Error:      "Flag not found",
+			}
+		}
+		return models.Config{}, &handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to get flag: %v", err),
+		}
+	}
+
+	return config, nil
+}
+
+// evaluateFlag evaluates a flag config in an environment. Flags have no
+// encrypted fields, so the stored metadata is used as is.
+func evaluateFlag(config models.Config, env string, evalContext map[string]interface{}) (models.FlagEvaluation, *handlers.ServiceResponse) {
+	metadata := config.Metadata
+	if env != "" {
+		overlay, defined := config.Overlays[env]
+		if !defined && env != baseEnvironment {
+			return models.FlagEvaluation{}, &handlers.ServiceResponse{
+				StatusCode: http.StatusNotFound,
+				Error:      fmt.Sprintf("environment %q is not defined for the flag", env),
+			}
+		}
+		metadata = mergeOverlay(metadata, overlay)
+	}
+
+	flag, errs := flags.Parse(config.Subtype, metadata)
+	if len(errs) > 0 {
+		return models.FlagEvaluation{}, &handlers.ServiceResponse{
+			StatusCode: http.StatusUnprocessableEntity,
+			Error:      "flag cannot be evaluated",
+			Data:       errs,
+		}
+	}
+
+	result := flag.Evaluate(config.Name, evalContext)
+	return models.FlagEvaluation{
+		Key:       config.Name,
+		ConfigID:  config.ID.Hex(),
+		Variant:   result.Variant,
+		Value:     result.Value,
+		Reason:    result.Reason,
+		Rule:      result.Rule,
+		ErrorCode: result.ErrorCode,
+	}, nil
+}
@@ -0,0 +1,547 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"reflect"
+	"slices"
+	"sort"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"makatom-api-config/internal/auth"
+	"makatom-api-config/internal/models"
+	"makatom-api-config/internal/registry"
+	"makatom/common/pkg/database/mongodb"
+	"makatom/common/pkg/handlers"
+	"makatom/common/pkg/types"
+)
+
+// MigrationService reports schema compatibility of stored configs and applies
+// declared metadata transforms across them
+type MigrationService struct {
+	configService *ConfigService
+	repo          *mongodb.MongoRepository[models.Migration]
+	registry      *registry.Registry
+}
+
+// NewMigrationService creates a new MigrationService instance
+func NewMigrationService(configService *ConfigService, migrationCollection *mongo.Collection, typeRegistry *registry.Registry) *MigrationService {
+	return &MigrationService{
+		configService: configService,
+		repo:          mongodb.NewMongoRepository[models.Migration](migrationCollection),
+		registry:      typeRegistry,
+	}
+}
+
+// GetCompatibility lists the configs of a subtype that fail its current schema
+func (s *MigrationService) GetCompatibility(ctx context.Context, req models.SubtypeNameRequest) handlers.ServiceResponse {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+	tenantID := principal.TenantID
+
+	schemaInfo, errResp := s.currentSchema(ctx, tenantID, req.Type, req.Subtype)
+	if errResp != nil {
+		return *errResp
+	}
+
+	// Only report configs the caller may read
+	scopeFilter, allowed := principal.ScopeFilter(auth.PermConfigRead)
+	if !allowed {
+		return forbiddenResponse(auth.PermConfigRead)
+	}
+	filter := bson.M{"tenant_id": tenantID, "type": req.Type, "subtype": req.Subtype}
+	for key, value := range scopeFilter {
+		filter[key] = value
+	}
+
+	configs, err := s.configService.repo.Find(ctx, filter, 0, 0)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to get configs: %v", err),
+		}
+	}
+
+	report := models.CompatibilityReport{
+		Type:          req.Type,
+		Subtype:       req.Subtype,
+		SchemaVersion: schemaInfo.Version,
+		Checked:       len(configs),
+		Configs:       []models.CompatibilityIssue{},
+	}
+
+	for _, config := range configs {
+		if config.SchemaVersion != schemaInfo.Version {
+			report.Outdated++
+		}
+
+		issue := models.CompatibilityIssue{
+			ConfigID:      config.ID,
+			Name:          config.Name,
+			SchemaVersion: config.SchemaVersion,
+		}
+
+		plain, unencrypted, err := s.plainMetadata(ctx, config, schemaInfo)
+		if err != nil {
+			issue.Errors = append(issue.Errors, err.Error())
+			report.Configs = append(report.Configs, issue)
+			continue
+		}
+		issue.UnencryptedFields = unencrypted
+
+		for _, field := range config.EncryptedFields {
+			if !slices.Contains(schemaInfo.EncryptedFields, field) {
+				issue.Errors = append(issue.Errors, fmt.Sprintf("field %q is stored encrypted but no longer marked for encryption", field))
+			}
+		}
+
+		valid, validation, err := s.registry.ValidateMetadata(ctx, tenantID, config.Type, config.Subtype, plain)
+		if err != nil {
+			issue.Errors = append(issue.Errors, err.Error())
+		} else if !valid {
+			issue.Validation = validation
+		}
+
+		// Every environment is checked in its merged view, like on write
+		plainOverlays, overlaysUnencrypted, err := s.plainOverlays(ctx, config, schemaInfo)
+		if err != nil {
+			issue.Errors = append(issue.Errors, err.Error())
+		}
+		issue.UnencryptedFields = append(issue.UnencryptedFields, overlaysUnencrypted...)
+		for env, overlay := range plainOverlays {
+			valid, validation, err := s.registry.ValidateMetadata(ctx, tenantID, config.Type, config.Subtype, mergeOverlay(plain, overlay))
+			if err != nil {
+				issue.Errors = append(issue.Errors, fmt.Sprintf("environment %s: %v", env, err))
+			} else if !valid {
+				if issue.Overlays == nil {
+					issue.Overlays = make(map[string]interface{})
+				}
+				issue.Overlays[env] = validation
+			}
+		}
+
+		if issue.Validation != nil || len(issue.Overlays) > 0 || len(issue.UnencryptedFields) > 0 || len(issue.Errors) > 0 {
+			report.Configs = append(report.Configs, issue)
+		}
+	}
+	report.Incompatible = len(report.Configs)
+
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusOK,
+		Data:       report,
+	}
+}
+
+// RunMigration applies the declared transforms to every config of a subtype,
+// re-encrypting metadata and archiving the previous version of each changed config
+func (s *MigrationService) RunMigration(ctx context.Context, req models.RunMigrationRequest) handlers.ServiceResponse {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+	tenantID := principal.TenantID
+	userID := principal.Subject
+
+	schemaInfo, errResp := s.currentSchema(ctx, tenantID, req.Type, req.Subtype)
+	if errResp != nil {
+		return *errResp
+	}
+
+	if errs := validateTransforms(req.Transforms, schemaInfo); len(errs) > 0 {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      "invalid transforms",
+			Data:       errs,
+		}
+	}
+
+	configs, err := s.configService.repo.Find(ctx, bson.M{
+		"tenant_id": tenantID,
+		"type":      req.Type,
+		"subtype":   req.Subtype,
+	}, 0, 0)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to get configs: %v", err),
+		}
+	}
+
+	migration := models.Migration{
+		Base:          &types.Base{},
+		TenantID:      tenantID,
+		Type:          req.Type,
+		Subtype:       req.Subtype,
+		Transforms:    req.Transforms,
+		DryRun:        req.DryRun,
+		SchemaVersion: schemaInfo.Version,
+		Matched:       len(configs),
+		Results:       []models.MigrationConfigResult{},
+		CreatedBy:     userID,
+	}
+
+	for _, config := range configs {
+		var result models.MigrationConfigResult
+		switch {
+		case !principal.Can(auth.PermConfigWrite, config.Resource()):
+			result = skippedResult(config, fmt.Sprintf("permission denied: %s", auth.PermConfigWrite))
+		case s.configService.reviewPolicy.Protects(config.Tags):
+			result = skippedResult(config, "config is protected: change it through a reviewed draft")
+		default:
+			result = s.migrateConfig(ctx, config, schemaInfo, req.Transforms, req.DryRun, userID)
+		}
+		switch result.Status {
+		case models.MigrationResultMigrated:
+			migration.Migrated++
+		case models.MigrationResultUnchanged:
+			migration.Unchanged++
+		case models.MigrationResultFailed:
+			migration.Failed++
+		case models.MigrationResultSkipped:
+			migration.Skipped++
+		}
+		migration.Results = append(migration.Results, result)
+	}
+
+	switch {
+	case req.DryRun:
+		migration.Status = models.MigrationStatusDryRun
+	case migration.Failed > 0:
+		migration.Status = models.MigrationStatusCompletedWithErrors
+	default:
+		migration.Status = models.MigrationStatusCompleted
+	}
+	migration.CompletedAt = time.Now()
+
+	recorded, err := s.repo.InsertOne(ctx, migration)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to record migration: %v", err),
+		}
+	}
+
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusOK,
+		Data:       recorded,
+	}
+}
+
+// GetMigrations lists the migration runs of a subtype
+func (s *MigrationService) GetMigrations(ctx context.Context, req models.SubtypeNameRequest) handlers.ServiceResponse {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+
+	migrations, err := s.repo.Find(ctx, bson.M{
+		"tenant_id": principal.TenantID,
+		"type":      req.Type,
+		"subtype":   req.Subtype,
+	}, 0, 0)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to get migrations: %v", err),
+		}
+	}
+
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusOK,
+		Data: map[string]interface{}{
+			"migrations": migrations,
+			"total":      len(migrations),
+		},
+	}
+}
+
+// skippedResult reports a config the migration left untouched
+func skippedResult(config models.Config, reason string) models.MigrationConfigResult {
+	return models.MigrationConfigResult{
+		ConfigID: config.ID,
+		Name:     config.Name,
+		Status:   models.MigrationResultSkipped,
+		Errors:   []string{reason},
+	}
+}
+
+// migrateConfig applies the transforms to a single config
+func (s *MigrationService) migrateConfig(ctx context.Context, config models.Config, schemaInfo registry.SchemaInfo, transforms []models.MigrationTransform, dryRun bool, userID string) models.MigrationConfigResult {
+	result := models.MigrationConfigResult{
+		ConfigID: config.ID,
+		Name:     config.Name,
+	}
+	fail := func(errs ...string) models.MigrationConfigResult {
+		result.Status = models.MigrationResultFailed
+		result.Errors = errs
+		return result
+	}
+
+	plain, unencrypted, err := s.plainMetadata(ctx, config, schemaInfo)
+	if err != nil {
+		return fail(err.Error())
+	}
+
+	plainOverlays, overlaysUnencrypted, err := s.plainOverlays(ctx, config, schemaInfo)
+	if err != nil {
+		return fail(err.Error())
+	}
+
+	migrated, errs := applyTransforms(plain, transforms, false)
+	migratedOverlays := make(models.EnvOverlays, len(plainOverlays))
+	for env, overlay := range plainOverlays {
+		migratedOverlay, overlayErrs := applyTransforms(overlay, transforms, true)
+		for _, overlayErr := range overlayErrs {
+			errs = append(errs, fmt.Sprintf("environment %s: %s", env, overlayErr))
+		}
+		migratedOverlays[env] = migratedOverlay
+	}
+	if len(errs) > 0 {
+		return fail(errs...)
+	}
+
+	valid, validation, err := s.registry.ValidateMetadata(ctx, config.TenantID, config.Type, config.Subtype, migrated)
+	if err != nil {
+		return fail(err.Error())
+	}
+	if !valid {
+		return fail(fmt.Sprintf("metadata validation failed: %+v", validation))
+	}
+	for env, overlay := range migratedOverlays {
+		valid, validation, err := s.registry.ValidateMetadata(ctx, config.TenantID, config.Type, config.Subtype, mergeOverlay(migrated, overlay))
+		if err != nil {
+			return fail(fmt.Sprintf("environment %s: %v", env, err))
+		}
+		if !valid {
+			return fail(fmt.Sprintf("environment %s: metadata validation failed: %+v", env, validation))
+		}
+	}
+
+	changed := !reflect.DeepEqual(plain, migrated) ||
+		!reflect.DeepEqual(plainOverlays, migratedOverlays) ||
+		len(unencrypted) > 0 ||
+		len(overlaysUnencrypted) > 0 ||
+		config.SchemaVersion != schemaInfo.Version
+	if !changed {
+		result.Status = models.MigrationResultUnchanged
+		return result
+	}
+	if dryRun {
+		result.Status = models.MigrationResultMigrated
+		return result
+	}
+
+	encrypted, err := s.registry.EncryptMetadata(ctx, config.TenantID, config.Type, config.Subtype, migrated)
+	if err != nil {
+		return fail(fmt.Sprintf("failed to encrypt metadata: %v", err))
+	}
+	encryptedOverlays, err := s.configService.encryptOverlays(ctx, config.TenantID, config.Type, config.Subtype, migratedOverlays)
+	if err != nil {
+		return fail(fmt.Sprintf("failed to encrypt overlays: %v", err))
+	}
+
+	// Archive the current version and update within one transaction, like UpdateConfig
+	err = s.configService.repo.WithTransaction(ctx, func(sessCtx mongo.SessionContext) error {
+		if err := s.configService.archiveConfigVersionWithSession(sessCtx, config, userID); err != nil {
+			return fmt.Errorf("failed to archive config version: %v", err)
+		}
+		set := bson.M{
+			"metadata":         encrypted,
+			"schema_version":   schemaInfo.Version,
+			"encrypted_fields": schemaInfo.EncryptedFields,
+			"last_updated_by":  userID,
+		}
+		if len(encryptedOverlays) > 0 {
+			set["overlays"] = encryptedOverlays
+		}
+		_, err := s.configService.repo.UpdateByID(sessCtx, config.ID, bson.M{"$set": set})
+		if err != nil {
+			return fmt.Errorf("failed to update config: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return fail(fmt.Sprintf("transaction failed: %v", err))
+	}
+	s.configService.recordChange(ctx, config.TenantID, config.ID)
+
+	result.Status = models.MigrationResultMigrated
+	return result
+}
+
+// plainMetadata decrypts the stored metadata of a config. Fields marked for
+// encryption in the current schema but stored in plaintext are returned as is
+// and listed separately.
+func (s *MigrationService) plainMetadata(ctx context.Context, config models.Config, schemaInfo registry.SchemaInfo) (map[string]interface{}, []string, error) {
+	decrypted, unencrypted, err := s.plainFields(ctx, config, schemaInfo, config.Metadata)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decrypt metadata: %v", err)
+	}
+	return decrypted, unencrypted, nil
+}
+
+// plainOverlays decrypts the stored overlays of a config like plainMetadata.
+// Plaintext fields are listed by their stored path, overlays.<env>.<field>.
+func (s *MigrationService) plainOverlays(ctx context.Context, config models.Config, schemaInfo registry.SchemaInfo) (models.EnvOverlays, []string, error) {
+	unencrypted := make([]string, 0)
+	decrypted := make(models.EnvOverlays, len(config.Overlays))
+	for env, overlay := range config.Overlays {
+		plain, fields, err := s.plainFields(ctx, config, schemaInfo, overlay)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to decrypt overlay of environment %s: %v", env, err)
+		}
+		for _, field := range fields {
+			unencrypted = append(unencrypted, "overlays."+env+"."+field)
+		}
+		decrypted[env] = plain
+	}
+	sort.Strings(unencrypted)
+	return decrypted, unencrypted, nil
+}
+
+// plainFields decrypts stored metadata or an overlay of a config, returning the
+// fields marked for encryption that are stored in plaintext
+func (s *MigrationService) plainFields(ctx context.Context, config models.Config, schemaInfo registry.SchemaInfo, fields map[string]interface{}) (map[string]interface{}, []string, error) {
+	unencrypted := make([]string, 0)
+	stored := make(map[string]interface{}, len(fields))
+	for key, value := range fields {
+		stored[key] = value
+	}
+
+	for _, field := range schemaInfo.EncryptedFields {
+		value, present := stored[field]
+		if !present || value == nil {
+			continue
+		}
+		if s.storedInPlaintext(ctx, config, field, value) {
+			unencrypted = append(unencrypted, field)
+		}
+	}
+
+	// Keep plaintext values away from decryption, it would fail on them
+	plaintext := make(map[string]interface{}, len(unencrypted))
+	for _, field := range unencrypted {
+		plaintext[field] = stored[field]
+		delete(stored, field)
+	}
+
+	decrypted, err := s.registry.DecryptMetadata(ctx, config.TenantID, config.Type, config.Subtype, stored)
+	if err != nil {
+		return nil, nil, err
+	}
+	if decrypted == nil {
+		decrypted = make(map[string]interface{})
+	}
+	for field, value := range plaintext {
+		decrypted[field] = value
+	}
+
+	return decrypted, unencrypted, nil
+}
+
+// storedInPlaintext reports whether a field marked for encryption is stored unencrypted
+func (s *MigrationService) storedInPlaintext(ctx context.Context, config models.Config, field string, value interface{}) bool {
+	// Versioned configs record which fields were encrypted on write
+	if config.SchemaVersion != "" {
+		return !slices.Contains(config.EncryptedFields, field)
+	}
+
+	// Configs written before schema versions existed need probing
+	if !registry.IsBuiltinSubtype(config.Type, config.Subtype) {
+		return !registry.IsEncrypted(value)
+	}
+	_, err := s.registry.DecryptMetadata(ctx, config.TenantID, config.Type, config.Subtype, map[string]interface{}{field: value})
+	return err != nil
+}
+
+// currentSchema loads the schema of an existing subtype or returns the error response
+func (s *MigrationService) currentSchema(ctx context.Context, tenantID, typeName, subtypeName string) (registry.SchemaInfo, *handlers.ServiceResponse) {
+	_, exists, err := s.registry.GetSubtype(ctx, tenantID, typeName, subtypeName)
+	if err != nil {
+		return registry.SchemaInfo{}, &handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to get subtype: %v", err),
+		}
+	}
+	if !exists {
+		return registry.SchemaInfo{}, &handlers.ServiceResponse{
+			StatusCode: http.StatusNotFound,
+			Error:      "Subtype not found",
+		}
+	}
+
+	schemaInfo, err := s.registry.Schema(ctx, tenantID, typeName, subtypeName)
+	if err != nil {
+		return registry.SchemaInfo{}, &handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to get subtype schema: %v", err),
+		}
+	}
+	return schemaInfo, nil
+}
+
+// validateTransforms checks the declared transforms against the current schema
+func validateTransforms(transforms []models.MigrationTransform, schemaInfo registry.SchemaInfo) []string {
+	var errs []string
+	for i, transform := range transforms {
+		switch transform.Op {
+		case models.MigrationOpRename:
+			if transform.To == "" {
+				errs = append(errs, fmt.Sprintf("transform %d: rename requires \"to\"", i))
+			}
+		case models.MigrationOpDefault:
+			if transform.Value == nil {
+				errs = append(errs, fmt.Sprintf("transform %d: default requires \"value\"", i))
+			}
+		case models.MigrationOpEncrypt:
+			if !slices.Contains(schemaInfo.EncryptedFields, transform.Field) {
+				errs = append(errs, fmt.Sprintf("transform %d: field %q is not marked for encryption in the schema", i, transform.Field))
+			}
+		default:
+			errs = append(errs, fmt.Sprintf("transform %d: unknown op %q", i, transform.Op))
+		}
+	}
+	return errs
+}
+
+// applyTransforms returns a copy of the plaintext metadata with the transforms applied.
+// Encryption happens afterwards for every field marked in the schema. Overlays inherit
+// missing fields from the base metadata, so defaults only replace their explicit nulls.
+func applyTransforms(metadata map[string]interface{}, transforms []models.MigrationTransform, overlay bool) (map[string]interface{}, []string) {
+	migrated := make(map[string]interface{}, len(metadata))
+	for key, value := range metadata {
+		migrated[key] = value
+	}
+
+	var errs []string
+	for _, transform := range transforms {
+		switch transform.Op {
+		case models.MigrationOpRename:
+			value, present := migrated[transform.Field]
+			if !present {
+				continue
+			}
+			if _, taken := migrated[transform.To]; taken {
+				errs = append(errs, fmt.Sprintf("cannot rename %q to %q: target field already set", transform.Field, transform.To))
+				continue
+			}
+			migrated[transform.To] = value
+			delete(migrated, transform.Field)
+		case models.MigrationOpDefault:
+			value, present := migrated[transform.Field]
+			if overlay && !present {
+				continue
+			}
+			if value == nil {
+				migrated[transform.Field] = transform.Value
+			}
+		}
+	}
+	return migrated, errs
+}
@@ -0,0 +1,204 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"makatom-api-config/internal/auth"
+	"makatom-api-config/internal/models"
+	"makatom/common/pkg/database/mongodb"
+	"makatom/common/pkg/handlers"
+	"makatom/common/pkg/types"
+)
+
+// RoleBindingService handles business logic for role binding operations
+type RoleBindingService struct {
+	repo *mongodb.MongoRepository[models.RoleBinding]
+}
+
+// NewRoleBindingService creates a new RoleBindingService instance
+func NewRoleBindingService(bindingCollection *mongo.Collection) *RoleBindingService {
+	return &RoleBindingService{
+		repo: mongodb.NewMongoRepository[models.RoleBinding](bindingCollection),
+	}
+}
+
+// LoadBindings returns the stored bindings of a subject, implementing auth.BindingStore
+func (s *RoleBindingService) LoadBindings(ctx context.Context, tenantID, subject string) ([]auth.Binding, error) {
+	stored, err := s.repo.Find(ctx, bson.M{
+		"tenant_id": tenantID,
+		"subject":   subject,
+	}, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	bindings := make([]auth.Binding, len(stored))
+	for i, binding := range stored {
+		bindings[i] = binding.ToBinding()
+	}
+	return bindings, nil
+}
+
+// CreateRoleBinding grants a role to a subject in the caller's tenant
+func (s *RoleBindingService) CreateRoleBinding(ctx context.Context, req models.CreateRoleBindingRequest) handlers.ServiceResponse {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+
+	if !auth.IsValidRole(auth.Role(req.Role)) {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      fmt.Sprintf("unknown role: %s", req.Role),
+		}
+	}
+
+	if req.Subtype != "" && req.Type == "" {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      "subtype scope requires a type",
+		}
+	}
+
+	// Admins bound to a scope may only grant access within it
+	scope := auth.Scope{
+		Type:    req.Type,
+		Subtype: req.Subtype,
+		Tag:     req.Tag,
+	}
+	if !principal.CanScope(auth.PermRBACManage, scope) {
+		return forbiddenResponse(auth.PermRBACManage)
+	}
+
+	binding := models.RoleBinding{
+		Base:      &types.Base{},
+		TenantID:  principal.TenantID,
+		Subject:   req.Subject,
+		Role:      auth.Role(req.Role),
+		Scope:     scope,
+		CreatedBy: principal.Subject,
+	}
+
+	created, err := s.repo.InsertOne(ctx, binding)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to create role binding: %v", err),
+		}
+	}
+
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusCreated,
+		Data:       created.ToResponse(),
+	}
+}
+
+// GetRoleBindings lists the role bindings of the caller's tenant that the caller
+// could grant themselves, so scoped admins only see bindings within their scope
+func (s *RoleBindingService) GetRoleBindings(ctx context.Context, query models.RoleBindingQuery) handlers.ServiceResponse {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+
+	filter := bson.M{"tenant_id": principal.TenantID}
+	if query.Subject != "" {
+		filter["subject"] = query.Subject
+	}
+
+	bindings, err := s.repo.Find(ctx, filter, 0, 0)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to get role bindings: %v", err),
+		}
+	}
+
+	responses := make([]models.RoleBindingResponse, 0, len(bindings))
+	for _, binding := range bindings {
+		if principal.CanGrant(binding.ToBinding()) {
+			responses = append(responses, binding.ToResponse())
+		}
+	}
+
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusOK,
+		Data: map[string]interface{}{
+			"bindings": responses,
+			"total":    len(responses),
+		},
+	}
+}
+
+// DeleteRoleBinding revokes a role binding
+func (s *RoleBindingService) DeleteRoleBinding(ctx context.Context, req models.RoleBindingIDRequest) handlers.ServiceResponse {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+
+	id, err := primitive.ObjectIDFromHex(req.ID)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      "Invalid role binding ID",
+		}
+	}
+
+	filter := bson.M{
+		"_id":       id,
+		"tenant_id": principal.TenantID,
+	}
+	binding, err := s.repo.FindOne(ctx, filter)
+	if err != nil {
+		if err.Error() == "not found" {
+			return handlers.ServiceResponse{
+				StatusCode: http.StatusNotFound,
+				Error:      "Role binding not found",
+			}
+		}
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to get role binding: %v", err),
+		}
+	}
+
+	// Admins bound to a scope may only revoke access within it
+	if !principal.CanScope(auth.PermRBACManage, binding.Scope) {
+		return forbiddenResponse(auth.PermRBACManage)
+	}
+
+	if _, err := s.repo.DeleteOne(ctx, filter); err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to delete role binding: %v", err),
+		}
+	}
+
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusOK,
+		Data:       map[string]string{"message": "Role binding deleted successfully"},
+	}
+}
+
+// unauthorizedResponse is returned when a request reaches a service without a principal
+func unauthorizedResponse() handlers.ServiceResponse {
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusUnauthorized,
+		Error:      "unauthenticated",
+	}
+}
+
+// forbiddenResponse is returned when the principal lacks a scoped permission
+func forbiddenResponse(perm auth.Permission) handlers.ServiceResponse {
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusForbidden,
+		Error:      fmt.Sprintf("permission denied: %s", perm),
+	}
+}
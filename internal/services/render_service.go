@@ -0,0 +1,229 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"makatom-api-config/internal/auth"
+	"makatom-api-config/internal/models"
+	"makatom-api-config/internal/registry"
+	"makatom/common/pkg/handlers"
+)
+
+var (
+	// k8sKeyPattern is the allowed form of ConfigMap and Secret data keys
+	k8sKeyPattern = regexp.MustCompile(`^[-._a-zA-Z0-9]+$`)
+	// k8sNamePattern is a DNS-1123 subdomain, the allowed form of object names
+	k8sNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?(\.[a-z0-9]([-a-z0-9]*[a-z0-9])?)*$`)
+)
+
+// RenderService renders stored configs into deployment manifests
+type RenderService struct {
+	configService *ConfigService
+	registry      *registry.Registry
+}
+
+// NewRenderService creates a new RenderService instance
+func NewRenderService(configService *ConfigService, typeRegistry *registry.Registry) *RenderService {
+	return &RenderService{
+		configService: configService,
+		registry:      typeRegistry,
+	}
+}
+
+// RenderConfigs renders the matching configs as a ConfigMap for plain metadata and
+// a Secret for the fields encrypted by their subtype schema
+func (s *RenderService) RenderConfigs(ctx context.Context, req models.RenderConfigsRequest) handlers.ServiceResponse {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+	tenantID := principal.TenantID
+
+	if req.Format != "k8s" {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      fmt.Sprintf("unsupported format %q, supported formats: k8s", req.Format),
+		}
+	}
+
+	labels, err := parseLabels(req.Labels)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      err.Error(),
+		}
+	}
+
+	filter, allowed := configFilter(principal, req.Query())
+	if !allowed {
+		return forbiddenResponse(auth.PermConfigRead)
+	}
+	configs, err := s.configService.repo.Find(ctx, filter, 0, 0)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to get configs: %v", err),
+		}
+	}
+	if len(configs) == 0 {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusNotFound,
+			Error:      "no configs match the query",
+		}
+	}
+	sort.Slice(configs, func(i, j int) bool { return configs[i].Name < configs[j].Name })
+
+	name := req.Name
+	if name == "" && len(configs) == 1 {
+		name = strings.ToLower(configs[0].Name)
+	}
+	if !k8sNamePattern.MatchString(name) || len(name) > 253 {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      fmt.Sprintf("invalid manifest name %q, pass a DNS-1123 name with the name parameter", name),
+		}
+	}
+
+	plain := map[string]string{}
+	secret := map[string]string{}
+	source := map[string]string{}
+	for _, config := range configs {
+		schema, err := s.registry.Schema(ctx, tenantID, config.Type, config.Subtype)
+		if err != nil {
+			return handlers.ServiceResponse{
+				StatusCode: http.StatusInternalServerError,
+				Error:      fmt.Sprintf("failed to load schema for config %s: %v", config.Name, err),
+			}
+		}
+		encrypted := make(map[string]bool)
+		for _, field := range schema.EncryptedFields {
+			encrypted[field] = true
+		}
+		for _, field := range config.EncryptedFields {
+			encrypted[field] = true
+		}
+
+		metadata := config.Metadata
+		if len(encrypted) > 0 && len(metadata) > 0 {
+			// A Secret can only be rendered with the plaintext values
+			if !principal.Can(auth.PermSecretRead, config.Resource()) {
+				return forbiddenResponse(auth.PermSecretRead)
+			}
+			metadata, err = s.registry.DecryptMetadata(ctx, tenantID, config.Type, config.Subtype, metadata)
+			if err != nil {
+				return handlers.ServiceResponse{
+					StatusCode: http.StatusInternalServerError,
+					Error:      fmt.Sprintf("failed to decrypt metadata for config %s: %v", config.Name, err),
+				}
+			}
+		}
+
+		for field, value := range metadata {
+			key := field
+			if req.PrefixKeys {
+				key = config.Name + "." + field
+			}
+			if !k8sKeyPattern.MatchString(key) {
+				return handlers.ServiceResponse{
+					StatusCode: http.StatusUnprocessableEntity,
+					Error:      fmt.Sprintf("config %s: %q is not a valid ConfigMap or Secret key", config.Name, key),
+				}
+			}
+			if previous, exists := source[key]; exists {
+				return handlers.ServiceResponse{
+					StatusCode: http.StatusConflict,
+					Error:      fmt.Sprintf("key %q is set by configs %s and %s, use prefix_keys=true", key, previous, config.Name),
+				}
+			}
+			source[key] = config.Name
+
+			if encrypted[field] {
+				secret[key] = base64.StdEncoding.EncodeToString([]byte(manifestValue(value)))
+			} else {
+				plain[key] = manifestValue(value)
+			}
+		}
+	}
+
+	meta := models.K8sObjectMeta{Name: name, Namespace: req.Namespace, Labels: labels}
+	manifests := []models.K8sManifest{{
+		APIVersion: "v1",
+		Kind:       "ConfigMap",
+		Metadata:   meta,
+		Data:       plain,
+	}}
+	if len(secret) > 0 {
+		manifests = append(manifests, models.K8sManifest{
+			APIVersion: "v1",
+			Kind:       "Secret",
+			Metadata:   meta,
+			Type:       "Opaque",
+			Data:       secret,
+		})
+	}
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	for _, manifest := range manifests {
+		if err := encoder.Encode(manifest); err != nil {
+			return handlers.ServiceResponse{
+				StatusCode: http.StatusInternalServerError,
+				Error:      fmt.Sprintf("failed to encode manifests: %v", err),
+			}
+		}
+	}
+	encoder.Close()
+
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusOK,
+		Data: models.RawResponse{
+			ContentType: "application/yaml",
+			Body:        out.Bytes(),
+		},
+	}
+}
+
+// parseLabels parses "key=value,key=value" into labels, adding the managed-by label
+func parseLabels(raw string) (map[string]string, error) {
+	labels := map[string]string{"app.kubernetes.io/managed-by": "makatom"}
+	if raw == "" {
+		return labels, nil
+	}
+	for _, pair := range strings.Split(raw, ",") {
+		key, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid label %q, expected key=value", pair)
+		}
+		labels[key] = value
+	}
+	return labels, nil
+}
+
+// manifestValue renders a metadata value; objects and arrays are JSON encoded,
+// BSON documents as the objects they hold
+func manifestValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case nil:
+		return ""
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool, int, int32, int64:
+		return fmt.Sprint(v)
+	}
+	encoded, _ := json.Marshal(models.PlainValue(value))
+	return string(encoded)
+}
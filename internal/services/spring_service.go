@@ -0,0 +1,290 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"makatom-api-config/internal/auth"
+	"makatom-api-config/internal/models"
+	"makatom/common/pkg/handlers"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	// springSharedApplication is the name of the configs every application receives
+	springSharedApplication = "application"
+	// springProfileTagPrefix marks configs that only apply under a profile
+	springProfileTagPrefix = "profile:"
+	// springLatestLabel serves the current configs, like an empty label
+	springLatestLabel = "latest"
+)
+
+// SpringService serves configs to Spring Cloud Config clients. An application
+// receives the configs named after it or tagged with it, and the configs named
+// "application". A profile activates the environment overlay of that name and
+// the configs tagged profile:<name>. A label is an archive version number or an
+// RFC 3339 timestamp selecting the version of each config current at that time.
+type SpringService struct {
+	configService *ConfigService
+}
+
+// NewSpringService creates a new SpringService instance
+func NewSpringService(configService *ConfigService) *SpringService {
+	return &SpringService{configService: configService}
+}
+
+// springLabel selects the version of each config a label serves; the zero
+// value selects the current configs
+type springLabel struct {
+	version int
+	at      time.Time
+}
+
+// GetEnvironment returns the Spring Environment of an application and profiles
+func (s *SpringService) GetEnvironment(ctx context.Context, req models.SpringEnvironmentRequest) handlers.ServiceResponse {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+
+	if err := validateNamespace(req.Namespace); err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      err.Error(),
+		}
+	}
+	label, err := parseSpringLabel(req.Label)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusNotFound,
+			Error:      err.Error(),
+		}
+	}
+
+	profiles := make([]string, 0)
+	for _, profile := range strings.Split(req.Profile, ",") {
+		if profile = strings.TrimSpace(profile); profile != "" {
+			profiles = append(profiles, profile)
+		}
+	}
+
+	filter, allowed := configFilter(principal, models.ConfigQuery{})
+	if !allowed {
+		return forbiddenResponse(auth.PermConfigRead)
+	}
+	filter["namespace"] = namespaceFilter(req.Namespace)
+	filter["$and"] = []bson.M{{"$or": []bson.M{
+		{"name": bson.M{"$in": []string{req.Application, springSharedApplication}}},
+		{"tags": req.Application},
+	}}}
+
+	configs, err := s.configService.repo.Find(ctx, filter, 0, 0)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to get configs: %v", err),
+		}
+	}
+
+	// Configs of the application rank above the shared ones
+	shared := func(config models.Config) bool {
+		return config.Name != req.Application && !slices.Contains(config.Tags, req.Application)
+	}
+	sort.Slice(configs, func(i, j int) bool {
+		if shared(configs[i]) != shared(configs[j]) {
+			return !shared(configs[i])
+		}
+		return springSourceName(configs[i]) < springSourceName(configs[j])
+	})
+
+	selected := make([]models.Config, 0, len(configs))
+	for _, config := range configs {
+		if !springProfilesMatch(config, profiles) {
+			continue
+		}
+		config, found, err := s.atLabel(ctx, config, label)
+		if err != nil {
+			return handlers.ServiceResponse{
+				StatusCode: http.StatusInternalServerError,
+				Error:      fmt.Sprintf("failed to get config version: %v", err),
+			}
+		}
+		if !found {
+			continue
+		}
+		config, err = s.configService.decryptForReader(ctx, principal, config)
+		if err != nil {
+			return handlers.ServiceResponse{
+				StatusCode: http.StatusInternalServerError,
+				Error:      fmt.Sprintf("config %s: %v", config.ID.Hex(), err),
+			}
+		}
+		selected = append(selected, config)
+	}
+
+	// Later profiles take precedence, as in Spring
+	sources := make([]models.SpringPropertySource, 0)
+	for i := len(profiles) - 1; i >= 0; i-- {
+		profile := profiles[i]
+		for _, config := range selected {
+			if overlay, defined := config.Overlays[profile]; defined {
+				sources = append(sources, springSource(springSourceName(config)+" ("+profile+")", mergeOverlay(config.Metadata, overlay)))
+			}
+			if slices.Contains(config.Tags, springProfileTagPrefix+profile) {
+				sources = append(sources, springSource(springSourceName(config), config.Metadata))
+			}
+		}
+	}
+	for _, config := range selected {
+		if !springProfileSpecific(config) {
+			sources = append(sources, springSource(springSourceName(config), config.Metadata))
+		}
+	}
+
+	environment := models.SpringEnvironment{
+		Name:            req.Application,
+		Profiles:        profiles,
+		PropertySources: sources,
+	}
+	if req.Label != "" {
+		environment.Label = &req.Label
+	}
+
+	body, err := json.Marshal(environment)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to encode environment: %v", err),
+		}
+	}
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusOK,
+		Data: models.RawResponse{
+			ContentType: "application/json",
+			Body:        body,
+		},
+	}
+}
+
+// atLabel returns the version of the config the label selects. It reports false
+// when the config has no such version or did not exist at that time.
+func (s *SpringService) atLabel(ctx context.Context, config models.Config, label springLabel) (models.Config, bool, error) {
+	if label.version == 0 && label.at.IsZero() {
+		return config, true, nil
+	}
+	if !label.at.IsZero() && !config.UpdatedAt.After(label.at) {
+		return config, true, nil
+	}
+	if !label.at.IsZero() && config.CreatedAt.After(label.at) {
+		return config, false, nil
+	}
+
+	archives, current, err := s.configService.configVersions(ctx, config)
+	if err != nil {
+		return config, false, err
+	}
+	if label.version == current {
+		return config, true, nil
+	}
+
+	// Version n was current until it was archived: a timestamp selects the
+	// first version archived after it
+	var selected *models.ConfigArchive
+	for i, archive := range archives {
+		if (label.version != 0 && archive.Version == label.version) ||
+			(!label.at.IsZero() && archive.ArchivedAt.After(label.at)) {
+			selected = &archives[i]
+			break
+		}
+	}
+	if selected == nil {
+		return config, false, nil
+	}
+
+	config.Metadata = selected.Metadata
+	config.Overlays = selected.Overlays
+	return config, true, nil
+}
+
+// parseSpringLabel parses a version number or RFC 3339 timestamp label
+func parseSpringLabel(label string) (springLabel, error) {
+	if label == "" || label == springLatestLabel {
+		return springLabel{}, nil
+	}
+	if version, err := strconv.Atoi(label); err == nil && version > 0 {
+		return springLabel{version: version}, nil
+	}
+	if at, err := time.Parse(time.RFC3339, label); err == nil {
+		return springLabel{at: at}, nil
+	}
+	return springLabel{}, fmt.Errorf("label %q is neither a version number nor an RFC 3339 timestamp", label)
+}
+
+// springProfilesMatch reports whether a config tagged for profiles has one of them active
+func springProfilesMatch(config models.Config, profiles []string) bool {
+	if !springProfileSpecific(config) {
+		return true
+	}
+	for _, profile := range profiles {
+		if slices.Contains(config.Tags, springProfileTagPrefix+profile) {
+			return true
+		}
+	}
+	return false
+}
+
+// springProfileSpecific reports whether the config only applies under a profile
+func springProfileSpecific(config models.Config) bool {
+	for _, tag := range config.Tags {
+		if strings.HasPrefix(tag, springProfileTagPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// springSourceName names the property source of a config
+func springSourceName(config models.Config) string {
+	name := config.Type + "/"
+	if config.Subtype != "" {
+		name += config.Subtype + "/"
+	}
+	name += config.Name
+	if config.Namespace != "" {
+		name = config.Namespace + ":" + name
+	}
+	return "makatom:" + name
+}
+
+// springSource flattens metadata into Spring properties: nested keys are
+// dotted and list items indexed, as in Spring's relaxed binding
+func springSource(name string, metadata map[string]interface{}) models.SpringPropertySource {
+	properties := make(map[string]interface{})
+	var flatten func(key string, value interface{})
+	flatten = func(key string, value interface{}) {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			for field, item := range v {
+				flatten(key+"."+field, item)
+			}
+		case []interface{}:
+			for i, item := range v {
+				flatten(fmt.Sprintf("%s[%d]", key, i), item)
+			}
+		default:
+			properties[key] = v
+		}
+	}
+	for field, value := range metadata {
+		flatten(field, models.PlainValue(value))
+	}
+	return models.SpringPropertySource{Name: name, Source: properties}
+}
@@ -0,0 +1,634 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+
+	"makatom-api-config/internal/auth"
+	"makatom-api-config/internal/models"
+	"makatom-api-config/internal/registry"
+	"makatom/common/pkg/database/mongodb"
+	"makatom/common/pkg/handlers"
+	"makatom/common/pkg/types"
+)
+
+// typeNamePattern restricts type and subtype names, which become MongoDB field paths
+var typeNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// validateTypeName rejects a type or subtype name that does not match typeNamePattern
+func validateTypeName(kind, name string) *handlers.ServiceResponse {
+	if typeNamePattern.MatchString(name) {
+		return nil
+	}
+	return &handlers.ServiceResponse{
+		StatusCode: http.StatusBadRequest,
+		Error:      fmt.Sprintf("invalid %s name %q: must match %s", kind, name, typeNamePattern),
+	}
+}
+
+// TypeDefinitionService handles tenant-defined config types and subtypes and
+// serves the merged registry view
+type TypeDefinitionService struct {
+	repo       *mongodb.MongoRepository[models.TypeDefinition]
+	configRepo *mongodb.MongoRepository[models.Config]
+	registry   *registry.Registry
+}
+
+// NewTypeDefinitionService creates a new TypeDefinitionService instance
+func NewTypeDefinitionService(typeCollection, configCollection *mongo.Collection, typeRegistry *registry.Registry) *TypeDefinitionService {
+	return &TypeDefinitionService{
+		repo:       mongodb.NewMongoRepository[models.TypeDefinition](typeCollection),
+		configRepo: mongodb.NewMongoRepository[models.Config](configCollection),
+		registry:   typeRegistry,
+	}
+}
+
+// GetAllTypes returns the merged view of built-in and tenant types
+func (s *TypeDefinitionService) GetAllTypes(ctx context.Context, req types.EmptyRequest) handlers.ServiceResponse {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+
+	views, err := s.registry.GetAllTypes(ctx, principal.TenantID)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to get types: %v", err),
+		}
+	}
+
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusOK,
+		Data:       views,
+	}
+}
+
+// GetType returns a single type from the merged view
+func (s *TypeDefinitionService) GetType(ctx context.Context, req models.TypeNameRequest) handlers.ServiceResponse {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+
+	view, exists, err := s.registry.GetType(ctx, principal.TenantID, req.Type)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to get type: %v", err),
+		}
+	}
+	if !exists {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusNotFound,
+			Error:      "Type not found",
+		}
+	}
+
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusOK,
+		Data:       view,
+	}
+}
+
+// GetSubtypes returns the subtypes of a type from the merged view
+func (s *TypeDefinitionService) GetSubtypes(ctx context.Context, req models.TypeNameRequest) handlers.ServiceResponse {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+
+	view, exists, err := s.registry.GetType(ctx, principal.TenantID, req.Type)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to get type: %v", err),
+		}
+	}
+	if !exists {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusNotFound,
+			Error:      "Type not found",
+		}
+	}
+
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusOK,
+		Data:       view.Subtypes,
+	}
+}
+
+// GetSubtype returns a single subtype from the merged view
+func (s *TypeDefinitionService) GetSubtype(ctx context.Context, req models.SubtypeNameRequest) handlers.ServiceResponse {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+
+	view, exists, err := s.registry.GetSubtype(ctx, principal.TenantID, req.Type, req.Subtype)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to get subtype: %v", err),
+		}
+	}
+	if !exists {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusNotFound,
+			Error:      "Subtype not found",
+		}
+	}
+
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusOK,
+		Data:       view,
+	}
+}
+
+// GetSubtypeJSONSchema returns the metadata schema of a subtype as JSON Schema draft 2020-12
+func (s *TypeDefinitionService) GetSubtypeJSONSchema(ctx context.Context, req models.SubtypeNameRequest) handlers.ServiceResponse {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+
+	view, exists, err := s.registry.GetSubtype(ctx, principal.TenantID, req.Type, req.Subtype)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to get subtype: %v", err),
+		}
+	}
+	if !exists {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusNotFound,
+			Error:      "Subtype not found",
+		}
+	}
+
+	// Served as the bare document, so JSON Schema tooling can fetch it directly
+	body, err := json.Marshal(registry.ToJSONSchema(req.Type, view))
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to encode JSON Schema: %v", err),
+		}
+	}
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusOK,
+		Data: models.RawResponse{
+			ContentType: "application/schema+json",
+			Body:        body,
+		},
+	}
+}
+
+// ImportSubtypeJSONSchema defines a new tenant subtype from a JSON Schema document
+func (s *TypeDefinitionService) ImportSubtypeJSONSchema(ctx context.Context, req models.ImportJSONSchemaRequest) handlers.ServiceResponse {
+	schema, description, errs := registry.FromJSONSchema(req.JSONSchema)
+	if len(errs) > 0 {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      "invalid JSON Schema",
+			Data:       errs,
+		}
+	}
+
+	return s.CreateSubtype(ctx, models.CreateSubtypeRequest{
+		Type:           req.Type,
+		Name:           req.Subtype,
+		Description:    description,
+		MetadataSchema: schema,
+	})
+}
+
+// ValidateMetadata validates metadata against the merged view
+func (s *TypeDefinitionService) ValidateMetadata(ctx context.Context, req models.ValidateMetadataRequest) handlers.ServiceResponse {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+
+	_, result, err := s.registry.ValidateMetadata(ctx, principal.TenantID, req.Type, req.Subtype, req.Metadata)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      err.Error(),
+		}
+	}
+
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusOK,
+		Data:       result,
+	}
+}
+
+// CreateType creates a tenant-defined type
+func (s *TypeDefinitionService) CreateType(ctx context.Context, req models.CreateTypeRequest) handlers.ServiceResponse {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+	// Type admins scoped to a type or tag may only manage the types they cover
+	if !principal.CanScope(auth.PermTypeManage, auth.Scope{Type: req.Name}) {
+		return forbiddenResponse(auth.PermTypeManage)
+	}
+
+	if errResp := validateTypeName("type", req.Name); errResp != nil {
+		return *errResp
+	}
+
+	if registry.IsBuiltinType(req.Name) {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusConflict,
+			Error:      "a built-in type with this name already exists; add subtypes to it instead",
+		}
+	}
+
+	_, err := s.repo.FindOne(ctx, bson.M{"tenant_id": principal.TenantID, "name": req.Name})
+	if err == nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusConflict,
+			Error:      "type with this name already exists for this tenant",
+		}
+	}
+	if err.Error() != "not found" {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to check existing type: %v", err),
+		}
+	}
+
+	definition := models.TypeDefinition{
+		Base:        &types.Base{},
+		TenantID:    principal.TenantID,
+		Name:        req.Name,
+		Description: req.Description,
+		Subtypes:    map[string]models.SubtypeDefinition{},
+		CreatedBy:   principal.Subject,
+		UpdatedBy:   principal.Subject,
+	}
+
+	created, err := s.repo.InsertOne(ctx, definition)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to create type: %v", err),
+		}
+	}
+
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusCreated,
+		Data:       created.ToResponse(),
+	}
+}
+
+// UpdateType updates the description of a tenant-defined type
+func (s *TypeDefinitionService) UpdateType(ctx context.Context, req models.UpdateTypeRequest) handlers.ServiceResponse {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+	if !principal.CanScope(auth.PermTypeManage, auth.Scope{Type: req.Type}) {
+		return forbiddenResponse(auth.PermTypeManage)
+	}
+
+	if registry.IsBuiltinType(req.Type) {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      "built-in types cannot be modified",
+		}
+	}
+
+	existing, errResp := s.findTenantType(ctx, principal.TenantID, req.Type)
+	if errResp != nil {
+		return *errResp
+	}
+
+	updated, err := s.repo.UpdateByID(ctx, existing.ID, bson.M{"$set": bson.M{
+		"description": req.Description,
+		"updated_by":  principal.Subject,
+	}})
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to update type: %v", err),
+		}
+	}
+
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusOK,
+		Data:       updated.ToResponse(),
+	}
+}
+
+// DeleteType deletes a tenant-defined type, refusing while configs still use it
+func (s *TypeDefinitionService) DeleteType(ctx context.Context, req models.TypeNameRequest) handlers.ServiceResponse {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+	if !principal.CanScope(auth.PermTypeManage, auth.Scope{Type: req.Type}) {
+		return forbiddenResponse(auth.PermTypeManage)
+	}
+
+	existing, errResp := s.findTenantType(ctx, principal.TenantID, req.Type)
+	if errResp != nil {
+		return *errResp
+	}
+
+	// Only the tenant subtypes of a built-in type would be removed, so check those
+	filter := bson.M{"tenant_id": principal.TenantID, "type": req.Type}
+	if registry.IsBuiltinType(req.Type) {
+		subtypeNames := make([]string, 0, len(existing.Subtypes))
+		for name := range existing.Subtypes {
+			subtypeNames = append(subtypeNames, name)
+		}
+		filter["subtype"] = bson.M{"$in": subtypeNames}
+	}
+	if errResp := s.ensureUnused(ctx, filter); errResp != nil {
+		return *errResp
+	}
+
+	_, err := s.repo.DeleteOne(ctx, bson.M{"_id": existing.ID})
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to delete type: %v", err),
+		}
+	}
+
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusOK,
+		Data:       map[string]string{"message": "Type deleted successfully"},
+	}
+}
+
+// CreateSubtype adds a tenant-defined subtype to a tenant or built-in type
+func (s *TypeDefinitionService) CreateSubtype(ctx context.Context, req models.CreateSubtypeRequest) handlers.ServiceResponse {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+	if !principal.CanScope(auth.PermTypeManage, auth.Scope{Type: req.Type, Subtype: req.Name}) {
+		return forbiddenResponse(auth.PermTypeManage)
+	}
+
+	if errResp := validateTypeName("subtype", req.Name); errResp != nil {
+		return *errResp
+	}
+
+	if errs := registry.ValidateSchema(req.MetadataSchema); len(errs) > 0 {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      "invalid metadata schema",
+			Data:       errs,
+		}
+	}
+
+	if registry.IsBuiltinSubtype(req.Type, req.Name) {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusConflict,
+			Error:      "a built-in subtype with this name already exists",
+		}
+	}
+
+	existing, err := s.repo.FindOne(ctx, bson.M{"tenant_id": principal.TenantID, "name": req.Type})
+	if err != nil && err.Error() != "not found" {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to get type: %v", err),
+		}
+	}
+	found := err == nil
+
+	// Subtypes of built-in types get an implicit tenant definition document
+	if !found && !registry.IsBuiltinType(req.Type) {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusNotFound,
+			Error:      "Type not found",
+		}
+	}
+	if found {
+		if _, exists := existing.Subtypes[req.Name]; exists {
+			return handlers.ServiceResponse{
+				StatusCode: http.StatusConflict,
+				Error:      "subtype with this name already exists for this tenant",
+			}
+		}
+	}
+
+	subtype := models.SubtypeDefinition{
+		Name:           req.Name,
+		Description:    req.Description,
+		MetadataSchema: req.MetadataSchema,
+		CreatedBy:      principal.Subject,
+		UpdatedBy:      principal.Subject,
+		UpdatedAt:      time.Now(),
+	}
+
+	if !found {
+		definition := models.TypeDefinition{
+			Base:      &types.Base{},
+			TenantID:  principal.TenantID,
+			Name:      req.Type,
+			Subtypes:  map[string]models.SubtypeDefinition{req.Name: subtype},
+			CreatedBy: principal.Subject,
+			UpdatedBy: principal.Subject,
+		}
+		created, err := s.repo.InsertOne(ctx, definition)
+		if err != nil {
+			return handlers.ServiceResponse{
+				StatusCode: http.StatusInternalServerError,
+				Error:      fmt.Sprintf("failed to create subtype: %v", err),
+			}
+		}
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusCreated,
+			Data:       created.ToResponse(),
+		}
+	}
+
+	updated, err := s.repo.UpdateByID(ctx, existing.ID, bson.M{"$set": bson.M{
+		"subtypes." + req.Name: subtype,
+		"updated_by":           principal.Subject,
+	}})
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to create subtype: %v", err),
+		}
+	}
+
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusCreated,
+		Data:       updated.ToResponse(),
+	}
+}
+
+// UpdateSubtype replaces the description and schema of a tenant-defined subtype
+func (s *TypeDefinitionService) UpdateSubtype(ctx context.Context, req models.UpdateSubtypeRequest) handlers.ServiceResponse {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+	if !principal.CanScope(auth.PermTypeManage, auth.Scope{Type: req.Type, Subtype: req.Subtype}) {
+		return forbiddenResponse(auth.PermTypeManage)
+	}
+
+	if errResp := validateTypeName("subtype", req.Subtype); errResp != nil {
+		return *errResp
+	}
+
+	if registry.IsBuiltinSubtype(req.Type, req.Subtype) {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      "built-in subtypes cannot be modified",
+		}
+	}
+
+	if errs := registry.ValidateSchema(req.MetadataSchema); len(errs) > 0 {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      "invalid metadata schema",
+			Data:       errs,
+		}
+	}
+
+	existing, errResp := s.findTenantType(ctx, principal.TenantID, req.Type)
+	if errResp != nil {
+		return *errResp
+	}
+	current, exists := existing.Subtypes[req.Subtype]
+	if !exists {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusNotFound,
+			Error:      "Subtype not found",
+		}
+	}
+
+	subtype := models.SubtypeDefinition{
+		Name:           req.Subtype,
+		Description:    req.Description,
+		MetadataSchema: req.MetadataSchema,
+		CreatedBy:      current.CreatedBy,
+		UpdatedBy:      principal.Subject,
+		UpdatedAt:      time.Now(),
+	}
+
+	updated, err := s.repo.UpdateByID(ctx, existing.ID, bson.M{"$set": bson.M{
+		"subtypes." + req.Subtype: subtype,
+		"updated_by":              principal.Subject,
+	}})
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to update subtype: %v", err),
+		}
+	}
+
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusOK,
+		Data:       updated.ToResponse(),
+	}
+}
+
+// DeleteSubtype deletes a tenant-defined subtype, refusing while configs still use it
+func (s *TypeDefinitionService) DeleteSubtype(ctx context.Context, req models.SubtypeNameRequest) handlers.ServiceResponse {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+	if !principal.CanScope(auth.PermTypeManage, auth.Scope{Type: req.Type, Subtype: req.Subtype}) {
+		return forbiddenResponse(auth.PermTypeManage)
+	}
+
+	if errResp := validateTypeName("subtype", req.Subtype); errResp != nil {
+		return *errResp
+	}
+
+	if registry.IsBuiltinSubtype(req.Type, req.Subtype) {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusBadRequest,
+			Error:      "built-in subtypes cannot be deleted",
+		}
+	}
+
+	existing, errResp := s.findTenantType(ctx, principal.TenantID, req.Type)
+	if errResp != nil {
+		return *errResp
+	}
+	if _, exists := existing.Subtypes[req.Subtype]; !exists {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusNotFound,
+			Error:      "Subtype not found",
+		}
+	}
+
+	if errResp := s.ensureUnused(ctx, bson.M{
+		"tenant_id": principal.TenantID,
+		"type":      req.Type,
+		"subtype":   req.Subtype,
+	}); errResp != nil {
+		return *errResp
+	}
+
+	updated, err := s.repo.UpdateByID(ctx, existing.ID, bson.M{
+		"$unset": bson.M{"subtypes." + req.Subtype: ""},
+		"$set":   bson.M{"updated_by": principal.Subject},
+	})
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to delete subtype: %v", err),
+		}
+	}
+
+	return handlers.ServiceResponse{
+		StatusCode: http.StatusOK,
+		Data:       updated.ToResponse(),
+	}
+}
+
+// findTenantType loads the tenant definition of a type or returns the error response
+func (s *TypeDefinitionService) findTenantType(ctx context.Context, tenantID, name string) (models.TypeDefinition, *handlers.ServiceResponse) {
+	existing, err := s.repo.FindOne(ctx, bson.M{"tenant_id": tenantID, "name": name})
+	if err != nil {
+		if err.Error() == "not found" {
+			return existing, &handlers.ServiceResponse{
+				StatusCode: http.StatusNotFound,
+				Error:      "Type not found",
+			}
+		}
+		return existing, &handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to get type: %v", err),
+		}
+	}
+	return existing, nil
+}
+
+// ensureUnused returns an error response when configs match the filter
+func (s *TypeDefinitionService) ensureUnused(ctx context.Context, filter bson.M) *handlers.ServiceResponse {
+	count, err := s.configRepo.Count(ctx, filter)
+	if err != nil {
+		return &handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to count configs: %v", err),
+		}
+	}
+	if count > 0 {
+		return &handlers.ServiceResponse{
+			StatusCode: http.StatusConflict,
+			Error:      fmt.Sprintf("%d configs still use this definition", count),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"makatom-api-config/internal/auth"
+	"makatom-api-config/internal/models"
+	"makatom/common/pkg/handlers"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// vaultMount is the path of the emulated KV v2 secrets engine
+const vaultMount = "secret"
+
+// VaultService serves the encrypted fields of configs through a read-only
+// emulation of Vault's KV v2 secrets engine. A secret is a config; its data
+// are the fields encrypted by the subtype schema, decrypted. Secret versions
+// are the config versions kept in the config archive.
+type VaultService struct {
+	configService *ConfigService
+}
+
+// NewVaultService creates a new VaultService instance
+func NewVaultService(configService *ConfigService) *VaultService {
+	return &VaultService{configService: configService}
+}
+
+// ReadSecret serves GET /v1/secret/data/{path...}, optionally at ?version=
+func (s *VaultService) ReadSecret(ctx context.Context, req models.VaultSecretRequest) handlers.ServiceResponse {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+
+	config, errResp := s.findSecret(ctx, principal, req)
+	if errResp != nil {
+		return *errResp
+	}
+	archives, current, err := s.configService.configVersions(ctx, config)
+	if err != nil {
+		return vaultError(http.StatusInternalServerError, fmt.Sprintf("failed to get versions: %v", err))
+	}
+
+	version := req.Version
+	if version == 0 {
+		version = current
+	}
+	metadata, encryptedFields := config.Metadata, config.EncryptedFields
+	if version != current {
+		found := false
+		for _, archive := range archives {
+			if archive.Version == version {
+				metadata, encryptedFields, found = archive.Metadata, archive.EncryptedFields, true
+				break
+			}
+		}
+		if !found {
+			return vaultError(http.StatusNotFound)
+		}
+	}
+
+	// Configs written before schema versions did not record their encrypted fields
+	if len(encryptedFields) == 0 {
+		schemaInfo, err := s.configService.registry.Schema(ctx, config.TenantID, config.Type, config.Subtype)
+		if err != nil {
+			return vaultError(http.StatusInternalServerError, fmt.Sprintf("failed to get schema: %v", err))
+		}
+		encryptedFields = schemaInfo.EncryptedFields
+	}
+
+	decrypted, err := s.configService.registry.DecryptMetadata(ctx, config.TenantID, config.Type, config.Subtype, metadata)
+	if err != nil {
+		return vaultError(http.StatusInternalServerError, fmt.Sprintf("failed to decrypt metadata: %v", err))
+	}
+	data := make(map[string]interface{})
+	for _, field := range encryptedFields {
+		if value, present := decrypted[field]; present {
+			data[field] = value
+		}
+	}
+
+	return vaultResponse(models.VaultSecret{
+		Data: data,
+		Metadata: models.VaultVersion{
+			CreatedTime: vaultTime(versionCreatedAt(config, archives, version)),
+			Version:     version,
+		},
+	})
+}
+
+// ReadSecretMetadata serves GET /v1/secret/metadata/{path...}
+func (s *VaultService) ReadSecretMetadata(ctx context.Context, req models.VaultSecretRequest) handlers.ServiceResponse {
+	principal, ok := auth.FromContext(ctx)
+	if !ok {
+		return unauthorizedResponse()
+	}
+
+	config, errResp := s.findSecret(ctx, principal, req)
+	if errResp != nil {
+		return *errResp
+	}
+	archives, current, err := s.configService.configVersions(ctx, config)
+	if err != nil {
+		return vaultError(http.StatusInternalServerError, fmt.Sprintf("failed to get versions: %v", err))
+	}
+
+	versions := make(map[string]models.VaultVersionState, len(archives)+1)
+	oldest := current
+	for _, archive := range archives {
+		oldest = min(oldest, archive.Version)
+		versions[strconv.Itoa(archive.Version)] = models.VaultVersionState{
+			CreatedTime: vaultTime(versionCreatedAt(config, archives, archive.Version)),
+		}
+	}
+	updated := vaultTime(versionCreatedAt(config, archives, current))
+	versions[strconv.Itoa(current)] = models.VaultVersionState{CreatedTime: updated}
+
+	return vaultResponse(models.VaultSecretMetadata{
+		CreatedTime:        vaultTime(config.CreatedAt),
+		CurrentVersion:     current,
+		DeleteVersionAfter: "0s",
+		MaxVersions:        MaxArchiveHistory + 1,
+		OldestVersion:      oldest,
+		UpdatedTime:        updated,
+		Versions:           versions,
+	})
+}
+
+// MountInfo serves the mount lookup Vault clients use to detect a KV v2 engine
+func (s *VaultService) MountInfo(ctx context.Context, req models.VaultSecretRequest) handlers.ServiceResponse {
+	if req.Path != vaultMount && !strings.HasPrefix(req.Path, vaultMount+"/") {
+		return vaultError(http.StatusNotFound)
+	}
+	return vaultResponse(map[string]interface{}{
+		"path":        vaultMount + "/",
+		"type":        "kv",
+		"description": "config service secrets",
+		"options":     map[string]string{"version": "2"},
+	})
+}
+
+// findSecret loads the config a secret path points at, if the caller may read its secrets
+func (s *VaultService) findSecret(ctx context.Context, principal *auth.Principal, req models.VaultSecretRequest) (models.Config, *handlers.ServiceResponse) {
+	if err := validateNamespace(req.Namespace); err != nil {
+		resp := vaultError(http.StatusBadRequest, err.Error())
+		return models.Config{}, &resp
+	}
+
+	filter := bson.M{"tenant_id": principal.TenantID, "namespace": namespaceFilter(req.Namespace)}
+	segments := strings.Split(req.Path, "/")
+	switch len(segments) {
+	case 2:
+		filter["type"], filter["subtype"], filter["name"] = segments[0], "", segments[1]
+	case 3:
+		filter["type"], filter["subtype"], filter["name"] = segments[0], segments[1], segments[2]
+	default:
+		resp := vaultError(http.StatusNotFound)
+		return models.Config{}, &resp
+	}
+
+	config, err := s.configService.repo.FindOne(ctx, filter)
+	if err != nil {
+		status, messages := http.StatusInternalServerError, []string{fmt.Sprintf("failed to get config: %v", err)}
+		if err.Error() == "not found" {
+			status, messages = http.StatusNotFound, nil
+		}
+		resp := vaultError(status, messages...)
+		return models.Config{}, &resp
+	}
+
+	if !principal.Can(auth.PermSecretRead, config.Resource()) {
+		resp := vaultError(http.StatusForbidden, "permission denied")
+		return models.Config{}, &resp
+	}
+	return config, nil
+}
+
+// versionCreatedAt returns when a version became current: when the version
+// before it was archived, or when the config was created
+func versionCreatedAt(config models.Config, archives []models.ConfigArchive, version int) time.Time {
+	for _, archive := range archives {
+		if archive.Version == version-1 {
+			return archive.ArchivedAt
+		}
+	}
+	return config.CreatedAt
+}
+
+func vaultTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// vaultResponse wraps data in the Vault response document
+func vaultResponse(data interface{}) handlers.ServiceResponse {
+	return vaultDocument(http.StatusOK, models.VaultResponse{
+		RequestID: primitive.NewObjectID().Hex(),
+		Data:      data,
+	})
+}
+
+// vaultError returns a Vault error document; Vault answers 404 with no messages
+func vaultError(status int, messages ...string) handlers.ServiceResponse {
+	if messages == nil {
+		messages = []string{}
+	}
+	return vaultDocument(status, map[string][]string{"errors": messages})
+}
+
+func vaultDocument(status int, document interface{}) handlers.ServiceResponse {
+	body, err := json.Marshal(document)
+	if err != nil {
+		return handlers.ServiceResponse{
+			StatusCode: http.StatusInternalServerError,
+			Error:      fmt.Sprintf("failed to encode response: %v", err),
+		}
+	}
+	return handlers.ServiceResponse{
+		StatusCode: status,
+		Data: models.RawResponse{
+			ContentType: "application/json",
+			Body:        body,
+		},
+	}
+}
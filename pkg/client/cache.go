@@ -0,0 +1,228 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultRefreshInterval is used when CacheOptions.RefreshInterval is zero
+const DefaultRefreshInterval = 30 * time.Second
+
+// CacheOptions configures a Cache
+type CacheOptions struct {
+	// Query selects the configs kept in the cache
+	Query ListOptions
+	// RefreshInterval is the polling interval of the background refresh
+	RefreshInterval time.Duration
+	// SnapshotPath, when set, is where the last-known-good configs are written
+	// after every successful refresh and read from when the service is down
+	SnapshotPath string
+	// OnChange is called after a refresh that changed the cached configs
+	OnChange func(configs []Config)
+	// OnError is called when a background refresh fails
+	OnError func(err error)
+}
+
+// snapshot is the on-disk format of the last-known-good configs
+type snapshot struct {
+	SavedAt time.Time `json:"saved_at"`
+	Configs []Config  `json:"configs"`
+}
+
+// Cache keeps a set of configs in memory and refreshes them in the background
+type Cache struct {
+	client *Client
+	opts   CacheOptions
+
+	mu      sync.RWMutex
+	configs map[string]Config
+	stale   bool
+}
+
+// NewCache creates a new Cache instance
+func NewCache(c *Client, opts CacheOptions) *Cache {
+	if opts.RefreshInterval <= 0 {
+		opts.RefreshInterval = DefaultRefreshInterval
+	}
+	return &Cache{
+		client:  c,
+		opts:    opts,
+		configs: make(map[string]Config),
+	}
+}
+
+// Start loads the configs and keeps refreshing them until ctx is done. When the
+// service is unavailable on start, the snapshot is served instead.
+func (c *Cache) Start(ctx context.Context) error {
+	if err := c.Refresh(ctx); err != nil {
+		if !isUnavailable(err) || c.opts.SnapshotPath == "" {
+			return err
+		}
+		if snapErr := c.loadSnapshot(); snapErr != nil {
+			return fmt.Errorf("config service unavailable (%v) and no usable snapshot: %v", err, snapErr)
+		}
+	}
+
+	go c.run(ctx)
+	return nil
+}
+
+// Refresh fetches the configs once and replaces the cached set
+func (c *Cache) Refresh(ctx context.Context) error {
+	configs, err := c.client.ListAll(ctx, c.opts.Query)
+	if err != nil {
+		return err
+	}
+
+	changed := c.replace(configs, false)
+	if c.opts.SnapshotPath != "" {
+		if err := c.saveSnapshot(configs); err != nil {
+			return fmt.Errorf("failed to save snapshot: %v", err)
+		}
+	}
+	if changed && c.opts.OnChange != nil {
+		c.opts.OnChange(c.All())
+	}
+	return nil
+}
+
+// Get returns a cached config by ID
+func (c *Cache) Get(id string) (Config, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	config, ok := c.configs[id]
+	return config, ok
+}
+
+// GetByName returns a cached config by its natural key
+func (c *Cache) GetByName(configType, subtype, name string) (Config, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, config := range c.configs {
+		if config.Type == configType && config.Subtype == subtype && config.Name == name {
+			return config, true
+		}
+	}
+	return Config{}, false
+}
+
+// All returns every cached config ordered by type, subtype and name
+func (c *Cache) All() []Config {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	configs := make([]Config, 0, len(c.configs))
+	for _, config := range c.configs {
+		configs = append(configs, config)
+	}
+	sort.Slice(configs, func(i, j int) bool {
+		return configKey(configs[i]) < configKey(configs[j])
+	})
+	return configs
+}
+
+// Stale reports whether the cache is serving the on-disk snapshot because the
+// service has not been reachable since start
+func (c *Cache) Stale() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.stale
+}
+
+// run refreshes the cache on every tick until ctx is done
+func (c *Cache) run(ctx context.Context) {
+	ticker := time.NewTicker(c.opts.RefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.Refresh(ctx); err != nil && c.opts.OnError != nil {
+				c.opts.OnError(err)
+			}
+		}
+	}
+}
+
+// replace swaps the cached set and reports whether it changed
+func (c *Cache) replace(configs []Config, stale bool) bool {
+	next := make(map[string]Config, len(configs))
+	for _, config := range configs {
+		next[config.ID] = config
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	changed := len(next) != len(c.configs)
+	if !changed {
+		for id, config := range next {
+			current, ok := c.configs[id]
+			if !ok || !current.UpdatedAt.Equal(config.UpdatedAt) {
+				changed = true
+				break
+			}
+		}
+	}
+
+	c.configs = next
+	c.stale = stale
+	return changed
+}
+
+// loadSnapshot fills the cache from the on-disk snapshot
+func (c *Cache) loadSnapshot() error {
+	raw, err := os.ReadFile(c.opts.SnapshotPath)
+	if err != nil {
+		return err
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(raw, &snap); err != nil {
+		return fmt.Errorf("corrupt snapshot: %v", err)
+	}
+
+	c.replace(snap.Configs, true)
+	return nil
+}
+
+// saveSnapshot atomically writes the configs to the snapshot path. Snapshots
+// may contain decrypted secrets, so the file is only readable by its owner.
+func (c *Cache) saveSnapshot(configs []Config) error {
+	encoded, err := json.Marshal(snapshot{SavedAt: time.Now(), Configs: configs})
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(c.opts.SnapshotPath)
+	tmp, err := os.CreateTemp(dir, ".snapshot-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := tmp.Chmod(0o600); err != nil {
+		tmp.Close()
+		return err
+	}
+	if _, err := tmp.Write(encoded); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.opts.SnapshotPath)
+}
+
+func configKey(config Config) string {
+	return config.Type + "/" + config.Subtype + "/" + config.Name + "/" + config.ID
+}
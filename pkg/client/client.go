@@ -0,0 +1,347 @@
+// Package client is a typed Go client for the Makatom config service.
+//
+// It wraps the HTTP endpoints and the ServiceResponse envelope, and provides a
+// Cache that keeps a set of configs in memory with an on-disk last-known-good
+// snapshot, so consumers can start while the config service is unavailable.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client calls the config service HTTP API
+type Client struct {
+	baseURL       string
+	authorization string
+	httpClient    *http.Client
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithToken authenticates requests with a user JWT
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.authorization = "Bearer " + token
+	}
+}
+
+// WithAPIKey authenticates requests with a service-account API key
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.authorization = "ApiKey " + key
+	}
+}
+
+// WithHTTPClient replaces the default HTTP client
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// New creates a new Client for the config service at baseURL
+func New(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 30 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// envelope is the JSON shape of handlers.ServiceResponse
+type envelope struct {
+	Data  json.RawMessage `json:"data"`
+	Error string          `json:"error"`
+}
+
+// Get retrieves a config by its ID
+func (c *Client) Get(ctx context.Context, id string) (Config, error) {
+	var config Config
+	err := c.do(ctx, http.MethodGet, "/config", url.Values{"id": {id}}, nil, &config)
+	return config, err
+}
+
+// GetResolved retrieves a config by its ID with its config references replaced by their values
+func (c *Client) GetResolved(ctx context.Context, id string) (Config, error) {
+	var config Config
+	err := c.do(ctx, http.MethodGet, "/config", url.Values{"id": {id}, "resolve": {"true"}}, nil, &config)
+	return config, err
+}
+
+// GetEnv retrieves a config by its ID with its metadata merged with an environment overlay
+func (c *Client) GetEnv(ctx context.Context, id, env string) (Config, error) {
+	var config Config
+	err := c.do(ctx, http.MethodGet, "/config", url.Values{"id": {id}, "env": {env}}, nil, &config)
+	return config, err
+}
+
+// EnvironmentDiff lists the metadata keys whose values differ between the environments of a config
+func (c *Client) EnvironmentDiff(ctx context.Context, id string) (EnvironmentDiff, error) {
+	var diff EnvironmentDiff
+	err := c.do(ctx, http.MethodGet, "/config/environments/diff", url.Values{"id": {id}}, nil, &diff)
+	return diff, err
+}
+
+// GetByName retrieves a config by its natural key
+func (c *Client) GetByName(ctx context.Context, configType, subtype, name string) (Config, error) {
+	result, err := c.List(ctx, ListOptions{Name: name, Type: configType, Subtype: subtype, Limit: 1})
+	if err != nil {
+		return Config{}, err
+	}
+	if len(result.Configs) == 0 {
+		return Config{}, &APIError{StatusCode: http.StatusNotFound, Message: "Config not found"}
+	}
+	return result.Configs[0], nil
+}
+
+// GetInherited retrieves a config deep-merged with the same config in the ancestor namespaces
+func (c *Client) GetInherited(ctx context.Context, namespace, configType, subtype, name string) (InheritedConfig, error) {
+	var config InheritedConfig
+	query := url.Values{"namespace": {namespace}, "type": {configType}, "name": {name}}
+	if subtype != "" {
+		query.Set("subtype", subtype)
+	}
+	err := c.do(ctx, http.MethodGet, "/config/inherited", query, nil, &config)
+	return config, err
+}
+
+// List retrieves a page of configs
+func (c *Client) List(ctx context.Context, opts ListOptions) (ListResult, error) {
+	var result ListResult
+	err := c.do(ctx, http.MethodGet, "/configs", opts.values(), nil, &result)
+	return result, err
+}
+
+// ListAll retrieves every config matching opts, following pagination
+func (c *Client) ListAll(ctx context.Context, opts ListOptions) ([]Config, error) {
+	const pageSize = 100
+
+	opts.Skip = 0
+	opts.Limit = pageSize
+
+	var configs []Config
+	for {
+		page, err := c.List(ctx, opts)
+		if err != nil {
+			return nil, err
+		}
+		configs = append(configs, page.Configs...)
+		if len(page.Configs) < pageSize || int64(len(configs)) >= page.Total {
+			return configs, nil
+		}
+		opts.Skip += pageSize
+	}
+}
+
+// Create creates a new config
+func (c *Client) Create(ctx context.Context, req CreateRequest) (Config, error) {
+	var config Config
+	err := c.do(ctx, http.MethodPost, "/config", nil, req, &config)
+	return config, err
+}
+
+// Update updates the tags and metadata of a config
+func (c *Client) Update(ctx context.Context, id string, req UpdateRequest) (Config, error) {
+	var config Config
+	err := c.do(ctx, http.MethodPut, "/config", url.Values{"id": {id}}, req, &config)
+	return config, err
+}
+
+// ScheduleUpdate schedules an update of a config to be applied at applyAt
+func (c *Client) ScheduleUpdate(ctx context.Context, id string, req UpdateRequest, applyAt time.Time) (ScheduledChange, error) {
+	var change ScheduledChange
+	body := struct {
+		UpdateRequest
+		ApplyAt time.Time `json:"apply_at"`
+	}{req, applyAt}
+	err := c.do(ctx, http.MethodPut, "/config", url.Values{"id": {id}}, body, &change)
+	return change, err
+}
+
+// Schedules lists the scheduled updates of a config, optionally filtered by status
+func (c *Client) Schedules(ctx context.Context, id, status string) ([]ScheduledChange, error) {
+	var result struct {
+		Schedules []ScheduledChange `json:"schedules"`
+	}
+	query := url.Values{"config_id": {id}, "limit": {"100"}}
+	if status != "" {
+		query.Set("status", status)
+	}
+	err := c.do(ctx, http.MethodGet, "/config/schedules", query, nil, &result)
+	return result.Schedules, err
+}
+
+// CancelSchedule cancels a pending scheduled update
+func (c *Client) CancelSchedule(ctx context.Context, scheduleID string) (ScheduledChange, error) {
+	var change ScheduledChange
+	err := c.do(ctx, http.MethodDelete, "/config/schedule", url.Values{"id": {scheduleID}}, nil, &change)
+	return change, err
+}
+
+// Delete deletes a config and its archives
+func (c *Client) Delete(ctx context.Context, id string) error {
+	return c.do(ctx, http.MethodDelete, "/config", url.Values{"id": {id}}, nil, nil)
+}
+
+// Archives retrieves the archived versions of a config
+func (c *Client) Archives(ctx context.Context, id string) ([]ConfigArchive, error) {
+	var result struct {
+		Archives []ConfigArchive `json:"archives"`
+	}
+	err := c.do(ctx, http.MethodGet, "/config/archives", url.Values{"id": {id}}, nil, &result)
+	return result.Archives, err
+}
+
+// Restore restores a config from one of its archives
+func (c *Client) Restore(ctx context.Context, id, archiveID string) (Config, error) {
+	var config Config
+	err := c.do(ctx, http.MethodPost, "/config/restore", url.Values{"id": {id}, "archive_id": {archiveID}}, nil, &config)
+	return config, err
+}
+
+// Decrypt decrypts a single encrypted metadata field of a config
+func (c *Client) Decrypt(ctx context.Context, id, field string) (DecryptResult, error) {
+	var result DecryptResult
+	body := map[string]string{"config_id": id, "field_name": field}
+	err := c.do(ctx, http.MethodPost, "/config/decrypt", nil, body, &result)
+	return result, err
+}
+
+// EvaluateFlag evaluates a feature flag for an evaluation context
+func (c *Client) EvaluateFlag(ctx context.Context, req EvaluateFlagRequest) (FlagEvaluation, error) {
+	var evaluation FlagEvaluation
+	err := c.do(ctx, http.MethodPost, "/flags/evaluate", nil, req, &evaluation)
+	return evaluation, err
+}
+
+// do performs a request and decodes the data of the response envelope into out
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	resp, err := c.send(ctx, method, path, query, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return decodeEnvelope(resp, out)
+}
+
+// send builds and sends a request, returning the raw response
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, body interface{}) (*http.Response, error) {
+	endpoint := c.baseURL + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode request: %v", err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.authorization != "" {
+		req.Header.Set("Authorization", c.authorization)
+	}
+
+	return c.httpClient.Do(req)
+}
+
+// decodeEnvelope unwraps the ServiceResponse envelope of resp into out
+func decodeEnvelope(resp *http.Response, out interface{}) error {
+	raw, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %v", err)
+	}
+
+	var env envelope
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &env); err != nil {
+			if resp.StatusCode >= 300 {
+				return &APIError{StatusCode: resp.StatusCode, Message: strings.TrimSpace(string(raw))}
+			}
+			return fmt.Errorf("failed to decode response: %v", err)
+		}
+	}
+
+	if resp.StatusCode >= 300 {
+		apiErr := &APIError{StatusCode: resp.StatusCode, Message: env.Error}
+		if apiErr.Message == "" {
+			apiErr.Message = http.StatusText(resp.StatusCode)
+		}
+		if len(env.Data) > 0 {
+			json.Unmarshal(env.Data, &apiErr.Details)
+		}
+		return apiErr
+	}
+
+	if out == nil || len(env.Data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(env.Data, out); err != nil {
+		return fmt.Errorf("failed to decode response data: %v", err)
+	}
+	return nil
+}
+
+// values converts the options to query parameters
+func (o ListOptions) values() url.Values {
+	values := url.Values{}
+	if o.Name != "" {
+		values.Set("name", o.Name)
+	}
+	if o.Namespace != "" {
+		values.Set("namespace", o.Namespace)
+	}
+	if o.NamespacePrefix != "" {
+		values.Set("namespace_prefix", o.NamespacePrefix)
+	}
+	if o.Type != "" {
+		values.Set("type", o.Type)
+	}
+	if o.Subtype != "" {
+		values.Set("subtype", o.Subtype)
+	}
+	if o.Tag != "" {
+		values.Set("tag", o.Tag)
+	}
+	if o.Limit > 0 {
+		values.Set("limit", strconv.FormatInt(o.Limit, 10))
+	}
+	if o.Skip > 0 {
+		values.Set("skip", strconv.FormatInt(o.Skip, 10))
+	}
+	return values
+}
+
+// isUnavailable reports whether err means the service could not be reached or is failing
+func isUnavailable(err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode >= 500
+	}
+	return err != nil
+}
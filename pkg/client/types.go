@@ -0,0 +1,181 @@
+package client
+
+import (
+	"fmt"
+	"time"
+)
+
+// EnvOverlays maps environment names to metadata merged over the base metadata
+type EnvOverlays map[string]map[string]interface{}
+
+// Config mirrors the config representation returned by the config service
+type Config struct {
+	ID              string                 `json:"id"`
+	Name            string                 `json:"name"`
+	Namespace       string                 `json:"namespace,omitempty"`
+	Type            string                 `json:"type"`
+	Subtype         string                 `json:"subtype,omitempty"`
+	Tags            []string               `json:"tags,omitempty"`
+	TenantID        string                 `json:"tenant_id"`
+	CreatedBy       string                 `json:"created_by"`
+	LastUpdatedBy   string                 `json:"last_updated_by"`
+	Metadata        map[string]interface{} `json:"metadata,omitempty"`
+	SchemaVersion   string                 `json:"schema_version,omitempty"`
+	EncryptedFields []string               `json:"encrypted_fields,omitempty"`
+	Overlays        EnvOverlays            `json:"overlays,omitempty"`
+	Env             string                 `json:"env,omitempty"`
+	// DecryptionError is set on a listed config the server could not decrypt
+	DecryptionError string    `json:"decryption_error,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// ConfigArchive mirrors an archived config version
+type ConfigArchive struct {
+	ID            string                 `json:"id"`
+	ConfigID      string                 `json:"config_id"`
+	Name          string                 `json:"name"`
+	Namespace     string                 `json:"namespace,omitempty"`
+	Type          string                 `json:"type"`
+	Subtype       string                 `json:"subtype,omitempty"`
+	Tags          []string               `json:"tags,omitempty"`
+	TenantID      string                 `json:"tenant_id"`
+	CreatedBy     string                 `json:"created_by"`
+	LastUpdatedBy string                 `json:"last_updated_by"`
+	Metadata      map[string]interface{} `json:"metadata,omitempty"`
+	Version       int                    `json:"version"`
+	SchemaVersion string                 `json:"schema_version,omitempty"`
+	ArchivedAt    time.Time              `json:"archived_at"`
+	ArchivedBy    string                 `json:"archived_by"`
+	CreatedAt     time.Time              `json:"created_at"`
+}
+
+// ListOptions filters and paginates List. Namespace selects one namespace;
+// NamespacePrefix selects a namespace and every namespace below it.
+type ListOptions struct {
+	Name            string
+	Namespace       string
+	NamespacePrefix string
+	Type            string
+	Subtype         string
+	Tag             string
+	Limit           int64
+	Skip            int64
+}
+
+// ListResult is a page of configs
+type ListResult struct {
+	Configs []Config `json:"configs"`
+	Total   int64    `json:"total"`
+	Limit   int64    `json:"limit"`
+	Skip    int64    `json:"skip"`
+}
+
+// CreateRequest is the payload of Create
+type CreateRequest struct {
+	Name      string                 `json:"name"`
+	Namespace string                 `json:"namespace,omitempty"`
+	Type      string                 `json:"type"`
+	Subtype   string                 `json:"subtype,omitempty"`
+	Tags      []string               `json:"tags,omitempty"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	Overlays  EnvOverlays            `json:"overlays,omitempty"`
+}
+
+// UpdateRequest is the payload of Update. Only tags, metadata and overlays can
+// change; a non-nil Overlays replaces all overlays.
+type UpdateRequest struct {
+	Tags     []string               `json:"tags,omitempty"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	Overlays EnvOverlays            `json:"overlays,omitempty"`
+}
+
+// ScheduledChange is an update the config service applies at ApplyAt
+type ScheduledChange struct {
+	ID          string                 `json:"id"`
+	ConfigID    string                 `json:"config_id"`
+	Name        string                 `json:"name"`
+	Type        string                 `json:"type"`
+	Subtype     string                 `json:"subtype,omitempty"`
+	Tags        []string               `json:"tags,omitempty"`
+	Metadata    map[string]interface{} `json:"metadata,omitempty"`
+	Overlays    EnvOverlays            `json:"overlays,omitempty"`
+	ApplyAt     time.Time              `json:"apply_at"`
+	Status      string                 `json:"status"`
+	CreatedBy   string                 `json:"created_by"`
+	AppliedAt   *time.Time             `json:"applied_at,omitempty"`
+	CancelledBy string                 `json:"cancelled_by,omitempty"`
+	Error       string                 `json:"error,omitempty"`
+}
+
+// EnvironmentDiff compares the base metadata with the merged view of every environment
+type EnvironmentDiff struct {
+	ConfigID     string                            `json:"config_id"`
+	Environments []string                          `json:"environments"`
+	Differing    map[string]map[string]interface{} `json:"differing"`
+	Identical    []string                          `json:"identical"`
+	Redacted     []string                          `json:"redacted"`
+}
+
+// InheritedConfig is a config deep-merged with its ancestor namespaces
+type InheritedConfig struct {
+	Namespace  string                 `json:"namespace"`
+	Name       string                 `json:"name"`
+	Type       string                 `json:"type"`
+	Subtype    string                 `json:"subtype,omitempty"`
+	Metadata   map[string]interface{} `json:"metadata"`
+	Provenance map[string]string      `json:"provenance"`
+	Sources    []InheritedSource      `json:"sources"`
+}
+
+// InheritedSource is one namespace level that contributed to an InheritedConfig
+type InheritedSource struct {
+	Namespace     string    `json:"namespace"`
+	ConfigID      string    `json:"config_id"`
+	SchemaVersion string    `json:"schema_version,omitempty"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// DecryptResult is the decrypted value of a single field
+type DecryptResult struct {
+	ConfigID       string      `json:"config_id"`
+	FieldName      string      `json:"field_name"`
+	DecryptedValue interface{} `json:"decrypted_value"`
+}
+
+// EvaluateFlagRequest selects a feature flag and the context to evaluate it for
+type EvaluateFlagRequest struct {
+	Key       string                 `json:"key"`
+	Namespace string                 `json:"namespace,omitempty"`
+	Env       string                 `json:"env,omitempty"`
+	Context   map[string]interface{} `json:"context"`
+}
+
+// FlagEvaluation is the variant a feature flag serves and the reason it was chosen
+type FlagEvaluation struct {
+	Key       string      `json:"key"`
+	ConfigID  string      `json:"config_id"`
+	Variant   string      `json:"variant"`
+	Value     interface{} `json:"value"`
+	Reason    string      `json:"reason"`
+	Rule      string      `json:"rule,omitempty"`
+	ErrorCode string      `json:"error_code,omitempty"`
+}
+
+// APIError is returned when the config service answers with a non-2xx status
+type APIError struct {
+	StatusCode int
+	Message    string
+	// Details carries the response data, e.g. a metadata validation result
+	Details interface{}
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("config service returned %d: %s", e.StatusCode, e.Message)
+}
+
+// IsNotFound reports whether err is a 404 from the config service
+func IsNotFound(err error) bool {
+	apiErr, ok := err.(*APIError)
+	return ok && apiErr.StatusCode == 404
+}
@@ -0,0 +1,1553 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: makatom/config/v1/config.proto
+
+// The config service over gRPC. Every RPC runs through the same service layer
+// as the HTTP API, so validation, encryption, authorization and archiving
+// behave identically. Authenticate with the "authorization" metadata key,
+// carrying "Bearer <token>" or "ApiKey <key>" as on HTTP.
+
+package configpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ConfigEvent_Kind int32
+
+const (
+	ConfigEvent_KIND_UNSPECIFIED ConfigEvent_Kind = 0
+	ConfigEvent_KIND_CREATED     ConfigEvent_Kind = 1
+	ConfigEvent_KIND_UPDATED     ConfigEvent_Kind = 2
+	ConfigEvent_KIND_DELETED     ConfigEvent_Kind = 3
+)
+
+// Enum value maps for ConfigEvent_Kind.
+var (
+	ConfigEvent_Kind_name = map[int32]string{
+		0: "KIND_UNSPECIFIED",
+		1: "KIND_CREATED",
+		2: "KIND_UPDATED",
+		3: "KIND_DELETED",
+	}
+	ConfigEvent_Kind_value = map[string]int32{
+		"KIND_UNSPECIFIED": 0,
+		"KIND_CREATED":     1,
+		"KIND_UPDATED":     2,
+		"KIND_DELETED":     3,
+	}
+)
+
+func (x ConfigEvent_Kind) Enum() *ConfigEvent_Kind {
+	p := new(ConfigEvent_Kind)
+	*p = x
+	return p
+}
+
+func (x ConfigEvent_Kind) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ConfigEvent_Kind) Descriptor() protoreflect.EnumDescriptor {
+	return file_makatom_config_v1_config_proto_enumTypes[0].Descriptor()
+}
+
+func (ConfigEvent_Kind) Type() protoreflect.EnumType {
+	return &file_makatom_config_v1_config_proto_enumTypes[0]
+}
+
+func (x ConfigEvent_Kind) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ConfigEvent_Kind.Descriptor instead.
+func (ConfigEvent_Kind) EnumDescriptor() ([]byte, []int) {
+	return file_makatom_config_v1_config_proto_rawDescGZIP(), []int{15, 0}
+}
+
+type Config struct {
+	state           protoimpl.MessageState      `protogen:"open.v1"`
+	Id              string                      `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name            string                      `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Namespace       string                      `protobuf:"bytes,3,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Type            string                      `protobuf:"bytes,4,opt,name=type,proto3" json:"type,omitempty"`
+	Subtype         string                      `protobuf:"bytes,5,opt,name=subtype,proto3" json:"subtype,omitempty"`
+	Tags            []string                    `protobuf:"bytes,6,rep,name=tags,proto3" json:"tags,omitempty"`
+	TenantId        string                      `protobuf:"bytes,7,opt,name=tenant_id,json=tenantId,proto3" json:"tenant_id,omitempty"`
+	CreatedBy       string                      `protobuf:"bytes,8,opt,name=created_by,json=createdBy,proto3" json:"created_by,omitempty"`
+	LastUpdatedBy   string                      `protobuf:"bytes,9,opt,name=last_updated_by,json=lastUpdatedBy,proto3" json:"last_updated_by,omitempty"`
+	Metadata        *structpb.Struct            `protobuf:"bytes,10,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	SchemaVersion   string                      `protobuf:"bytes,11,opt,name=schema_version,json=schemaVersion,proto3" json:"schema_version,omitempty"`
+	EncryptedFields []string                    `protobuf:"bytes,12,rep,name=encrypted_fields,json=encryptedFields,proto3" json:"encrypted_fields,omitempty"`
+	Overlays        map[string]*structpb.Struct `protobuf:"bytes,13,rep,name=overlays,proto3" json:"overlays,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// env is the environment merged into metadata, when one was requested
+	Env       string                 `protobuf:"bytes,14,opt,name=env,proto3" json:"env,omitempty"`
+	CreatedAt *timestamppb.Timestamp `protobuf:"bytes,15,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt *timestamppb.Timestamp `protobuf:"bytes,16,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	// decryption_error is set on a listed config that could not be decrypted;
+	// metadata and overlays then hold the stored ciphertext
+	DecryptionError string `protobuf:"bytes,17,opt,name=decryption_error,json=decryptionError,proto3" json:"decryption_error,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *Config) Reset() {
+	*x = Config{}
+	mi := &file_makatom_config_v1_config_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Config) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Config) ProtoMessage() {}
+
+func (x *Config) ProtoReflect() protoreflect.Message {
+	mi := &file_makatom_config_v1_config_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Config.ProtoReflect.Descriptor instead.
+func (*Config) Descriptor() ([]byte, []int) {
+	return file_makatom_config_v1_config_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Config) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Config) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Config) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *Config) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Config) GetSubtype() string {
+	if x != nil {
+		return x.Subtype
+	}
+	return ""
+}
+
+func (x *Config) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *Config) GetTenantId() string {
+	if x != nil {
+		return x.TenantId
+	}
+	return ""
+}
+
+func (x *Config) GetCreatedBy() string {
+	if x != nil {
+		return x.CreatedBy
+	}
+	return ""
+}
+
+func (x *Config) GetLastUpdatedBy() string {
+	if x != nil {
+		return x.LastUpdatedBy
+	}
+	return ""
+}
+
+func (x *Config) GetMetadata() *structpb.Struct {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *Config) GetSchemaVersion() string {
+	if x != nil {
+		return x.SchemaVersion
+	}
+	return ""
+}
+
+func (x *Config) GetEncryptedFields() []string {
+	if x != nil {
+		return x.EncryptedFields
+	}
+	return nil
+}
+
+func (x *Config) GetOverlays() map[string]*structpb.Struct {
+	if x != nil {
+		return x.Overlays
+	}
+	return nil
+}
+
+func (x *Config) GetEnv() string {
+	if x != nil {
+		return x.Env
+	}
+	return ""
+}
+
+func (x *Config) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Config) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+func (x *Config) GetDecryptionError() string {
+	if x != nil {
+		return x.DecryptionError
+	}
+	return ""
+}
+
+type ConfigArchive struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	ConfigId      string                 `protobuf:"bytes,2,opt,name=config_id,json=configId,proto3" json:"config_id,omitempty"`
+	Name          string                 `protobuf:"bytes,3,opt,name=name,proto3" json:"name,omitempty"`
+	Namespace     string                 `protobuf:"bytes,4,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Type          string                 `protobuf:"bytes,5,opt,name=type,proto3" json:"type,omitempty"`
+	Subtype       string                 `protobuf:"bytes,6,opt,name=subtype,proto3" json:"subtype,omitempty"`
+	Tags          []string               `protobuf:"bytes,7,rep,name=tags,proto3" json:"tags,omitempty"`
+	TenantId      string                 `protobuf:"bytes,8,opt,name=tenant_id,json=tenantId,proto3" json:"tenant_id,omitempty"`
+	CreatedBy     string                 `protobuf:"bytes,9,opt,name=created_by,json=createdBy,proto3" json:"created_by,omitempty"`
+	LastUpdatedBy string                 `protobuf:"bytes,10,opt,name=last_updated_by,json=lastUpdatedBy,proto3" json:"last_updated_by,omitempty"`
+	Metadata      *structpb.Struct       `protobuf:"bytes,11,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	Version       int32                  `protobuf:"varint,12,opt,name=version,proto3" json:"version,omitempty"`
+	SchemaVersion string                 `protobuf:"bytes,13,opt,name=schema_version,json=schemaVersion,proto3" json:"schema_version,omitempty"`
+	ArchivedAt    *timestamppb.Timestamp `protobuf:"bytes,14,opt,name=archived_at,json=archivedAt,proto3" json:"archived_at,omitempty"`
+	ArchivedBy    string                 `protobuf:"bytes,15,opt,name=archived_by,json=archivedBy,proto3" json:"archived_by,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,16,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConfigArchive) Reset() {
+	*x = ConfigArchive{}
+	mi := &file_makatom_config_v1_config_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConfigArchive) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConfigArchive) ProtoMessage() {}
+
+func (x *ConfigArchive) ProtoReflect() protoreflect.Message {
+	mi := &file_makatom_config_v1_config_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConfigArchive.ProtoReflect.Descriptor instead.
+func (*ConfigArchive) Descriptor() ([]byte, []int) {
+	return file_makatom_config_v1_config_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ConfigArchive) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *ConfigArchive) GetConfigId() string {
+	if x != nil {
+		return x.ConfigId
+	}
+	return ""
+}
+
+func (x *ConfigArchive) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ConfigArchive) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *ConfigArchive) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *ConfigArchive) GetSubtype() string {
+	if x != nil {
+		return x.Subtype
+	}
+	return ""
+}
+
+func (x *ConfigArchive) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *ConfigArchive) GetTenantId() string {
+	if x != nil {
+		return x.TenantId
+	}
+	return ""
+}
+
+func (x *ConfigArchive) GetCreatedBy() string {
+	if x != nil {
+		return x.CreatedBy
+	}
+	return ""
+}
+
+func (x *ConfigArchive) GetLastUpdatedBy() string {
+	if x != nil {
+		return x.LastUpdatedBy
+	}
+	return ""
+}
+
+func (x *ConfigArchive) GetMetadata() *structpb.Struct {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *ConfigArchive) GetVersion() int32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *ConfigArchive) GetSchemaVersion() string {
+	if x != nil {
+		return x.SchemaVersion
+	}
+	return ""
+}
+
+func (x *ConfigArchive) GetArchivedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ArchivedAt
+	}
+	return nil
+}
+
+func (x *ConfigArchive) GetArchivedBy() string {
+	if x != nil {
+		return x.ArchivedBy
+	}
+	return ""
+}
+
+func (x *ConfigArchive) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+type CreateConfigRequest struct {
+	state         protoimpl.MessageState      `protogen:"open.v1"`
+	Name          string                      `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Namespace     string                      `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Type          string                      `protobuf:"bytes,3,opt,name=type,proto3" json:"type,omitempty"`
+	Subtype       string                      `protobuf:"bytes,4,opt,name=subtype,proto3" json:"subtype,omitempty"`
+	Tags          []string                    `protobuf:"bytes,5,rep,name=tags,proto3" json:"tags,omitempty"`
+	Metadata      *structpb.Struct            `protobuf:"bytes,6,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	Overlays      map[string]*structpb.Struct `protobuf:"bytes,7,rep,name=overlays,proto3" json:"overlays,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateConfigRequest) Reset() {
+	*x = CreateConfigRequest{}
+	mi := &file_makatom_config_v1_config_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateConfigRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateConfigRequest) ProtoMessage() {}
+
+func (x *CreateConfigRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_makatom_config_v1_config_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateConfigRequest.ProtoReflect.Descriptor instead.
+func (*CreateConfigRequest) Descriptor() ([]byte, []int) {
+	return file_makatom_config_v1_config_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *CreateConfigRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CreateConfigRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *CreateConfigRequest) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *CreateConfigRequest) GetSubtype() string {
+	if x != nil {
+		return x.Subtype
+	}
+	return ""
+}
+
+func (x *CreateConfigRequest) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *CreateConfigRequest) GetMetadata() *structpb.Struct {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *CreateConfigRequest) GetOverlays() map[string]*structpb.Struct {
+	if x != nil {
+		return x.Overlays
+	}
+	return nil
+}
+
+type GetConfigRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Env           string                 `protobuf:"bytes,2,opt,name=env,proto3" json:"env,omitempty"`
+	Resolve       bool                   `protobuf:"varint,3,opt,name=resolve,proto3" json:"resolve,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetConfigRequest) Reset() {
+	*x = GetConfigRequest{}
+	mi := &file_makatom_config_v1_config_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetConfigRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetConfigRequest) ProtoMessage() {}
+
+func (x *GetConfigRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_makatom_config_v1_config_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetConfigRequest.ProtoReflect.Descriptor instead.
+func (*GetConfigRequest) Descriptor() ([]byte, []int) {
+	return file_makatom_config_v1_config_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetConfigRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *GetConfigRequest) GetEnv() string {
+	if x != nil {
+		return x.Env
+	}
+	return ""
+}
+
+func (x *GetConfigRequest) GetResolve() bool {
+	if x != nil {
+		return x.Resolve
+	}
+	return false
+}
+
+type ListConfigsRequest struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Name            string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Namespace       string                 `protobuf:"bytes,2,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	NamespacePrefix string                 `protobuf:"bytes,3,opt,name=namespace_prefix,json=namespacePrefix,proto3" json:"namespace_prefix,omitempty"`
+	Type            string                 `protobuf:"bytes,4,opt,name=type,proto3" json:"type,omitempty"`
+	Subtype         string                 `protobuf:"bytes,5,opt,name=subtype,proto3" json:"subtype,omitempty"`
+	Tag             string                 `protobuf:"bytes,6,opt,name=tag,proto3" json:"tag,omitempty"`
+	Limit           int64                  `protobuf:"varint,7,opt,name=limit,proto3" json:"limit,omitempty"`
+	Skip            int64                  `protobuf:"varint,8,opt,name=skip,proto3" json:"skip,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *ListConfigsRequest) Reset() {
+	*x = ListConfigsRequest{}
+	mi := &file_makatom_config_v1_config_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListConfigsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListConfigsRequest) ProtoMessage() {}
+
+func (x *ListConfigsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_makatom_config_v1_config_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListConfigsRequest.ProtoReflect.Descriptor instead.
+func (*ListConfigsRequest) Descriptor() ([]byte, []int) {
+	return file_makatom_config_v1_config_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ListConfigsRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *ListConfigsRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *ListConfigsRequest) GetNamespacePrefix() string {
+	if x != nil {
+		return x.NamespacePrefix
+	}
+	return ""
+}
+
+func (x *ListConfigsRequest) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *ListConfigsRequest) GetSubtype() string {
+	if x != nil {
+		return x.Subtype
+	}
+	return ""
+}
+
+func (x *ListConfigsRequest) GetTag() string {
+	if x != nil {
+		return x.Tag
+	}
+	return ""
+}
+
+func (x *ListConfigsRequest) GetLimit() int64 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *ListConfigsRequest) GetSkip() int64 {
+	if x != nil {
+		return x.Skip
+	}
+	return 0
+}
+
+type ListConfigsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Configs       []*Config              `protobuf:"bytes,1,rep,name=configs,proto3" json:"configs,omitempty"`
+	Total         int64                  `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListConfigsResponse) Reset() {
+	*x = ListConfigsResponse{}
+	mi := &file_makatom_config_v1_config_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListConfigsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListConfigsResponse) ProtoMessage() {}
+
+func (x *ListConfigsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_makatom_config_v1_config_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListConfigsResponse.ProtoReflect.Descriptor instead.
+func (*ListConfigsResponse) Descriptor() ([]byte, []int) {
+	return file_makatom_config_v1_config_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ListConfigsResponse) GetConfigs() []*Config {
+	if x != nil {
+		return x.Configs
+	}
+	return nil
+}
+
+func (x *ListConfigsResponse) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+// UpdateConfigRequest changes the parts of a config that are set. Proto3 cannot
+// tell an empty list from an absent one, so set replace_tags to replace the
+// tags and replace_overlays to replace the overlays, e.g. with none.
+type UpdateConfigRequest struct {
+	state           protoimpl.MessageState      `protogen:"open.v1"`
+	Id              string                      `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Tags            []string                    `protobuf:"bytes,2,rep,name=tags,proto3" json:"tags,omitempty"`
+	ReplaceTags     bool                        `protobuf:"varint,3,opt,name=replace_tags,json=replaceTags,proto3" json:"replace_tags,omitempty"`
+	Metadata        *structpb.Struct            `protobuf:"bytes,4,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	Overlays        map[string]*structpb.Struct `protobuf:"bytes,5,rep,name=overlays,proto3" json:"overlays,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	ReplaceOverlays bool                        `protobuf:"varint,6,opt,name=replace_overlays,json=replaceOverlays,proto3" json:"replace_overlays,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *UpdateConfigRequest) Reset() {
+	*x = UpdateConfigRequest{}
+	mi := &file_makatom_config_v1_config_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UpdateConfigRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UpdateConfigRequest) ProtoMessage() {}
+
+func (x *UpdateConfigRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_makatom_config_v1_config_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UpdateConfigRequest.ProtoReflect.Descriptor instead.
+func (*UpdateConfigRequest) Descriptor() ([]byte, []int) {
+	return file_makatom_config_v1_config_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *UpdateConfigRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *UpdateConfigRequest) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *UpdateConfigRequest) GetReplaceTags() bool {
+	if x != nil {
+		return x.ReplaceTags
+	}
+	return false
+}
+
+func (x *UpdateConfigRequest) GetMetadata() *structpb.Struct {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *UpdateConfigRequest) GetOverlays() map[string]*structpb.Struct {
+	if x != nil {
+		return x.Overlays
+	}
+	return nil
+}
+
+func (x *UpdateConfigRequest) GetReplaceOverlays() bool {
+	if x != nil {
+		return x.ReplaceOverlays
+	}
+	return false
+}
+
+type DeleteConfigRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteConfigRequest) Reset() {
+	*x = DeleteConfigRequest{}
+	mi := &file_makatom_config_v1_config_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteConfigRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteConfigRequest) ProtoMessage() {}
+
+func (x *DeleteConfigRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_makatom_config_v1_config_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteConfigRequest.ProtoReflect.Descriptor instead.
+func (*DeleteConfigRequest) Descriptor() ([]byte, []int) {
+	return file_makatom_config_v1_config_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *DeleteConfigRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type DeleteConfigResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Message       string                 `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteConfigResponse) Reset() {
+	*x = DeleteConfigResponse{}
+	mi := &file_makatom_config_v1_config_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteConfigResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteConfigResponse) ProtoMessage() {}
+
+func (x *DeleteConfigResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_makatom_config_v1_config_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteConfigResponse.ProtoReflect.Descriptor instead.
+func (*DeleteConfigResponse) Descriptor() ([]byte, []int) {
+	return file_makatom_config_v1_config_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *DeleteConfigResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type ListArchivesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ConfigId      string                 `protobuf:"bytes,1,opt,name=config_id,json=configId,proto3" json:"config_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListArchivesRequest) Reset() {
+	*x = ListArchivesRequest{}
+	mi := &file_makatom_config_v1_config_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListArchivesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListArchivesRequest) ProtoMessage() {}
+
+func (x *ListArchivesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_makatom_config_v1_config_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListArchivesRequest.ProtoReflect.Descriptor instead.
+func (*ListArchivesRequest) Descriptor() ([]byte, []int) {
+	return file_makatom_config_v1_config_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ListArchivesRequest) GetConfigId() string {
+	if x != nil {
+		return x.ConfigId
+	}
+	return ""
+}
+
+type ListArchivesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Archives      []*ConfigArchive       `protobuf:"bytes,1,rep,name=archives,proto3" json:"archives,omitempty"`
+	Total         int64                  `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListArchivesResponse) Reset() {
+	*x = ListArchivesResponse{}
+	mi := &file_makatom_config_v1_config_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListArchivesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListArchivesResponse) ProtoMessage() {}
+
+func (x *ListArchivesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_makatom_config_v1_config_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListArchivesResponse.ProtoReflect.Descriptor instead.
+func (*ListArchivesResponse) Descriptor() ([]byte, []int) {
+	return file_makatom_config_v1_config_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *ListArchivesResponse) GetArchives() []*ConfigArchive {
+	if x != nil {
+		return x.Archives
+	}
+	return nil
+}
+
+func (x *ListArchivesResponse) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+type RestoreConfigRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	ArchiveId     string                 `protobuf:"bytes,2,opt,name=archive_id,json=archiveId,proto3" json:"archive_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RestoreConfigRequest) Reset() {
+	*x = RestoreConfigRequest{}
+	mi := &file_makatom_config_v1_config_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RestoreConfigRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RestoreConfigRequest) ProtoMessage() {}
+
+func (x *RestoreConfigRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_makatom_config_v1_config_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RestoreConfigRequest.ProtoReflect.Descriptor instead.
+func (*RestoreConfigRequest) Descriptor() ([]byte, []int) {
+	return file_makatom_config_v1_config_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *RestoreConfigRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *RestoreConfigRequest) GetArchiveId() string {
+	if x != nil {
+		return x.ArchiveId
+	}
+	return ""
+}
+
+type DecryptFieldRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ConfigId      string                 `protobuf:"bytes,1,opt,name=config_id,json=configId,proto3" json:"config_id,omitempty"`
+	FieldName     string                 `protobuf:"bytes,2,opt,name=field_name,json=fieldName,proto3" json:"field_name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DecryptFieldRequest) Reset() {
+	*x = DecryptFieldRequest{}
+	mi := &file_makatom_config_v1_config_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DecryptFieldRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DecryptFieldRequest) ProtoMessage() {}
+
+func (x *DecryptFieldRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_makatom_config_v1_config_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DecryptFieldRequest.ProtoReflect.Descriptor instead.
+func (*DecryptFieldRequest) Descriptor() ([]byte, []int) {
+	return file_makatom_config_v1_config_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *DecryptFieldRequest) GetConfigId() string {
+	if x != nil {
+		return x.ConfigId
+	}
+	return ""
+}
+
+func (x *DecryptFieldRequest) GetFieldName() string {
+	if x != nil {
+		return x.FieldName
+	}
+	return ""
+}
+
+type DecryptFieldResponse struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	ConfigId       string                 `protobuf:"bytes,1,opt,name=config_id,json=configId,proto3" json:"config_id,omitempty"`
+	FieldName      string                 `protobuf:"bytes,2,opt,name=field_name,json=fieldName,proto3" json:"field_name,omitempty"`
+	DecryptedValue *structpb.Value        `protobuf:"bytes,3,opt,name=decrypted_value,json=decryptedValue,proto3" json:"decrypted_value,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *DecryptFieldResponse) Reset() {
+	*x = DecryptFieldResponse{}
+	mi := &file_makatom_config_v1_config_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DecryptFieldResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DecryptFieldResponse) ProtoMessage() {}
+
+func (x *DecryptFieldResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_makatom_config_v1_config_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DecryptFieldResponse.ProtoReflect.Descriptor instead.
+func (*DecryptFieldResponse) Descriptor() ([]byte, []int) {
+	return file_makatom_config_v1_config_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *DecryptFieldResponse) GetConfigId() string {
+	if x != nil {
+		return x.ConfigId
+	}
+	return ""
+}
+
+func (x *DecryptFieldResponse) GetFieldName() string {
+	if x != nil {
+		return x.FieldName
+	}
+	return ""
+}
+
+func (x *DecryptFieldResponse) GetDecryptedValue() *structpb.Value {
+	if x != nil {
+		return x.DecryptedValue
+	}
+	return nil
+}
+
+type WatchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Namespace     string                 `protobuf:"bytes,1,opt,name=namespace,proto3" json:"namespace,omitempty"`
+	Type          string                 `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	Subtype       string                 `protobuf:"bytes,3,opt,name=subtype,proto3" json:"subtype,omitempty"`
+	Name          string                 `protobuf:"bytes,4,opt,name=name,proto3" json:"name,omitempty"`
+	Tag           string                 `protobuf:"bytes,5,opt,name=tag,proto3" json:"tag,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchRequest) Reset() {
+	*x = WatchRequest{}
+	mi := &file_makatom_config_v1_config_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchRequest) ProtoMessage() {}
+
+func (x *WatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_makatom_config_v1_config_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchRequest.ProtoReflect.Descriptor instead.
+func (*WatchRequest) Descriptor() ([]byte, []int) {
+	return file_makatom_config_v1_config_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *WatchRequest) GetNamespace() string {
+	if x != nil {
+		return x.Namespace
+	}
+	return ""
+}
+
+func (x *WatchRequest) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *WatchRequest) GetSubtype() string {
+	if x != nil {
+		return x.Subtype
+	}
+	return ""
+}
+
+func (x *WatchRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *WatchRequest) GetTag() string {
+	if x != nil {
+		return x.Tag
+	}
+	return ""
+}
+
+type ConfigEvent struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Kind     ConfigEvent_Kind       `protobuf:"varint,1,opt,name=kind,proto3,enum=makatom.config.v1.ConfigEvent_Kind" json:"kind,omitempty"`
+	ConfigId string                 `protobuf:"bytes,2,opt,name=config_id,json=configId,proto3" json:"config_id,omitempty"`
+	// config is the config after the change; for deletions, the config before it
+	Config        *Config                `protobuf:"bytes,3,opt,name=config,proto3" json:"config,omitempty"`
+	OccurredAt    *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=occurred_at,json=occurredAt,proto3" json:"occurred_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConfigEvent) Reset() {
+	*x = ConfigEvent{}
+	mi := &file_makatom_config_v1_config_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConfigEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConfigEvent) ProtoMessage() {}
+
+func (x *ConfigEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_makatom_config_v1_config_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConfigEvent.ProtoReflect.Descriptor instead.
+func (*ConfigEvent) Descriptor() ([]byte, []int) {
+	return file_makatom_config_v1_config_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ConfigEvent) GetKind() ConfigEvent_Kind {
+	if x != nil {
+		return x.Kind
+	}
+	return ConfigEvent_KIND_UNSPECIFIED
+}
+
+func (x *ConfigEvent) GetConfigId() string {
+	if x != nil {
+		return x.ConfigId
+	}
+	return ""
+}
+
+func (x *ConfigEvent) GetConfig() *Config {
+	if x != nil {
+		return x.Config
+	}
+	return nil
+}
+
+func (x *ConfigEvent) GetOccurredAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.OccurredAt
+	}
+	return nil
+}
+
+var File_makatom_config_v1_config_proto protoreflect.FileDescriptor
+
+const file_makatom_config_v1_config_proto_rawDesc = "" +
+	"\n" +
+	"\x1emakatom/config/v1/config.proto\x12\x11makatom.config.v1\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xc5\x05\n" +
+	"\x06Config\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x1c\n" +
+	"\tnamespace\x18\x03 \x01(\tR\tnamespace\x12\x12\n" +
+	"\x04type\x18\x04 \x01(\tR\x04type\x12\x18\n" +
+	"\asubtype\x18\x05 \x01(\tR\asubtype\x12\x12\n" +
+	"\x04tags\x18\x06 \x03(\tR\x04tags\x12\x1b\n" +
+	"\ttenant_id\x18\a \x01(\tR\btenantId\x12\x1d\n" +
+	"\n" +
+	"created_by\x18\b \x01(\tR\tcreatedBy\x12&\n" +
+	"\x0flast_updated_by\x18\t \x01(\tR\rlastUpdatedBy\x123\n" +
+	"\bmetadata\x18\n" +
+	" \x01(\v2\x17.google.protobuf.StructR\bmetadata\x12%\n" +
+	"\x0eschema_version\x18\v \x01(\tR\rschemaVersion\x12)\n" +
+	"\x10encrypted_fields\x18\f \x03(\tR\x0fencryptedFields\x12C\n" +
+	"\boverlays\x18\r \x03(\v2'.makatom.config.v1.Config.OverlaysEntryR\boverlays\x12\x10\n" +
+	"\x03env\x18\x0e \x01(\tR\x03env\x129\n" +
+	"\n" +
+	"created_at\x18\x0f \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\x10 \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\x12)\n" +
+	"\x10decryption_error\x18\x11 \x01(\tR\x0fdecryptionError\x1aT\n" +
+	"\rOverlaysEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12-\n" +
+	"\x05value\x18\x02 \x01(\v2\x17.google.protobuf.StructR\x05value:\x028\x01\"\xa3\x04\n" +
+	"\rConfigArchive\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1b\n" +
+	"\tconfig_id\x18\x02 \x01(\tR\bconfigId\x12\x12\n" +
+	"\x04name\x18\x03 \x01(\tR\x04name\x12\x1c\n" +
+	"\tnamespace\x18\x04 \x01(\tR\tnamespace\x12\x12\n" +
+	"\x04type\x18\x05 \x01(\tR\x04type\x12\x18\n" +
+	"\asubtype\x18\x06 \x01(\tR\asubtype\x12\x12\n" +
+	"\x04tags\x18\a \x03(\tR\x04tags\x12\x1b\n" +
+	"\ttenant_id\x18\b \x01(\tR\btenantId\x12\x1d\n" +
+	"\n" +
+	"created_by\x18\t \x01(\tR\tcreatedBy\x12&\n" +
+	"\x0flast_updated_by\x18\n" +
+	" \x01(\tR\rlastUpdatedBy\x123\n" +
+	"\bmetadata\x18\v \x01(\v2\x17.google.protobuf.StructR\bmetadata\x12\x18\n" +
+	"\aversion\x18\f \x01(\x05R\aversion\x12%\n" +
+	"\x0eschema_version\x18\r \x01(\tR\rschemaVersion\x12;\n" +
+	"\varchived_at\x18\x0e \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"archivedAt\x12\x1f\n" +
+	"\varchived_by\x18\x0f \x01(\tR\n" +
+	"archivedBy\x129\n" +
+	"\n" +
+	"created_at\x18\x10 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\"\xe6\x02\n" +
+	"\x13CreateConfigRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1c\n" +
+	"\tnamespace\x18\x02 \x01(\tR\tnamespace\x12\x12\n" +
+	"\x04type\x18\x03 \x01(\tR\x04type\x12\x18\n" +
+	"\asubtype\x18\x04 \x01(\tR\asubtype\x12\x12\n" +
+	"\x04tags\x18\x05 \x03(\tR\x04tags\x123\n" +
+	"\bmetadata\x18\x06 \x01(\v2\x17.google.protobuf.StructR\bmetadata\x12P\n" +
+	"\boverlays\x18\a \x03(\v24.makatom.config.v1.CreateConfigRequest.OverlaysEntryR\boverlays\x1aT\n" +
+	"\rOverlaysEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12-\n" +
+	"\x05value\x18\x02 \x01(\v2\x17.google.protobuf.StructR\x05value:\x028\x01\"N\n" +
+	"\x10GetConfigRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x10\n" +
+	"\x03env\x18\x02 \x01(\tR\x03env\x12\x18\n" +
+	"\aresolve\x18\x03 \x01(\bR\aresolve\"\xdb\x01\n" +
+	"\x12ListConfigsRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x1c\n" +
+	"\tnamespace\x18\x02 \x01(\tR\tnamespace\x12)\n" +
+	"\x10namespace_prefix\x18\x03 \x01(\tR\x0fnamespacePrefix\x12\x12\n" +
+	"\x04type\x18\x04 \x01(\tR\x04type\x12\x18\n" +
+	"\asubtype\x18\x05 \x01(\tR\asubtype\x12\x10\n" +
+	"\x03tag\x18\x06 \x01(\tR\x03tag\x12\x14\n" +
+	"\x05limit\x18\a \x01(\x03R\x05limit\x12\x12\n" +
+	"\x04skip\x18\b \x01(\x03R\x04skip\"`\n" +
+	"\x13ListConfigsResponse\x123\n" +
+	"\aconfigs\x18\x01 \x03(\v2\x19.makatom.config.v1.ConfigR\aconfigs\x12\x14\n" +
+	"\x05total\x18\x02 \x01(\x03R\x05total\"\xe4\x02\n" +
+	"\x13UpdateConfigRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04tags\x18\x02 \x03(\tR\x04tags\x12!\n" +
+	"\freplace_tags\x18\x03 \x01(\bR\vreplaceTags\x123\n" +
+	"\bmetadata\x18\x04 \x01(\v2\x17.google.protobuf.StructR\bmetadata\x12P\n" +
+	"\boverlays\x18\x05 \x03(\v24.makatom.config.v1.UpdateConfigRequest.OverlaysEntryR\boverlays\x12)\n" +
+	"\x10replace_overlays\x18\x06 \x01(\bR\x0freplaceOverlays\x1aT\n" +
+	"\rOverlaysEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12-\n" +
+	"\x05value\x18\x02 \x01(\v2\x17.google.protobuf.StructR\x05value:\x028\x01\"%\n" +
+	"\x13DeleteConfigRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"0\n" +
+	"\x14DeleteConfigResponse\x12\x18\n" +
+	"\amessage\x18\x01 \x01(\tR\amessage\"2\n" +
+	"\x13ListArchivesRequest\x12\x1b\n" +
+	"\tconfig_id\x18\x01 \x01(\tR\bconfigId\"j\n" +
+	"\x14ListArchivesResponse\x12<\n" +
+	"\barchives\x18\x01 \x03(\v2 .makatom.config.v1.ConfigArchiveR\barchives\x12\x14\n" +
+	"\x05total\x18\x02 \x01(\x03R\x05total\"E\n" +
+	"\x14RestoreConfigRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1d\n" +
+	"\n" +
+	"archive_id\x18\x02 \x01(\tR\tarchiveId\"Q\n" +
+	"\x13DecryptFieldRequest\x12\x1b\n" +
+	"\tconfig_id\x18\x01 \x01(\tR\bconfigId\x12\x1d\n" +
+	"\n" +
+	"field_name\x18\x02 \x01(\tR\tfieldName\"\x93\x01\n" +
+	"\x14DecryptFieldResponse\x12\x1b\n" +
+	"\tconfig_id\x18\x01 \x01(\tR\bconfigId\x12\x1d\n" +
+	"\n" +
+	"field_name\x18\x02 \x01(\tR\tfieldName\x12?\n" +
+	"\x0fdecrypted_value\x18\x03 \x01(\v2\x16.google.protobuf.ValueR\x0edecryptedValue\"\x80\x01\n" +
+	"\fWatchRequest\x12\x1c\n" +
+	"\tnamespace\x18\x01 \x01(\tR\tnamespace\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x18\n" +
+	"\asubtype\x18\x03 \x01(\tR\asubtype\x12\x12\n" +
+	"\x04name\x18\x04 \x01(\tR\x04name\x12\x10\n" +
+	"\x03tag\x18\x05 \x01(\tR\x03tag\"\xa7\x02\n" +
+	"\vConfigEvent\x127\n" +
+	"\x04kind\x18\x01 \x01(\x0e2#.makatom.config.v1.ConfigEvent.KindR\x04kind\x12\x1b\n" +
+	"\tconfig_id\x18\x02 \x01(\tR\bconfigId\x121\n" +
+	"\x06config\x18\x03 \x01(\v2\x19.makatom.config.v1.ConfigR\x06config\x12;\n" +
+	"\voccurred_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"occurredAt\"R\n" +
+	"\x04Kind\x12\x14\n" +
+	"\x10KIND_UNSPECIFIED\x10\x00\x12\x10\n" +
+	"\fKIND_CREATED\x10\x01\x12\x10\n" +
+	"\fKIND_UPDATED\x10\x02\x12\x10\n" +
+	"\fKIND_DELETED\x10\x032\xa4\x06\n" +
+	"\rConfigService\x12Q\n" +
+	"\fCreateConfig\x12&.makatom.config.v1.CreateConfigRequest\x1a\x19.makatom.config.v1.Config\x12K\n" +
+	"\tGetConfig\x12#.makatom.config.v1.GetConfigRequest\x1a\x19.makatom.config.v1.Config\x12\\\n" +
+	"\vListConfigs\x12%.makatom.config.v1.ListConfigsRequest\x1a&.makatom.config.v1.ListConfigsResponse\x12Q\n" +
+	"\fUpdateConfig\x12&.makatom.config.v1.UpdateConfigRequest\x1a\x19.makatom.config.v1.Config\x12_\n" +
+	"\fDeleteConfig\x12&.makatom.config.v1.DeleteConfigRequest\x1a'.makatom.config.v1.DeleteConfigResponse\x12_\n" +
+	"\fListArchives\x12&.makatom.config.v1.ListArchivesRequest\x1a'.makatom.config.v1.ListArchivesResponse\x12S\n" +
+	"\rRestoreConfig\x12'.makatom.config.v1.RestoreConfigRequest\x1a\x19.makatom.config.v1.Config\x12_\n" +
+	"\fDecryptField\x12&.makatom.config.v1.DecryptFieldRequest\x1a'.makatom.config.v1.DecryptFieldResponse\x12J\n" +
+	"\x05Watch\x12\x1f.makatom.config.v1.WatchRequest\x1a\x1e.makatom.config.v1.ConfigEvent0\x01B*Z(makatom-api-config/pkg/configpb;configpbb\x06proto3"
+
+var (
+	file_makatom_config_v1_config_proto_rawDescOnce sync.Once
+	file_makatom_config_v1_config_proto_rawDescData []byte
+)
+
+func file_makatom_config_v1_config_proto_rawDescGZIP() []byte {
+	file_makatom_config_v1_config_proto_rawDescOnce.Do(func() {
+		file_makatom_config_v1_config_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_makatom_config_v1_config_proto_rawDesc), len(file_makatom_config_v1_config_proto_rawDesc)))
+	})
+	return file_makatom_config_v1_config_proto_rawDescData
+}
+
+var file_makatom_config_v1_config_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_makatom_config_v1_config_proto_msgTypes = make([]protoimpl.MessageInfo, 19)
+var file_makatom_config_v1_config_proto_goTypes = []any{
+	(ConfigEvent_Kind)(0),         // 0: makatom.config.v1.ConfigEvent.Kind
+	(*Config)(nil),                // 1: makatom.config.v1.Config
+	(*ConfigArchive)(nil),         // 2: makatom.config.v1.ConfigArchive
+	(*CreateConfigRequest)(nil),   // 3: makatom.config.v1.CreateConfigRequest
+	(*GetConfigRequest)(nil),      // 4: makatom.config.v1.GetConfigRequest
+	(*ListConfigsRequest)(nil),    // 5: makatom.config.v1.ListConfigsRequest
+	(*ListConfigsResponse)(nil),   // 6: makatom.config.v1.ListConfigsResponse
+	(*UpdateConfigRequest)(nil),   // 7: makatom.config.v1.UpdateConfigRequest
+	(*DeleteConfigRequest)(nil),   // 8: makatom.config.v1.DeleteConfigRequest
+	(*DeleteConfigResponse)(nil),  // 9: makatom.config.v1.DeleteConfigResponse
+	(*ListArchivesRequest)(nil),   // 10: makatom.config.v1.ListArchivesRequest
+	(*ListArchivesResponse)(nil),  // 11: makatom.config.v1.ListArchivesResponse
+	(*RestoreConfigRequest)(nil),  // 12: makatom.config.v1.RestoreConfigRequest
+	(*DecryptFieldRequest)(nil),   // 13: makatom.config.v1.DecryptFieldRequest
+	(*DecryptFieldResponse)(nil),  // 14: makatom.config.v1.DecryptFieldResponse
+	(*WatchRequest)(nil),          // 15: makatom.config.v1.WatchRequest
+	(*ConfigEvent)(nil),           // 16: makatom.config.v1.ConfigEvent
+	nil,                           // 17: makatom.config.v1.Config.OverlaysEntry
+	nil,                           // 18: makatom.config.v1.CreateConfigRequest.OverlaysEntry
+	nil,                           // 19: makatom.config.v1.UpdateConfigRequest.OverlaysEntry
+	(*structpb.Struct)(nil),       // 20: google.protobuf.Struct
+	(*timestamppb.Timestamp)(nil), // 21: google.protobuf.Timestamp
+	(*structpb.Value)(nil),        // 22: google.protobuf.Value
+}
+var file_makatom_config_v1_config_proto_depIdxs = []int32{
+	20, // 0: makatom.config.v1.Config.metadata:type_name -> google.protobuf.Struct
+	17, // 1: makatom.config.v1.Config.overlays:type_name -> makatom.config.v1.Config.OverlaysEntry
+	21, // 2: makatom.config.v1.Config.created_at:type_name -> google.protobuf.Timestamp
+	21, // 3: makatom.config.v1.Config.updated_at:type_name -> google.protobuf.Timestamp
+	20, // 4: makatom.config.v1.ConfigArchive.metadata:type_name -> google.protobuf.Struct
+	21, // 5: makatom.config.v1.ConfigArchive.archived_at:type_name -> google.protobuf.Timestamp
+	21, // 6: makatom.config.v1.ConfigArchive.created_at:type_name -> google.protobuf.Timestamp
+	20, // 7: makatom.config.v1.CreateConfigRequest.metadata:type_name -> google.protobuf.Struct
+	18, // 8: makatom.config.v1.CreateConfigRequest.overlays:type_name -> makatom.config.v1.CreateConfigRequest.OverlaysEntry
+	1,  // 9: makatom.config.v1.ListConfigsResponse.configs:type_name -> makatom.config.v1.Config
+	20, // 10: makatom.config.v1.UpdateConfigRequest.metadata:type_name -> google.protobuf.Struct
+	19, // 11: makatom.config.v1.UpdateConfigRequest.overlays:type_name -> makatom.config.v1.UpdateConfigRequest.OverlaysEntry
+	2,  // 12: makatom.config.v1.ListArchivesResponse.archives:type_name -> makatom.config.v1.ConfigArchive
+	22, // 13: makatom.config.v1.DecryptFieldResponse.decrypted_value:type_name -> google.protobuf.Value
+	0,  // 14: makatom.config.v1.ConfigEvent.kind:type_name -> makatom.config.v1.ConfigEvent.Kind
+	1,  // 15: makatom.config.v1.ConfigEvent.config:type_name -> makatom.config.v1.Config
+	21, // 16: makatom.config.v1.ConfigEvent.occurred_at:type_name -> google.protobuf.Timestamp
+	20, // 17: makatom.config.v1.Config.OverlaysEntry.value:type_name -> google.protobuf.Struct
+	20, // 18: makatom.config.v1.CreateConfigRequest.OverlaysEntry.value:type_name -> google.protobuf.Struct
+	20, // 19: makatom.config.v1.UpdateConfigRequest.OverlaysEntry.value:type_name -> google.protobuf.Struct
+	3,  // 20: makatom.config.v1.ConfigService.CreateConfig:input_type -> makatom.config.v1.CreateConfigRequest
+	4,  // 21: makatom.config.v1.ConfigService.GetConfig:input_type -> makatom.config.v1.GetConfigRequest
+	5,  // 22: makatom.config.v1.ConfigService.ListConfigs:input_type -> makatom.config.v1.ListConfigsRequest
+	7,  // 23: makatom.config.v1.ConfigService.UpdateConfig:input_type -> makatom.config.v1.UpdateConfigRequest
+	8,  // 24: makatom.config.v1.ConfigService.DeleteConfig:input_type -> makatom.config.v1.DeleteConfigRequest
+	10, // 25: makatom.config.v1.ConfigService.ListArchives:input_type -> makatom.config.v1.ListArchivesRequest
+	12, // 26: makatom.config.v1.ConfigService.RestoreConfig:input_type -> makatom.config.v1.RestoreConfigRequest
+	13, // 27: makatom.config.v1.ConfigService.DecryptField:input_type -> makatom.config.v1.DecryptFieldRequest
+	15, // 28: makatom.config.v1.ConfigService.Watch:input_type -> makatom.config.v1.WatchRequest
+	1,  // 29: makatom.config.v1.ConfigService.CreateConfig:output_type -> makatom.config.v1.Config
+	1,  // 30: makatom.config.v1.ConfigService.GetConfig:output_type -> makatom.config.v1.Config
+	6,  // 31: makatom.config.v1.ConfigService.ListConfigs:output_type -> makatom.config.v1.ListConfigsResponse
+	1,  // 32: makatom.config.v1.ConfigService.UpdateConfig:output_type -> makatom.config.v1.Config
+	9,  // 33: makatom.config.v1.ConfigService.DeleteConfig:output_type -> makatom.config.v1.DeleteConfigResponse
+	11, // 34: makatom.config.v1.ConfigService.ListArchives:output_type -> makatom.config.v1.ListArchivesResponse
+	1,  // 35: makatom.config.v1.ConfigService.RestoreConfig:output_type -> makatom.config.v1.Config
+	14, // 36: makatom.config.v1.ConfigService.DecryptField:output_type -> makatom.config.v1.DecryptFieldResponse
+	16, // 37: makatom.config.v1.ConfigService.Watch:output_type -> makatom.config.v1.ConfigEvent
+	29, // [29:38] is the sub-list for method output_type
+	20, // [20:29] is the sub-list for method input_type
+	20, // [20:20] is the sub-list for extension type_name
+	20, // [20:20] is the sub-list for extension extendee
+	0,  // [0:20] is the sub-list for field type_name
+}
+
+func init() { file_makatom_config_v1_config_proto_init() }
+func file_makatom_config_v1_config_proto_init() {
+	if File_makatom_config_v1_config_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_makatom_config_v1_config_proto_rawDesc), len(file_makatom_config_v1_config_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   19,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_makatom_config_v1_config_proto_goTypes,
+		DependencyIndexes: file_makatom_config_v1_config_proto_depIdxs,
+		EnumInfos:         file_makatom_config_v1_config_proto_enumTypes,
+		MessageInfos:      file_makatom_config_v1_config_proto_msgTypes,
+	}.Build()
+	File_makatom_config_v1_config_proto = out.File
+	file_makatom_config_v1_config_proto_goTypes = nil
+	file_makatom_config_v1_config_proto_depIdxs = nil
+}
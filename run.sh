@@ -12,6 +12,8 @@ export DEBUG="true"
 export MONGO_URI="mongodb://localhost:27017/makatom_config"
 export MONGO_DATABASE="makatom_config"
 export MONGO_URI_NAME="config"
+export JWT_SECRET="${JWT_SECRET:-dev-secret}"
+export CONFIG_ENCRYPTION_KEY="${CONFIG_ENCRYPTION_KEY:-dev-encryption-key}"
 
 echo "Environment variables set:"
 echo "  API_PORT: $API_PORT"
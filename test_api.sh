@@ -4,13 +4,15 @@
 # Make sure the server is running on :8080 before running this script
 
 BASE_URL="http://localhost:8080"
+# JWT for a principal holding the admin role, e.g. signed with JWT_SECRET
+AUTH_TOKEN="${AUTH_TOKEN:?AUTH_TOKEN must be set}"
 
 echo "Testing Config API..."
 echo "====================="
 
 # Test 1: Create a config
 echo "1. Creating a config..."
-CREATE_RESPONSE=$(curl -s -w "\nHTTP_STATUS:%{http_code}" -X POST "$BASE_URL/config" \
+CREATE_RESPONSE=$(curl -s -H "Authorization: Bearer $AUTH_TOKEN" -w "\nHTTP_STATUS:%{http_code}" -X POST "$BASE_URL/config" \
   -H "Content-Type: application/json" \
   -d '{
     "name": "database_config",
@@ -47,7 +49,7 @@ echo "Config ID: $CONFIG_ID"
 
 # Test 2: Get all configs
 echo -e "\n2. Getting all configs..."
-GET_ALL_RESPONSE=$(curl -s -w "\nHTTP_STATUS:%{http_code}" -X GET "$BASE_URL/configs?limit=10")
+GET_ALL_RESPONSE=$(curl -s -H "Authorization: Bearer $AUTH_TOKEN" -w "\nHTTP_STATUS:%{http_code}" -X GET "$BASE_URL/configs?limit=10")
 HTTP_STATUS=$(echo "$GET_ALL_RESPONSE" | grep "HTTP_STATUS:" | cut -d':' -f2)
 RESPONSE_BODY=$(echo "$GET_ALL_RESPONSE" | sed '/HTTP_STATUS:/d')
 
@@ -57,7 +59,7 @@ echo "$RESPONSE_BODY" | jq '.'
 # Test 3: Get config by ID
 if [ ! -z "$CONFIG_ID" ]; then
     echo -e "\n3. Getting config by ID: $CONFIG_ID"
-    GET_RESPONSE=$(curl -s -w "\nHTTP_STATUS:%{http_code}" -X GET "$BASE_URL/config/$CONFIG_ID")
+    GET_RESPONSE=$(curl -s -H "Authorization: Bearer $AUTH_TOKEN" -w "\nHTTP_STATUS:%{http_code}" -X GET "$BASE_URL/config/$CONFIG_ID")
     HTTP_STATUS=$(echo "$GET_RESPONSE" | grep "HTTP_STATUS:" | cut -d':' -f2)
     RESPONSE_BODY=$(echo "$GET_RESPONSE" | sed '/HTTP_STATUS:/d')
     
@@ -68,7 +70,7 @@ fi
 # Test 4: Update config
 if [ ! -z "$CONFIG_ID" ]; then
     echo -e "\n4. Updating config: $CONFIG_ID"
-    UPDATE_RESPONSE=$(curl -s -w "\nHTTP_STATUS:%{http_code}" -X PUT "$BASE_URL/config/$CONFIG_ID" \
+    UPDATE_RESPONSE=$(curl -s -H "Authorization: Bearer $AUTH_TOKEN" -w "\nHTTP_STATUS:%{http_code}" -X PUT "$BASE_URL/config/$CONFIG_ID" \
       -H "Content-Type: application/json" \
       -d '{
         "name": "updated_database_config",
@@ -90,7 +92,7 @@ fi
 # Test 5: Delete config
 if [ ! -z "$CONFIG_ID" ]; then
     echo -e "\n5. Deleting config: $CONFIG_ID"
-    DELETE_RESPONSE=$(curl -s -w "\nHTTP_STATUS:%{http_code}" -X DELETE "$BASE_URL/config/$CONFIG_ID")
+    DELETE_RESPONSE=$(curl -s -H "Authorization: Bearer $AUTH_TOKEN" -w "\nHTTP_STATUS:%{http_code}" -X DELETE "$BASE_URL/config/$CONFIG_ID")
     HTTP_STATUS=$(echo "$DELETE_RESPONSE" | grep "HTTP_STATUS:" | cut -d':' -f2)
     RESPONSE_BODY=$(echo "$DELETE_RESPONSE" | sed '/HTTP_STATUS:/d')
     